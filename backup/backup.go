@@ -0,0 +1,300 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// ManualLabel is part of the names of the backups created with ExecuteBackupCreate.
+const ManualLabel = "manual"
+
+// WaitTimeout bounds how long a backup or restore is waited for.
+var WaitTimeout = 30 * time.Minute
+
+var (
+	CreateBackupClient       = client.CreateBackup
+	ListBackupsClient        = client.ListBackups
+	DescribeBackupClient     = client.DescribeBackup
+	DeleteBackupClient       = client.DeleteBackup
+	RestoreTableClient       = client.RestoreTableFromBackup
+	WaitForBackupClient      = client.WaitForBackupAvailable
+	WaitForTableActiveClient = client.WaitForTableActive
+)
+
+// formatSize renders a backup size in bytes for the log, "-" if DynamoDB doesn't report one.
+func formatSize(size *int64) string {
+	if size == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", aws.ToInt64(size))
+}
+
+// logBackup logs a backup summary.
+func logBackup(dbmgr *client.DynamoDBManager, backup types.BackupSummary) {
+	dbmgr.Logger.Infof("Table:%s - backup:%s - status:%s - type:%s - created:%s - size:%s - arn:%s",
+		aws.ToString(backup.TableName), aws.ToString(backup.BackupName), backup.BackupStatus, backup.BackupType,
+		formatTime(backup.BackupCreationDateTime), formatSize(backup.BackupSizeBytes), aws.ToString(backup.BackupArn))
+}
+
+// ExecuteBackupCreate creates an on-demand backup of the given tables, or of the tables matched by the search conditions.
+// The backups are named TABLE-LABEL-TIMESTAMP, see client.BackupName, with the manual label if none is given.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value, the label and a wait flag as input.
+// With wait it only returns once every backup is available.
+// It returns an error if no tables were selected or any backup couldn't be created.
+func ExecuteBackupCreate(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, label string, wait bool) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+	if label == "" {
+		label = ManualLabel
+	}
+
+	var failedTables []string
+	for _, tableName := range targets {
+		backup, err := CreateBackupClient(dbmgr, tableName, client.BackupName(tableName, label, time.Now()))
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		if wait {
+			if err := WaitForBackupClient(dbmgr, aws.ToString(backup.BackupArn), WaitTimeout); err != nil {
+				dbmgr.Logger.Errorf("Backup of table:%s is not available due to: %v", tableName, err)
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+			dbmgr.Logger.Infof("Backup:%s of table:%s is available", aws.ToString(backup.BackupName), tableName)
+		}
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to back up tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
+
+// ExecuteBackupList logs the on-demand backups of the given tables, of the tables matched by the search conditions,
+// or of all tables when neither is given, newest first.
+// It takes a DynamoDBManager, table names, a fuzzy table name and a tag value as input.
+// It returns an error if the tables can't be selected or their backups can't be listed.
+func ExecuteBackupList(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) error {
+	var backups []types.BackupSummary
+	if len(tableNames) == 0 && tableFuzzyName == "" && tagValue == "" {
+		var err error
+		if backups, err = ListBackupsClient(dbmgr, ""); err != nil {
+			return err
+		}
+	} else {
+		targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+			return err
+		}
+		for _, tableName := range targets {
+			tableBackups, err := ListBackupsClient(dbmgr, tableName)
+			if err != nil {
+				return err
+			}
+			backups = append(backups, tableBackups...)
+		}
+	}
+
+	sortNewestFirst(backups)
+	for _, backup := range backups {
+		logBackup(dbmgr, backup)
+	}
+	dbmgr.Logger.Infof("%d backups found", len(backups))
+	return nil
+}
+
+// sortNewestFirst sorts backups by creation time, newest first.
+func sortNewestFirst(backups []types.BackupSummary) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		return aws.ToTime(backups[i].BackupCreationDateTime).After(aws.ToTime(backups[j].BackupCreationDateTime))
+	})
+}
+
+// deleteBackups deletes backups, refusing those of tables the deletion protection policy doesn't allow it on.
+// Backups of tables that no longer exist aren't checked, there is no table left whose protection could be.
+// With dry-run the deletions are only logged.
+// It returns the ARNs of the backups that couldn't be deleted.
+func deleteBackups(dbmgr *client.DynamoDBManager, backups []types.BackupSummary, dryRun bool) []string {
+	var failedBackups []string
+	existingTables := make(map[string]bool)
+	if dbmgr.RequireProtection {
+		tableNames, err := GetTableListClient(dbmgr)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to list the tables to check the protection policy due to: %v", err)
+			for _, backup := range backups {
+				failedBackups = append(failedBackups, aws.ToString(backup.BackupArn))
+			}
+			return failedBackups
+		}
+		for _, tableName := range tableNames {
+			existingTables[tableName] = true
+		}
+	}
+
+	for _, backup := range backups {
+		backupArn := aws.ToString(backup.BackupArn)
+		tableName := aws.ToString(backup.TableName)
+		if dbmgr.RequireProtection && !existingTables[tableName] {
+			dbmgr.Logger.Infof("Table:%s no longer exists - its backup:%s is not covered by the protection policy", tableName, aws.ToString(backup.BackupName))
+		} else if err := CheckPolicyClient(dbmgr, tableName, "delete backup:"+aws.ToString(backup.BackupName)+" of"); err != nil {
+			dbmgr.Logger.Errorf("%v", err)
+			failedBackups = append(failedBackups, backupArn)
+			continue
+		}
+
+		if dryRun {
+			dbmgr.Logger.Infof("Table:%s - would delete backup:%s created:%s - arn:%s", aws.ToString(backup.TableName), aws.ToString(backup.BackupName),
+				formatTime(backup.BackupCreationDateTime), backupArn)
+			continue
+		}
+		if err := DeleteBackupClient(dbmgr, backupArn); err != nil {
+			failedBackups = append(failedBackups, backupArn)
+		}
+	}
+	return failedBackups
+}
+
+// ExecuteBackupDelete deletes the on-demand backups with the given ARNs.
+// It takes a DynamoDBManager, the backup ARNs and a dry-run flag as input.
+// With dry-run the deletions are only logged.
+// It returns an error if any backup couldn't be described or deleted.
+func ExecuteBackupDelete(dbmgr *client.DynamoDBManager, backupArns []string, dryRun bool) error {
+	var backups []types.BackupSummary
+	var failedBackups []string
+	for _, backupArn := range backupArns {
+		description, err := DescribeBackupClient(dbmgr, backupArn)
+		if err != nil {
+			failedBackups = append(failedBackups, backupArn)
+			continue
+		}
+		backups = append(backups, types.BackupSummary{
+			BackupArn:              description.BackupDetails.BackupArn,
+			BackupName:             description.BackupDetails.BackupName,
+			BackupCreationDateTime: description.BackupDetails.BackupCreationDateTime,
+			TableName:              description.SourceTableDetails.TableName,
+		})
+	}
+
+	failedBackups = append(failedBackups, deleteBackups(dbmgr, backups, dryRun)...)
+	if len(failedBackups) > 0 {
+		return errors.New(fmt.Sprintf("Failed to delete backups: %s", strings.Join(failedBackups, ",")))
+	}
+	return nil
+}
+
+// ExecuteBackupRestore restores an on-demand backup to a new table, which must not exist yet.
+// It takes a DynamoDBManager, the backup ARN, the target table name and a wait flag as input.
+// With wait it only returns once the restored table is ACTIVE.
+// It returns an error if the restore fails.
+func ExecuteBackupRestore(dbmgr *client.DynamoDBManager, backupArn string, targetTableName string, wait bool) error {
+	description, err := DescribeBackupClient(dbmgr, backupArn)
+	if err != nil {
+		return err
+	}
+	if description.BackupDetails.BackupStatus != types.BackupStatusAvailable {
+		return errors.New(fmt.Sprintf("Refused to restore backup:%s : its status is %s, not AVAILABLE", backupArn, description.BackupDetails.BackupStatus))
+	}
+
+	dbmgr.Logger.Infof("Restoring backup:%s of table:%s created:%s to table:%s ...", aws.ToString(description.BackupDetails.BackupName),
+		aws.ToString(description.SourceTableDetails.TableName), formatTime(description.BackupDetails.BackupCreationDateTime), targetTableName)
+	if err := RestoreTableClient(dbmgr, backupArn, targetTableName); err != nil {
+		return err
+	}
+
+	if !wait {
+		dbmgr.Logger.Infof("Table:%s is being restored, it is usable once ACTIVE", targetTableName)
+		return nil
+	}
+	if err := WaitForTableActiveClient(dbmgr, targetTableName, WaitTimeout); err != nil {
+		return err
+	}
+	dbmgr.Logger.Infof("Table:%s restored and ACTIVE", targetTableName)
+	return nil
+}
+
+// PruneOptions selects the on-demand backups to delete: those older than MaxAge and those beyond the newest Keep
+// backups of each table. Zero values disable a limit.
+type PruneOptions struct {
+	MaxAge time.Duration
+	Keep   int
+	DryRun bool
+}
+
+// selectExpired returns the backups of a table the retention prunes, given newest first.
+// Only available backups created by users are pruned, system and AWS Backup backups are left alone.
+func selectExpired(backups []types.BackupSummary, opts PruneOptions, now time.Time) []types.BackupSummary {
+	var expired []types.BackupSummary
+	kept := 0
+	for _, backup := range backups {
+		if backup.BackupType != types.BackupTypeUser || backup.BackupStatus != types.BackupStatusAvailable {
+			continue
+		}
+		tooOld := opts.MaxAge > 0 && now.Sub(aws.ToTime(backup.BackupCreationDateTime)) > opts.MaxAge
+		if tooOld || (opts.Keep > 0 && kept >= opts.Keep) {
+			expired = append(expired, backup)
+			continue
+		}
+		kept++
+	}
+	return expired
+}
+
+// ExecuteBackupPrune deletes the on-demand backups of the given tables, or of the tables matched by the search
+// conditions, that exceed the retention.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value and the PruneOptions as input.
+// With dry-run the deletions are only logged.
+// It returns an error if no tables were selected or any backup couldn't be listed or deleted.
+func ExecuteBackupPrune(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, opts PruneOptions) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	var failed []string
+	pruned := 0
+	for _, tableName := range targets {
+		backups, err := ListBackupsClient(dbmgr, tableName)
+		if err != nil {
+			failed = append(failed, tableName)
+			continue
+		}
+		sortNewestFirst(backups)
+
+		expired := selectExpired(backups, opts, time.Now())
+		dbmgr.Logger.Infof("Table:%s - %d of %d backups exceed the retention", tableName, len(expired), len(backups))
+		failedBackups := deleteBackups(dbmgr, expired, opts.DryRun)
+		failed = append(failed, failedBackups...)
+		pruned += len(expired) - len(failedBackups)
+	}
+
+	verb := "Deleted"
+	if opts.DryRun {
+		verb = "Would delete"
+	}
+	dbmgr.Logger.Infof("%s %d backups of %d tables", verb, pruned, len(targets))
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failed) > 0 {
+		return errors.New(fmt.Sprintf("Failed to prune backups: %s", strings.Join(failed, ",")))
+	}
+	return nil
+}
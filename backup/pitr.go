@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+var (
+	GetContinuousBackupsClient    = client.GetContinuousBackups
+	UpdateContinuousBackupsClient = client.UpdateContinuousBackups
+	GetTableListClient            = client.GetTableList
+	CheckPolicyClient             = client.CheckDestructiveOperation
+	ResolveTablesTask             = search.ResolveTables
+)
+
+// ErrPitrDisabled is returned by ExecutePitrReport when tables lack point-in-time recovery.
+var ErrPitrDisabled = errors.New("tables without point-in-time recovery found")
+
+// formatTime renders a restorable time for the log, "-" if DynamoDB doesn't report one.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// isPitrEnabled reports whether point-in-time recovery is enabled.
+func isPitrEnabled(pitr *types.PointInTimeRecoveryDescription) bool {
+	return pitr.PointInTimeRecoveryStatus == types.PointInTimeRecoveryStatusEnabled
+}
+
+// ExecutePitrShow logs the point-in-time recovery status of the given tables, or of the tables matched by the
+// search conditions, with the earliest and latest restorable times of the tables having it enabled.
+// It takes a DynamoDBManager, table names, a fuzzy table name and a tag value as input.
+// It returns an error if the tables can't be selected or their status can't be retrieved.
+func ExecutePitrShow(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	for _, tableName := range targets {
+		pitr, err := GetContinuousBackupsClient(dbmgr, tableName)
+		if err != nil {
+			return err
+		}
+		if !isPitrEnabled(pitr) {
+			dbmgr.Logger.Infof("Table:%s - PITR:%s", tableName, pitr.PointInTimeRecoveryStatus)
+			continue
+		}
+		dbmgr.Logger.Infof("Table:%s - PITR:%s - earliest restorable:%s - latest restorable:%s", tableName, pitr.PointInTimeRecoveryStatus,
+			formatTime(pitr.EarliestRestorableDateTime), formatTime(pitr.LatestRestorableDateTime))
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	return nil
+}
+
+// ExecutePitrUpdate enables or disables point-in-time recovery of the given tables, or of the tables matched by
+// the search conditions, and logs a summary of changed, unchanged and failed tables.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value, the requested state and a dry-run flag as input.
+// With dry-run the changes are only logged. Disabling drops the restore window, so it is refused on tables the
+// deletion protection policy doesn't allow it on.
+// It returns an error if no tables were selected or any table couldn't be changed.
+func ExecutePitrUpdate(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, enabled bool, dryRun bool) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	var changedTables, unchangedTables, failedTables []string
+	for _, tableName := range targets {
+		pitr, err := GetContinuousBackupsClient(dbmgr, tableName)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		if isPitrEnabled(pitr) == enabled {
+			dbmgr.Logger.Infof("Table:%s - PITR already %s", tableName, pitr.PointInTimeRecoveryStatus)
+			unchangedTables = append(unchangedTables, tableName)
+			continue
+		}
+
+		if !enabled {
+			if err := CheckPolicyClient(dbmgr, tableName, "disable point-in-time recovery of"); err != nil {
+				dbmgr.Logger.Errorf("%v", err)
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+		}
+
+		if dryRun {
+			dbmgr.Logger.Infof("Table:%s - would change PITR: %s -> enabled:%t", tableName, pitr.PointInTimeRecoveryStatus, enabled)
+			changedTables = append(changedTables, tableName)
+			continue
+		}
+
+		if !enabled {
+			dbmgr.Logger.Warnf("Disabling PITR of table:%s drops its restore window since %s", tableName, formatTime(pitr.EarliestRestorableDateTime))
+		}
+		if err := UpdateContinuousBackupsClient(dbmgr, tableName, enabled); err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		changedTables = append(changedTables, tableName)
+	}
+
+	verb := "Changed"
+	if dryRun {
+		verb = "Would change"
+	}
+	dbmgr.Logger.Infof("%s PITR of %d tables: %s", verb, len(changedTables), strings.Join(changedTables, ","))
+	dbmgr.Logger.Infof("Unchanged tables: %d, failed tables: %d", len(unchangedTables), len(failedTables))
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to change PITR of tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
+
+// ExecutePitrReport reports the given tables, the tables matched by the search conditions or all tables when
+// neither is given, that don't have point-in-time recovery enabled.
+// It takes a DynamoDBManager, table names, a fuzzy table name and a tag value as input.
+// It returns ErrPitrDisabled if any table lacks point-in-time recovery, or another error if the report couldn't be done.
+func ExecutePitrReport(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) error {
+	var err error
+	if len(tableNames) == 0 && tableFuzzyName == "" && tagValue == "" {
+		tableNames, err = GetTableListClient(dbmgr)
+	} else {
+		tableNames, err = ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	}
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to list the tables to report due to: %v", err)
+		return err
+	}
+	sort.Strings(tableNames)
+
+	var missingTables []string
+	for _, tableName := range tableNames {
+		pitr, err := GetContinuousBackupsClient(dbmgr, tableName)
+		if err != nil {
+			return err
+		}
+		if !isPitrEnabled(pitr) {
+			dbmgr.Logger.Warnf("Table:%s - PITR:%s", tableName, pitr.PointInTimeRecoveryStatus)
+			missingTables = append(missingTables, tableName)
+		}
+	}
+
+	dbmgr.Logger.Infof("%d of %d tables lack point-in-time recovery: %s", len(missingTables), len(tableNames), strings.Join(missingTables, ","))
+	if len(missingTables) > 0 {
+		return ErrPitrDisabled
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package cleanup
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+// FinalBackupLabel is part of the names of the backups taken right before a table is deleted.
+const FinalBackupLabel = "final"
+
+// DefaultProtectedTags are the tags that keep a table from being deleted unless others are configured.
+var DefaultProtectedTags = []string{"protected=true"}
+
+// BackupWaitTimeout bounds how long a final backup is waited for before the table is deleted.
+var BackupWaitTimeout = 30 * time.Minute
+
+var (
+	DescribeTableClient = client.DescribeTable
+	GetTableTagsClient  = client.GetTableTags
+	GetTableListClient  = client.GetTableList
+	DeleteTableClient   = client.DeleteTable
+	CreateBackupClient  = client.CreateBackup
+	WaitForBackupClient = client.WaitForBackupAvailable
+	CheckPolicyClient   = client.CheckDestructiveOperation
+	ResolveTablesTask   = search.ResolveTables
+	ConfirmClient       = client.Confirm
+)
+
+// DeleteOptions narrows the selected tables and controls how they are deleted.
+// OlderThan and NamePrefix only keep tables created before the given age and whose name starts with the prefix.
+// A table carrying any of the ProtectedTags, given as KEY or KEY=VALUE, is never deleted.
+type DeleteOptions struct {
+	OlderThan     time.Duration
+	NamePrefix    string
+	ProtectedTags []string
+	FinalBackup   bool
+	DryRun        bool
+}
+
+// protectedTag returns the first protected tag a table carries, KEY matching any value and KEY=VALUE matching
+// the value case-insensitively.
+// It returns the matched tag and whether the table is protected.
+func protectedTag(tags map[string]string, protectedTags []string) (string, bool) {
+	for _, protected := range protectedTags {
+		key, value, hasValue := strings.Cut(protected, "=")
+		tagValue, exists := tags[key]
+		if exists && (!hasValue || strings.EqualFold(tagValue, value)) {
+			return fmt.Sprintf("%s=%s", key, tagValue), true
+		}
+	}
+	return "", false
+}
+
+// selectTables resolves the tables to delete: the given tables, the tables matched by the search conditions or,
+// with only a name prefix, every table. Only the tables starting with the name prefix are kept.
+// It returns the table names and an error.
+func selectTables(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, namePrefix string) ([]string, error) {
+	var candidates []string
+	var err error
+	if len(tableNames) == 0 && tableFuzzyName == "" && tagValue == "" {
+		if namePrefix == "" {
+			return nil, errors.New("no table names, search conditions or name prefix provided")
+		}
+		candidates, err = GetTableListClient(dbmgr)
+	} else {
+		candidates, err = ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	for _, tableName := range candidates {
+		if strings.HasPrefix(tableName, namePrefix) {
+			targets = append(targets, tableName)
+		}
+	}
+	return targets, nil
+}
+
+// ExecuteDelete deletes the given tables, or the tables matched by the search conditions, after typed confirmation
+// of every table name. Tables with deletion protection, with replicas or carrying a protected tag are refused, as
+// are tables the deletion protection policy doesn't allow it on. With a final backup the table is only deleted once
+// its backup is available. With dry-run the tables that would be deleted are only logged.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value and the delete options as input.
+// It returns an error if no tables were selected or any table couldn't be deleted.
+func ExecuteDelete(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, options DeleteOptions) error {
+	targets, err := selectTables(dbmgr, tableNames, tableFuzzyName, tagValue, options.NamePrefix)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	var deletedTables, skippedTables, failedTables []string
+	for _, tableName := range targets {
+		table, err := DescribeTableClient(dbmgr, tableName)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		if options.OlderThan > 0 {
+			if table.CreationDateTime == nil || table.CreationDateTime.After(time.Now().Add(-options.OlderThan)) {
+				dbmgr.Logger.Debugf("Table:%s - created less than %v ago, skipped", tableName, options.OlderThan)
+				skippedTables = append(skippedTables, tableName)
+				continue
+			}
+		}
+
+		if aws.ToBool(table.DeletionProtectionEnabled) {
+			dbmgr.Logger.Errorf("Refused to delete table:%s : deletion protection is enabled", tableName)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		if len(table.Replicas) > 0 {
+			dbmgr.Logger.Errorf("Refused to delete table:%s : it is a global table with %d replicas", tableName, len(table.Replicas))
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		tags, err := GetTableTagsClient(dbmgr, aws.ToString(table.TableArn))
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		if tag, protected := protectedTag(client.TagsToMap(tags), options.ProtectedTags); protected {
+			dbmgr.Logger.Errorf("Refused to delete table:%s : it carries the protected tag %s", tableName, tag)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		if err := CheckPolicyClient(dbmgr, tableName, "delete"); err != nil {
+			dbmgr.Logger.Errorf("%v", err)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		created := "-"
+		if table.CreationDateTime != nil {
+			created = table.CreationDateTime.UTC().Format(time.RFC3339)
+		}
+		if options.DryRun {
+			dbmgr.Logger.Infof("Table:%s - would delete, created:%s - items:%d - size:%d", tableName, created, aws.ToInt64(table.ItemCount), aws.ToInt64(table.TableSizeBytes))
+			deletedTables = append(deletedTables, tableName)
+			continue
+		}
+
+		if !ConfirmClient(fmt.Sprintf("Type the table name to confirm deleting table:%s and all of its items: ", tableName), tableName) {
+			dbmgr.Logger.Warnf("Table:%s - confirmation didn't match the table name, not deleted", tableName)
+			skippedTables = append(skippedTables, tableName)
+			continue
+		}
+
+		if options.FinalBackup {
+			backup, err := CreateBackupClient(dbmgr, tableName, client.BackupName(tableName, FinalBackupLabel, time.Now()))
+			if err != nil {
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+			if err := WaitForBackupClient(dbmgr, aws.ToString(backup.BackupArn), BackupWaitTimeout); err != nil {
+				dbmgr.Logger.Errorf("Final backup of table:%s is not available, table not deleted due to: %v", tableName, err)
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+			dbmgr.Logger.Infof("Final backup:%s of table:%s is available - arn:%s", aws.ToString(backup.BackupName), tableName, aws.ToString(backup.BackupArn))
+		}
+
+		if err := DeleteTableClient(dbmgr, tableName); err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		deletedTables = append(deletedTables, tableName)
+	}
+
+	verb := "Deleted"
+	if options.DryRun {
+		verb = "Would delete"
+	}
+	dbmgr.Logger.Infof("%s %d of %d tables: %s", verb, len(deletedTables), len(targets), strings.Join(deletedTables, ","))
+	dbmgr.Logger.Infof("Skipped tables: %d, failed tables: %d", len(skippedTables), len(failedTables))
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to delete tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
+	aastypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+)
+
+// Scalable dimensions of DynamoDB tables and global secondary indexes
+const (
+	TableReadDimension  = aastypes.ScalableDimensionDynamoDBTableReadCapacityUnits
+	TableWriteDimension = aastypes.ScalableDimensionDynamoDBTableWriteCapacityUnits
+	IndexReadDimension  = aastypes.ScalableDimensionDynamoDBIndexReadCapacityUnits
+	IndexWriteDimension = aastypes.ScalableDimensionDynamoDBIndexWriteCapacityUnits
+)
+
+var AutoScalingNewFromConfig = applicationautoscaling.NewFromConfig
+
+// ScalingResourceId returns the Application Auto Scaling resource id of a table, or of one of its indexes
+// if an index name is given.
+func ScalingResourceId(tableName string, indexName string) string {
+	if indexName == "" {
+		return fmt.Sprintf("table/%s", tableName)
+	}
+	return fmt.Sprintf("table/%s/index/%s", tableName, indexName)
+}
+
+// GetScalableTargets retrieves the auto scaling targets registered for the given resource ids.
+// It returns the scalable targets and an error.
+func GetScalableTargets(dbmgr *DynamoDBManager, resourceIds []string) ([]aastypes.ScalableTarget, error) {
+	input := &applicationautoscaling.DescribeScalableTargetsInput{
+		ServiceNamespace: aastypes.ServiceNamespaceDynamodb,
+		ResourceIds:      resourceIds,
+	}
+
+	var targets []aastypes.ScalableTarget
+	paginator := applicationautoscaling.NewDescribeScalableTargetsPaginator(AutoScalingNewFromConfig(dbmgr.AwsConfig), input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.Background())
+		if err != nil {
+			dbmgr.Logger.Errorf("Error calling DescribeScalableTargets for resources:%v: %v", resourceIds, err)
+			return nil, err
+		}
+		targets = append(targets, output.ScalableTargets...)
+	}
+	return targets, nil
+}
+
+// GetScalingPolicies retrieves the scaling policies attached to a resource.
+// It returns the scaling policies and an error.
+func GetScalingPolicies(dbmgr *DynamoDBManager, resourceId string) ([]aastypes.ScalingPolicy, error) {
+	input := &applicationautoscaling.DescribeScalingPoliciesInput{
+		ServiceNamespace: aastypes.ServiceNamespaceDynamodb,
+		ResourceId:       aws.String(resourceId),
+	}
+
+	var policies []aastypes.ScalingPolicy
+	paginator := applicationautoscaling.NewDescribeScalingPoliciesPaginator(AutoScalingNewFromConfig(dbmgr.AwsConfig), input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.Background())
+		if err != nil {
+			dbmgr.Logger.Errorf("Error calling DescribeScalingPolicies for resource:%s: %v", resourceId, err)
+			return nil, err
+		}
+		policies = append(policies, output.ScalingPolicies...)
+	}
+	return policies, nil
+}
+
+// RegisterScalableTarget registers a table or index dimension for auto scaling, or changes its capacity limits.
+// It returns an error if the registration fails.
+func RegisterScalableTarget(dbmgr *DynamoDBManager, resourceId string, dimension aastypes.ScalableDimension, minCapacity int32, maxCapacity int32) error {
+	input := &applicationautoscaling.RegisterScalableTargetInput{
+		ServiceNamespace:  aastypes.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(resourceId),
+		ScalableDimension: dimension,
+		MinCapacity:       aws.Int32(minCapacity),
+		MaxCapacity:       aws.Int32(maxCapacity),
+	}
+
+	_, err := AutoScalingNewFromConfig(dbmgr.AwsConfig).RegisterScalableTarget(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error registering scalable target:%s %s: %v", resourceId, dimension, err)
+	} else {
+		dbmgr.Logger.Infof("Registered scalable target:%s %s - min: %d, max: %d", resourceId, dimension, minCapacity, maxCapacity)
+	}
+	return err
+}
+
+// PutTargetTrackingPolicy creates or replaces the target tracking policy keeping the capacity utilization
+// of a table or index dimension at the given percentage.
+// It returns an error if the policy can't be written.
+func PutTargetTrackingPolicy(dbmgr *DynamoDBManager, resourceId string, dimension aastypes.ScalableDimension, policyName string, targetUtilization float64) error {
+	metric := aastypes.MetricTypeDynamoDBReadCapacityUtilization
+	if dimension == TableWriteDimension || dimension == IndexWriteDimension {
+		metric = aastypes.MetricTypeDynamoDBWriteCapacityUtilization
+	}
+
+	input := &applicationautoscaling.PutScalingPolicyInput{
+		ServiceNamespace:  aastypes.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(resourceId),
+		ScalableDimension: dimension,
+		PolicyName:        aws.String(policyName),
+		PolicyType:        aastypes.PolicyTypeTargetTrackingScaling,
+		TargetTrackingScalingPolicyConfiguration: &aastypes.TargetTrackingScalingPolicyConfiguration{
+			TargetValue: aws.Float64(targetUtilization),
+			PredefinedMetricSpecification: &aastypes.PredefinedMetricSpecification{
+				PredefinedMetricType: metric,
+			},
+		},
+	}
+
+	_, err := AutoScalingNewFromConfig(dbmgr.AwsConfig).PutScalingPolicy(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error putting scaling policy:%s for %s %s: %v", policyName, resourceId, dimension, err)
+	} else {
+		dbmgr.Logger.Infof("Scaling policy:%s for %s %s - target utilization: %.1f%%", policyName, resourceId, dimension, targetUtilization)
+	}
+	return err
+}
+
+// DeregisterScalableTarget stops auto scaling a table or index dimension, deleting its scaling policies.
+// It returns an error if the deregistration fails.
+func DeregisterScalableTarget(dbmgr *DynamoDBManager, resourceId string, dimension aastypes.ScalableDimension) error {
+	input := &applicationautoscaling.DeregisterScalableTargetInput{
+		ServiceNamespace:  aastypes.ServiceNamespaceDynamodb,
+		ResourceId:        aws.String(resourceId),
+		ScalableDimension: dimension,
+	}
+
+	_, err := AutoScalingNewFromConfig(dbmgr.AwsConfig).DeregisterScalableTarget(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error deregistering scalable target:%s %s: %v", resourceId, dimension, err)
+	} else {
+		dbmgr.Logger.Infof("Deregistered scalable target:%s %s", resourceId, dimension)
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// invalidBackupNameChars matches the characters DynamoDB doesn't accept in backup names.
+var invalidBackupNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// BackupName builds the name of an on-demand backup of a table: TABLE-LABEL-YYYYMMDD-HHMMSS in UTC,
+// with the characters DynamoDB doesn't accept replaced and the length capped at the 255 characters it allows.
+func BackupName(tableName string, label string, now time.Time) string {
+	suffix := "-" + now.UTC().Format("20060102-150405")
+	if label != "" {
+		suffix = "-" + invalidBackupNameChars.ReplaceAllString(label, "-") + suffix
+	}
+	if len(tableName)+len(suffix) > 255 {
+		tableName = tableName[:255-len(suffix)]
+	}
+	return tableName + suffix
+}
+
+// CreateBackup creates an on-demand backup of a DynamoDB table.
+// It returns the backup details and an error.
+func CreateBackup(dbmgr *DynamoDBManager, tableName string, backupName string) (*types.BackupDetails, error) {
+	input := &dynamodb.CreateBackupInput{
+		TableName:  aws.String(tableName),
+		BackupName: aws.String(backupName),
+	}
+
+	output, err := dbmgr.DynamoDBClient.CreateBackup(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error creating backup:%s of table:%s: %v", backupName, tableName, err)
+		return nil, err
+	}
+	dbmgr.Logger.Infof("Backup created for table:%s - name: %s, arn: %s", tableName, backupName, aws.ToString(output.BackupDetails.BackupArn))
+	return output.BackupDetails, nil
+}
+
+// ListBackups retrieves the on-demand backups of a DynamoDB table, or of all tables if no table name is given.
+// It returns the backup summaries and an error.
+func ListBackups(dbmgr *DynamoDBManager, tableName string) ([]types.BackupSummary, error) {
+	input := &dynamodb.ListBackupsInput{}
+	if tableName != "" {
+		input.TableName = aws.String(tableName)
+	}
+
+	var backups []types.BackupSummary
+	for {
+		output, err := dbmgr.DynamoDBClient.ListBackups(context.Background(), input)
+		if err != nil {
+			dbmgr.Logger.Errorf("Error calling ListBackups for table:%s: %v", tableName, err)
+			return nil, err
+		}
+		backups = append(backups, output.BackupSummaries...)
+		if output.LastEvaluatedBackupArn == nil {
+			return backups, nil
+		}
+		input.ExclusiveStartBackupArn = output.LastEvaluatedBackupArn
+	}
+}
+
+// DescribeBackup retrieves the description of an on-demand backup.
+// It returns the backup description and an error.
+func DescribeBackup(dbmgr *DynamoDBManager, backupArn string) (*types.BackupDescription, error) {
+	input := &dynamodb.DescribeBackupInput{
+		BackupArn: aws.String(backupArn),
+	}
+
+	output, err := dbmgr.DynamoDBClient.DescribeBackup(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe backup:%s, Here's why: %v\n", backupArn, err)
+		return nil, err
+	}
+	return output.BackupDescription, nil
+}
+
+// DeleteBackup deletes an on-demand backup.
+// It returns an error if the deletion fails.
+func DeleteBackup(dbmgr *DynamoDBManager, backupArn string) error {
+	input := &dynamodb.DeleteBackupInput{
+		BackupArn: aws.String(backupArn),
+	}
+
+	_, err := dbmgr.DynamoDBClient.DeleteBackup(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error deleting backup:%s: %v", backupArn, err)
+	} else {
+		dbmgr.Logger.Infof("Deleted backup:%s", backupArn)
+	}
+	return err
+}
+
+// RestoreTableFromBackup creates a new DynamoDB table from an on-demand backup.
+// It returns an error if the restore can't be started.
+func RestoreTableFromBackup(dbmgr *DynamoDBManager, backupArn string, targetTableName string) error {
+	input := &dynamodb.RestoreTableFromBackupInput{
+		BackupArn:       aws.String(backupArn),
+		TargetTableName: aws.String(targetTableName),
+	}
+
+	_, err := dbmgr.DynamoDBClient.RestoreTableFromBackup(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error restoring backup:%s to table:%s: %v", backupArn, targetTableName, err)
+	} else {
+		dbmgr.Logger.Infof("Restore of backup:%s to table:%s started", backupArn, targetTableName)
+	}
+	return err
+}
+
+// WaitForBackupAvailable polls an on-demand backup until it is AVAILABLE.
+// It returns an error if the backup can't be described, is deleted or doesn't become available within maxWait.
+func WaitForBackupAvailable(dbmgr *DynamoDBManager, backupArn string, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	for {
+		backup, err := DescribeBackup(dbmgr, backupArn)
+		if err != nil {
+			return err
+		}
+
+		status := backup.BackupDetails.BackupStatus
+		if status == types.BackupStatusAvailable {
+			return nil
+		}
+		if status == types.BackupStatusDeleted {
+			return errors.New(fmt.Sprintf("backup:%s was deleted while waiting for it", backupArn))
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("backup:%s did not become available within %v", backupArn, maxWait))
+		}
+		dbmgr.Logger.Debugf("Waiting for backup:%s to become available, status:%s", backupArn, status)
+		time.Sleep(WaitPollInterval)
+	}
+}
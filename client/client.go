@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/bazelgo/dynamodb-manager/logging"
 
@@ -19,35 +20,67 @@ const (
 	DefaultWcu = 5
 )
 
+// Limits DynamoDB applies to provisioned capacity decreases within a UTC day.
+// The first FreeDecreasesPerDay decreases can happen at any time, every further one
+// requires that no decrease happened during the preceding DecreaseInterval.
+const (
+	FreeDecreasesPerDay = 4
+	MaxDecreasesPerDay  = 27
+	DecreaseInterval    = time.Hour
+)
+
 // DynamoDBManager represents the DynamoDB manager in Go.
 type DynamoDBManager struct {
 	DynamoDBClient *dynamodb.Client // Add DynamoDB client
 	Logger         *logging.Logger
+	AwsConfig      aws.Config
+	Profile        string // AWS profile the config was loaded from, empty for the default chain
+	Principal      string // ARN of the caller, resolved lazily by GetCallerArn
+	JournalFile    string // journal recording every UpdateTable call, empty disables journaling
+	PriceFile      string // price table file for cost estimates, empty uses the default one or the built-in prices
+
+	RequireProtection bool // refuse destructive operations on tables without deletion protection
+	AllowUnprotected  bool // override RequireProtection, destructive operations on unprotected tables are only reported
 }
 
 var LoadConfig = config.LoadDefaultConfig
 var DBNewFromConfig = dynamodb.NewFromConfig
 var NewListTablesPageIt = dynamodb.NewListTablesPaginator
 
+// WaitPollInterval is the delay between two status checks while waiting for a table.
+var WaitPollInterval = 10 * time.Second
+
 // CreateNewDynamoDBManager creates a new DynamoDBManager instance based on the provided AWS profile name.
 // It returns a DynamoDBManager and an error.
 func CreateNewDynamoDBManager(profileName string) (*DynamoDBManager, error) {
-	var configToUse aws.Config
-	var err error
+	return CreateNewDynamoDBManagerInRegion(profileName, "")
+}
 
+// CreateNewDynamoDBManagerInRegion creates a new DynamoDBManager instance based on the provided AWS profile name
+// and region. An empty region uses the region of the profile.
+// It returns a DynamoDBManager and an error.
+func CreateNewDynamoDBManagerInRegion(profileName string, region string) (*DynamoDBManager, error) {
+	var options []func(*config.LoadOptions) error
 	if profileName != "" {
 		// For local test purpose
-		configToUse, err = LoadConfig(context.TODO(), config.WithSharedConfigProfile(profileName))
-	} else {
-		configToUse, err = LoadConfig(context.Background())
+		options = append(options, config.WithSharedConfigProfile(profileName))
+	}
+	if region != "" {
+		options = append(options, config.WithRegion(region))
 	}
 
+	configToUse, err := LoadConfig(context.Background(), options...)
 	if err != nil {
 		fmt.Printf("CreateNewDynamoDBManager-config.LoadDefaultConfig:%s", err)
 		return nil, errors.New("Failed to instantiate aws config!")
 	}
 
-	return NewDynamoDBManager(configToUse)
+	dbmgr, err := NewDynamoDBManager(configToUse)
+	if err != nil {
+		return nil, err
+	}
+	dbmgr.Profile = profileName
+	return dbmgr, nil
 }
 
 // NewDynamoDBManager creates a new DynamoDBManager instance with the given AWS config.
@@ -59,6 +92,7 @@ func NewDynamoDBManager(cfg ...aws.Config) (*DynamoDBManager, error) {
 	return &DynamoDBManager{
 		DynamoDBClient: DBNewFromConfig(cfg[0]),
 		Logger:         nil,
+		AwsConfig:      cfg[0],
 	}, nil
 }
 
@@ -138,9 +172,7 @@ func GetCurrentBillingMode(dbmgr *DynamoDBManager, tableName string) (string, st
 		return "", "", "", err
 	}
 
-	if output.Table.BillingModeSummary != nil {
-		billingMode = fmt.Sprintf("%v", output.Table.BillingModeSummary.BillingMode)
-	}
+	billingMode = BillingModeOf(output.Table)
 
 	if billingMode == "PROVISIONED" {
 		rcu = fmt.Sprintf("%d", aws.ToInt64(output.Table.ProvisionedThroughput.ReadCapacityUnits))
@@ -150,6 +182,42 @@ func GetCurrentBillingMode(dbmgr *DynamoDBManager, tableName string) (string, st
 	return billingMode, rcu, wcu, nil
 }
 
+// GetCapacityDecreaseInfo retrieves the number of provisioned capacity decreases done today and
+// the time of the last decrease of a DynamoDB table.
+// It returns the number of decreases, the last decrease time (nil if never decreased), and an error.
+func GetCapacityDecreaseInfo(dbmgr *DynamoDBManager, tableName string) (int64, *time.Time, error) {
+	input := &dynamodb.DescribeTableInput{
+		TableName: &tableName,
+	}
+
+	output, err := dbmgr.DynamoDBClient.DescribeTable(context.Background(), input)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if output.Table.ProvisionedThroughput == nil {
+		return 0, nil, nil
+	}
+
+	return aws.ToInt64(output.Table.ProvisionedThroughput.NumberOfDecreasesToday), output.Table.ProvisionedThroughput.LastDecreaseDateTime, nil
+}
+
+// RemainingCapacityDecreases calculates how many provisioned capacity decreases are left for today
+// and whether a decrease is allowed at the given time.
+// It returns the remaining decreases and true if a decrease can be done right now.
+func RemainingCapacityDecreases(decreasesToday int64, lastDecrease *time.Time, now time.Time) (int64, bool) {
+	remaining := int64(MaxDecreasesPerDay) - decreasesToday
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	if decreasesToday < FreeDecreasesPerDay || lastDecrease == nil {
+		return remaining, true
+	}
+
+	return remaining, now.Sub(*lastDecrease) >= DecreaseInterval
+}
+
 // UpdateProvisionedCapacity updates the provisioned capacity of a DynamoDB table.
 // It returns an error if the update fails.
 func UpdateProvisionedCapacity(dbmgr *DynamoDBManager, switchToProvisioned bool, tableName string, rcuStr string, wcuStr string) error {
@@ -192,7 +260,7 @@ func UpdateProvisionedCapacity(dbmgr *DynamoDBManager, switchToProvisioned bool,
 		}
 	}
 
-	_, err := dbmgr.DynamoDBClient.UpdateTable(context.Background(), input)
+	_, err := updateTable(dbmgr, input)
 	if err != nil {
 		dbmgr.Logger.Errorf("Error updating provisioned capacity: %v", err)
 	} else {
@@ -202,6 +270,72 @@ func UpdateProvisionedCapacity(dbmgr *DynamoDBManager, switchToProvisioned bool,
 	return err
 }
 
+// SwitchToProvisionedCapacity switches an on-demand table to provisioned mode with the given capacity.
+// DynamoDB requires the capacity of every global secondary index in the same call, gsis provides it.
+// It returns an error if the update fails.
+func SwitchToProvisionedCapacity(dbmgr *DynamoDBManager, tableName string, rcu int64, wcu int64, gsis []GsiState) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName:   aws.String(tableName),
+		BillingMode: types.BillingModeProvisioned,
+		ProvisionedThroughput: &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(rcu),
+			WriteCapacityUnits: aws.Int64(wcu),
+		},
+	}
+	for _, gsi := range gsis {
+		input.GlobalSecondaryIndexUpdates = append(input.GlobalSecondaryIndexUpdates, types.GlobalSecondaryIndexUpdate{
+			Update: &types.UpdateGlobalSecondaryIndexAction{
+				IndexName: aws.String(gsi.IndexName),
+				ProvisionedThroughput: &types.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(gsi.Rcu),
+					WriteCapacityUnits: aws.Int64(gsi.Wcu),
+				},
+			},
+		})
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error switching table:%s to provisioned capacity: %v", tableName, err)
+		return err
+	}
+	dbmgr.Logger.Infof("Switched to provisioned capacity for table:%s - RCU: %d, WCU: %d", tableName, rcu, wcu)
+	for _, gsi := range gsis {
+		dbmgr.Logger.Infof("Provisioned capacity set for index:%s on table:%s - RCU: %d, WCU: %d", gsi.IndexName, tableName, gsi.Rcu, gsi.Wcu)
+	}
+	return nil
+}
+
+// WaitForTableActive polls a DynamoDB table until the table and all of its global secondary indexes and replicas are ACTIVE.
+// It returns an error if the table can't be described or doesn't become active within maxWait.
+func WaitForTableActive(dbmgr *DynamoDBManager, tableName string, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	for {
+		output, err := dbmgr.DynamoDBClient.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to describe table:%s while waiting, Here's why: %v\n", tableName, err)
+			return err
+		}
+
+		active := output.Table.TableStatus == types.TableStatusActive
+		for _, gsi := range output.Table.GlobalSecondaryIndexes {
+			active = active && gsi.IndexStatus == types.IndexStatusActive
+		}
+		for _, replica := range output.Table.Replicas {
+			active = active && replica.ReplicaStatus == types.ReplicaStatusActive
+		}
+		if active {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("table:%s did not become active within %v", tableName, maxWait))
+		}
+		dbmgr.Logger.Debugf("Waiting for table:%s to become active, status:%s", tableName, output.Table.TableStatus)
+		time.Sleep(WaitPollInterval)
+	}
+}
+
 // SwitchToOnDemandCapacity switches a DynamoDB table to on-demand capacity mode.
 // It returns an error if the switch fails.
 func SwitchToOnDemandCapacity(dbmgr *DynamoDBManager, tableName string) error {
@@ -210,7 +344,7 @@ func SwitchToOnDemandCapacity(dbmgr *DynamoDBManager, tableName string) error {
 		BillingMode: types.BillingModePayPerRequest,
 	}
 
-	_, err := dbmgr.DynamoDBClient.UpdateTable(context.TODO(), input)
+	_, err := updateTable(dbmgr, input)
 	if err != nil {
 		dbmgr.Logger.Errorf("error switching to on-demand capacity: %v", err)
 	} else {
@@ -219,3 +353,260 @@ func SwitchToOnDemandCapacity(dbmgr *DynamoDBManager, tableName string) error {
 
 	return err
 }
+
+// CreateTable creates a DynamoDB table.
+// It returns an error if the table can't be created.
+func CreateTable(dbmgr *DynamoDBManager, input *dynamodb.CreateTableInput) error {
+	output, err := dbmgr.DynamoDBClient.CreateTable(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error creating table:%s: %v", aws.ToString(input.TableName), err)
+		return err
+	}
+	dbmgr.Logger.Infof("Table created:%s - arn: %s, status: %s", aws.ToString(input.TableName), aws.ToString(output.TableDescription.TableArn), output.TableDescription.TableStatus)
+	return nil
+}
+
+// DeleteTable deletes a DynamoDB table and all of its items.
+// It returns an error if the deletion fails.
+func DeleteTable(dbmgr *DynamoDBManager, tableName string) error {
+	input := &dynamodb.DeleteTableInput{
+		TableName: aws.String(tableName),
+	}
+
+	_, err := dbmgr.DynamoDBClient.DeleteTable(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error deleting table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Deleted table:%s", tableName)
+	}
+	return err
+}
+
+// DescribeTable retrieves the full description of a DynamoDB table.
+// It returns the table description and an error.
+func DescribeTable(dbmgr *DynamoDBManager, tableName string) (*types.TableDescription, error) {
+	input := &dynamodb.DescribeTableInput{
+		TableName: aws.String(tableName),
+	}
+
+	output, err := dbmgr.DynamoDBClient.DescribeTable(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s, Here's why: %v\n", tableName, err)
+		return nil, err
+	}
+	return output.Table, nil
+}
+
+// UpdateGsiProvisionedCapacity updates the provisioned capacity of a global secondary index.
+// It returns an error if the update fails.
+func UpdateGsiProvisionedCapacity(dbmgr *DynamoDBManager, tableName string, indexName string, rcu int64, wcu int64) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName: aws.String(tableName),
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Update: &types.UpdateGlobalSecondaryIndexAction{
+					IndexName: aws.String(indexName),
+					ProvisionedThroughput: &types.ProvisionedThroughput{
+						ReadCapacityUnits:  aws.Int64(rcu),
+						WriteCapacityUnits: aws.Int64(wcu),
+					},
+				},
+			},
+		},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating provisioned capacity of index:%s on table:%s: %v", indexName, tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Provisioned capacity updated for index:%s on table:%s - RCU: %d, WCU: %d", indexName, tableName, rcu, wcu)
+	}
+	return err
+}
+
+// UpdateOnDemandThroughput sets the maximum read and write request units of an on-demand table.
+// A value of -1 removes the limit.
+// It returns an error if the update fails.
+func UpdateOnDemandThroughput(dbmgr *DynamoDBManager, tableName string, maxReadRequestUnits int64, maxWriteRequestUnits int64) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName: aws.String(tableName),
+		OnDemandThroughput: &types.OnDemandThroughput{
+			MaxReadRequestUnits:  aws.Int64(maxReadRequestUnits),
+			MaxWriteRequestUnits: aws.Int64(maxWriteRequestUnits),
+		},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating on-demand throughput of table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("On-demand throughput updated for table:%s - max read: %d, max write: %d", tableName, maxReadRequestUnits, maxWriteRequestUnits)
+	}
+	return err
+}
+
+// UpdateTableClass switches a DynamoDB table to the given table class (STANDARD or STANDARD_INFREQUENT_ACCESS).
+// It returns an error if the update fails.
+func UpdateTableClass(dbmgr *DynamoDBManager, tableName string, tableClass string) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName:  aws.String(tableName),
+		TableClass: types.TableClass(tableClass),
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating table class of table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Table class updated for table:%s - class: %s", tableName, tableClass)
+	}
+	return err
+}
+
+// UpdateDeletionProtection enables or disables the deletion protection of a DynamoDB table.
+// It returns an error if the update fails.
+func UpdateDeletionProtection(dbmgr *DynamoDBManager, tableName string, enabled bool) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName:                 aws.String(tableName),
+		DeletionProtectionEnabled: aws.Bool(enabled),
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating deletion protection of table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Deletion protection updated for table:%s - enabled: %t", tableName, enabled)
+	}
+	return err
+}
+
+// UpdateStream enables the stream of a DynamoDB table with the given view type, or disables it.
+// It returns an error if the update fails.
+func UpdateStream(dbmgr *DynamoDBManager, tableName string, enabled bool, viewType string) error {
+	specification := &types.StreamSpecification{StreamEnabled: aws.Bool(enabled)}
+	if enabled {
+		specification.StreamViewType = types.StreamViewType(viewType)
+	}
+	input := &dynamodb.UpdateTableInput{
+		TableName:           aws.String(tableName),
+		StreamSpecification: specification,
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating stream of table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Stream updated for table:%s - enabled: %t, view type: %s", tableName, enabled, viewType)
+	}
+	return err
+}
+
+// GetContinuousBackups retrieves the point-in-time recovery settings of a DynamoDB table.
+// It returns the point-in-time recovery description and an error.
+func GetContinuousBackups(dbmgr *DynamoDBManager, tableName string) (*types.PointInTimeRecoveryDescription, error) {
+	input := &dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(tableName),
+	}
+
+	output, err := dbmgr.DynamoDBClient.DescribeContinuousBackups(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error calling DescribeContinuousBackups for table:%s: %v", tableName, err)
+		return nil, err
+	}
+
+	if output.ContinuousBackupsDescription == nil || output.ContinuousBackupsDescription.PointInTimeRecoveryDescription == nil {
+		return &types.PointInTimeRecoveryDescription{PointInTimeRecoveryStatus: types.PointInTimeRecoveryStatusDisabled}, nil
+	}
+	return output.ContinuousBackupsDescription.PointInTimeRecoveryDescription, nil
+}
+
+// UpdateContinuousBackups enables or disables point-in-time recovery of a DynamoDB table.
+// It returns an error if the update fails.
+func UpdateContinuousBackups(dbmgr *DynamoDBManager, tableName string, enabled bool) error {
+	input := &dynamodb.UpdateContinuousBackupsInput{
+		TableName: aws.String(tableName),
+		PointInTimeRecoverySpecification: &types.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: aws.Bool(enabled),
+		},
+	}
+
+	_, err := dbmgr.DynamoDBClient.UpdateContinuousBackups(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating point-in-time recovery of table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Point-in-time recovery updated for table:%s - enabled: %t", tableName, enabled)
+	}
+	return err
+}
+
+// GetTimeToLive retrieves the Time to Live settings of a DynamoDB table.
+// It returns the Time to Live description and an error.
+func GetTimeToLive(dbmgr *DynamoDBManager, tableName string) (*types.TimeToLiveDescription, error) {
+	input := &dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(tableName),
+	}
+
+	output, err := dbmgr.DynamoDBClient.DescribeTimeToLive(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error calling DescribeTimeToLive for table:%s: %v", tableName, err)
+		return nil, err
+	}
+
+	if output.TimeToLiveDescription == nil {
+		return &types.TimeToLiveDescription{TimeToLiveStatus: types.TimeToLiveStatusDisabled}, nil
+	}
+	return output.TimeToLiveDescription, nil
+}
+
+// UpdateTimeToLive enables or disables Time to Live on the given attribute of a DynamoDB table.
+// It returns an error if the update fails.
+func UpdateTimeToLive(dbmgr *DynamoDBManager, tableName string, attributeName string, enabled bool) error {
+	input := &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(attributeName),
+			Enabled:       aws.Bool(enabled),
+		},
+	}
+
+	_, err := dbmgr.DynamoDBClient.UpdateTimeToLive(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating time to live of table:%s: %v", tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Time to live updated for table:%s - attribute: %s, enabled: %t", tableName, attributeName, enabled)
+	}
+	return err
+}
+
+// TagTable adds or overwrites the given tags on the DynamoDB table with the given ARN.
+// It returns an error if tagging fails.
+func TagTable(dbmgr *DynamoDBManager, tableArn string, tags []types.Tag) error {
+	input := &dynamodb.TagResourceInput{
+		ResourceArn: aws.String(tableArn),
+		Tags:        tags,
+	}
+
+	_, err := dbmgr.DynamoDBClient.TagResource(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error calling TagResource for arn:%s: %v", tableArn, err)
+	} else {
+		dbmgr.Logger.Infof("Tagged arn:%s with %d tags", tableArn, len(tags))
+	}
+	return err
+}
+
+// UntagTable removes the tags with the given keys from the DynamoDB table with the given ARN.
+// It returns an error if untagging fails.
+func UntagTable(dbmgr *DynamoDBManager, tableArn string, tagKeys []string) error {
+	input := &dynamodb.UntagResourceInput{
+		ResourceArn: aws.String(tableArn),
+		TagKeys:     tagKeys,
+	}
+
+	_, err := dbmgr.DynamoDBClient.UntagResource(context.Background(), input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error calling UntagResource for arn:%s: %v", tableArn, err)
+	} else {
+		dbmgr.Logger.Infof("Removed %d tags from arn:%s", len(tagKeys), tableArn)
+	}
+	return err
+}
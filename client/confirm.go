@@ -0,0 +1,34 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ConfirmInput is where the typed confirmations are read from.
+var ConfirmInput io.Reader = os.Stdin
+
+// ConfirmOutput is where the confirmation prompts are written.
+var ConfirmOutput io.Writer = os.Stdout
+
+// confirmReader buffers ConfirmInput across confirmations, so that no typed line is lost between two of them.
+var confirmReader *bufio.Reader
+var confirmSource io.Reader
+
+// Confirm writes the prompt and reads a line typed in answer, e.g. the name of the table about to be deleted.
+// It returns whether the typed line, without surrounding whitespace, matches the expected answer.
+func Confirm(prompt string, expected string) bool {
+	if confirmReader == nil || confirmSource != ConfirmInput {
+		confirmReader, confirmSource = bufio.NewReader(ConfirmInput), ConfirmInput
+	}
+
+	fmt.Fprint(ConfirmOutput, prompt)
+	answer, err := confirmReader.ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	return strings.TrimSpace(answer) == expected
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// CreateGlobalSecondaryIndex adds a global secondary index to a DynamoDB table. The attribute definitions must
+// cover the key attributes of the index. DynamoDB backfills the index from the existing items.
+// It returns an error if the index can't be created.
+func CreateGlobalSecondaryIndex(dbmgr *DynamoDBManager, tableName string, attributes []types.AttributeDefinition, index types.GlobalSecondaryIndex) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName:            aws.String(tableName),
+		AttributeDefinitions: attributes,
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Create: &types.CreateGlobalSecondaryIndexAction{
+					IndexName:             index.IndexName,
+					KeySchema:             index.KeySchema,
+					Projection:            index.Projection,
+					ProvisionedThroughput: index.ProvisionedThroughput,
+					OnDemandThroughput:    index.OnDemandThroughput,
+				},
+			},
+		},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error creating index:%s on table:%s: %v", aws.ToString(index.IndexName), tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Index:%s is being created on table:%s", aws.ToString(index.IndexName), tableName)
+	}
+	return err
+}
+
+// DeleteGlobalSecondaryIndex removes a global secondary index from a DynamoDB table.
+// It returns an error if the index can't be deleted.
+func DeleteGlobalSecondaryIndex(dbmgr *DynamoDBManager, tableName string, indexName string) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName: aws.String(tableName),
+		GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{
+			{
+				Delete: &types.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(indexName)},
+			},
+		},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error deleting index:%s on table:%s: %v", indexName, tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Index:%s is being deleted from table:%s", indexName, tableName)
+	}
+	return err
+}
+
+// WaitForIndexActive polls a global secondary index until it is ACTIVE and done backfilling, logging the progress.
+// It returns an error if the index can't be described, disappears or isn't active within maxWait.
+func WaitForIndexActive(dbmgr *DynamoDBManager, tableName string, indexName string, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	for {
+		output, err := dbmgr.DynamoDBClient.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to describe table:%s while waiting, Here's why: %v\n", tableName, err)
+			return err
+		}
+
+		var index *types.GlobalSecondaryIndexDescription
+		for i := range output.Table.GlobalSecondaryIndexes {
+			if aws.ToString(output.Table.GlobalSecondaryIndexes[i].IndexName) == indexName {
+				index = &output.Table.GlobalSecondaryIndexes[i]
+			}
+		}
+		if index == nil {
+			return errors.New(fmt.Sprintf("index:%s not found on table:%s", indexName, tableName))
+		}
+		if index.IndexStatus == types.IndexStatusActive && !aws.ToBool(index.Backfilling) {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("index:%s of table:%s did not become active within %v", indexName, tableName, maxWait))
+		}
+		dbmgr.Logger.Infof("Waiting for index:%s of table:%s - status:%s - backfilling:%t - items:%d",
+			indexName, tableName, index.IndexStatus, aws.ToBool(index.Backfilling), aws.ToInt64(index.ItemCount))
+		time.Sleep(WaitPollInterval)
+	}
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// JournalEntry records a single UpdateTable call made by the tool.
+type JournalEntry struct {
+	ID        string     `json:"id"`
+	Timestamp time.Time  `json:"timestamp"`
+	Profile   string     `json:"profile,omitempty"`
+	Principal string     `json:"principal,omitempty"`
+	TableName string     `json:"tableName"`
+	Before    TableState `json:"before"`
+	After     TableState `json:"after"`
+}
+
+var STSNewFromConfig = sts.NewFromConfig
+
+// DefaultJournalFile returns the journal location used when none is configured: ~/.dynamodb-manager/journal.jsonl
+func DefaultJournalFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".dynamodb-manager", "journal.jsonl")
+	}
+	return filepath.Join(home, ".dynamodb-manager", "journal.jsonl")
+}
+
+// GetCallerArn retrieves the ARN of the AWS principal the manager acts as, caching it for later calls.
+// It returns the ARN and an error.
+func GetCallerArn(dbmgr *DynamoDBManager) (string, error) {
+	if dbmgr.Principal != "" {
+		return dbmgr.Principal, nil
+	}
+
+	output, err := STSNewFromConfig(dbmgr.AwsConfig).GetCallerIdentity(context.Background(), nil)
+	if err != nil {
+		return "", err
+	}
+	dbmgr.Principal = aws.ToString(output.Arn)
+	return dbmgr.Principal, nil
+}
+
+// applyUpdateInput derives the state a table ends up in once an UpdateTable request is applied to it.
+func applyUpdateInput(before *TableState, input *dynamodb.UpdateTableInput) TableState {
+	after := *before
+	after.Gsis = append([]GsiState(nil), before.Gsis...)
+
+	if input.BillingMode != "" {
+		after.BillingMode = string(input.BillingMode)
+	}
+	if after.BillingMode == string(types.BillingModePayPerRequest) {
+		after.Rcu, after.Wcu = 0, 0
+		for i := range after.Gsis {
+			after.Gsis[i].Rcu, after.Gsis[i].Wcu = 0, 0
+		}
+	} else {
+		after.MaxReadRequestUnits, after.MaxWriteRequestUnits = 0, 0
+	}
+	if input.ProvisionedThroughput != nil {
+		after.Rcu = aws.ToInt64(input.ProvisionedThroughput.ReadCapacityUnits)
+		after.Wcu = aws.ToInt64(input.ProvisionedThroughput.WriteCapacityUnits)
+	}
+	if input.OnDemandThroughput != nil {
+		after.MaxReadRequestUnits = aws.ToInt64(input.OnDemandThroughput.MaxReadRequestUnits)
+		after.MaxWriteRequestUnits = aws.ToInt64(input.OnDemandThroughput.MaxWriteRequestUnits)
+	}
+	for _, gsiUpdate := range input.GlobalSecondaryIndexUpdates {
+		if create := gsiUpdate.Create; create != nil {
+			gsi := GsiState{IndexName: aws.ToString(create.IndexName)}
+			if create.ProvisionedThroughput != nil {
+				gsi.Rcu = aws.ToInt64(create.ProvisionedThroughput.ReadCapacityUnits)
+				gsi.Wcu = aws.ToInt64(create.ProvisionedThroughput.WriteCapacityUnits)
+			}
+			after.Gsis = append(after.Gsis, gsi)
+			continue
+		}
+		if gsiUpdate.Delete != nil {
+			for i := range after.Gsis {
+				if after.Gsis[i].IndexName == aws.ToString(gsiUpdate.Delete.IndexName) {
+					after.Gsis = append(after.Gsis[:i], after.Gsis[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		if gsiUpdate.Update == nil || gsiUpdate.Update.ProvisionedThroughput == nil {
+			continue
+		}
+		if gsi := after.Gsi(aws.ToString(gsiUpdate.Update.IndexName)); gsi != nil {
+			gsi.Rcu = aws.ToInt64(gsiUpdate.Update.ProvisionedThroughput.ReadCapacityUnits)
+			gsi.Wcu = aws.ToInt64(gsiUpdate.Update.ProvisionedThroughput.WriteCapacityUnits)
+		}
+	}
+	if input.TableClass != "" {
+		after.TableClass = string(input.TableClass)
+	}
+	if input.DeletionProtectionEnabled != nil {
+		after.DeletionProtection = aws.ToBool(input.DeletionProtectionEnabled)
+	}
+	after.Replicas = append(before.Replicas[:0:0], before.Replicas...)
+	for _, replicaUpdate := range input.ReplicaUpdates {
+		if create := replicaUpdate.Create; create != nil {
+			replica := ReplicaState{Region: aws.ToString(create.RegionName), TableClass: string(create.TableClassOverride)}
+			if create.ProvisionedThroughputOverride != nil {
+				replica.ReadCapacity = aws.ToInt64(create.ProvisionedThroughputOverride.ReadCapacityUnits)
+			}
+			after.Replicas = append(after.Replicas, replica)
+			continue
+		}
+		if replicaUpdate.Delete != nil {
+			for i := range after.Replicas {
+				if after.Replicas[i].Region == aws.ToString(replicaUpdate.Delete.RegionName) {
+					after.Replicas = append(after.Replicas[:i], after.Replicas[i+1:]...)
+					break
+				}
+			}
+			continue
+		}
+		if replicaUpdate.Update == nil {
+			continue
+		}
+		if replica := after.Replica(aws.ToString(replicaUpdate.Update.RegionName)); replica != nil {
+			if replicaUpdate.Update.TableClassOverride != "" {
+				replica.TableClass = string(replicaUpdate.Update.TableClassOverride)
+			}
+			if replicaUpdate.Update.ProvisionedThroughputOverride != nil {
+				replica.ReadCapacity = aws.ToInt64(replicaUpdate.Update.ProvisionedThroughputOverride.ReadCapacityUnits)
+			}
+		}
+	}
+	if stream := input.StreamSpecification; stream != nil {
+		after.StreamViewType = "DISABLED"
+		if aws.ToBool(stream.StreamEnabled) {
+			after.StreamViewType = string(stream.StreamViewType)
+		}
+	}
+	return after
+}
+
+// updateTable calls UpdateTable and, if a journal file is configured, records the table state before and after the call.
+// A journal that can't be written is reported but doesn't fail the update.
+// It returns the UpdateTable output and an error.
+func updateTable(dbmgr *DynamoDBManager, input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	if dbmgr.JournalFile == "" {
+		return dbmgr.DynamoDBClient.UpdateTable(context.Background(), input)
+	}
+
+	tableName := aws.ToString(input.TableName)
+	described, err := dbmgr.DynamoDBClient.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: input.TableName})
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := dbmgr.DynamoDBClient.UpdateTable(context.Background(), input)
+	if err != nil {
+		return output, err
+	}
+
+	before := TableStateFromDescription(tableName, described.Table)
+	entry := JournalEntry{
+		ID:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		Timestamp: time.Now().UTC(),
+		Profile:   dbmgr.Profile,
+		TableName: tableName,
+		Before:    *before,
+		After:     applyUpdateInput(before, input),
+	}
+	if principal, errPrincipal := GetCallerArn(dbmgr); errPrincipal != nil {
+		dbmgr.Logger.Warnf("Failed to get the caller identity for the journal: %v", errPrincipal)
+	} else {
+		entry.Principal = principal
+	}
+
+	if errJournal := AppendJournalEntry(dbmgr.JournalFile, entry); errJournal != nil {
+		dbmgr.Logger.Warnf("Failed to record change of table:%s in journal:%s due to: %v", tableName, dbmgr.JournalFile, errJournal)
+	} else {
+		dbmgr.Logger.Infof("Recorded change:%s of table:%s in journal:%s", entry.ID, tableName, dbmgr.JournalFile)
+	}
+	return output, nil
+}
+
+// AppendJournalEntry appends an entry as a JSON line to the journal file, creating the file if needed.
+// It returns an error if the entry can't be written.
+func AppendJournalEntry(journalFile string, entry JournalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(journalFile), 0755); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(journalFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadJournal reads every entry of a journal file in the order they were recorded.
+// A missing journal file holds no entries.
+// It returns the entries and an error.
+func ReadJournal(journalFile string) ([]JournalEntry, error) {
+	file, err := os.Open(journalFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid journal entry at %s:%d - error:%v", journalFile, lineNo, err))
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
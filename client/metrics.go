@@ -0,0 +1,207 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+)
+
+// DynamoDB metrics in CloudWatch
+const (
+	MetricsNamespace           = "AWS/DynamoDB"
+	ConsumedReadCapacityUnits  = "ConsumedReadCapacityUnits"
+	ConsumedWriteCapacityUnits = "ConsumedWriteCapacityUnits"
+	ReadThrottleEvents         = "ReadThrottleEvents"
+	WriteThrottleEvents        = "WriteThrottleEvents"
+)
+
+// MetricsPeriod is the period DynamoDB metrics are summed over for usage analysis.
+const MetricsPeriod = 5 * time.Minute
+
+// ActivityPeriod is the period DynamoDB metrics are summed over to check whether a table is used at all.
+// CloudWatch keeps hourly datapoints for 455 days, 5 minute ones only for 63 days.
+const ActivityPeriod = time.Hour
+
+var CloudWatchNewFromConfig = cloudwatch.NewFromConfig
+
+// MetricDatapoint is the sum of a metric over one period starting at Timestamp.
+type MetricDatapoint struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Sum       float64   `json:"sum" yaml:"sum"`
+}
+
+// MetricsSource provides the CloudWatch metrics of DynamoDB tables and global secondary indexes.
+type MetricsSource interface {
+	// GetMetricSums returns the sums of a metric per period between start and end, oldest first,
+	// for a table or, if an index name is given, for one of its global secondary indexes.
+	GetMetricSums(tableName string, indexName string, metricName string, start time.Time, end time.Time, period time.Duration) ([]MetricDatapoint, error)
+}
+
+// CloudWatchMetrics is the MetricsSource reading from CloudWatch.
+type CloudWatchMetrics struct {
+	dbmgr  *DynamoDBManager
+	client *cloudwatch.Client
+}
+
+// NewCloudWatchMetrics creates a MetricsSource reading from CloudWatch with the AWS config of the manager.
+func NewCloudWatchMetrics(dbmgr *DynamoDBManager) MetricsSource {
+	return &CloudWatchMetrics{dbmgr: dbmgr, client: CloudWatchNewFromConfig(dbmgr.AwsConfig)}
+}
+
+// GetMetricSums implements MetricsSource with GetMetricData.
+func (m *CloudWatchMetrics) GetMetricSums(tableName string, indexName string, metricName string, start time.Time, end time.Time, period time.Duration) ([]MetricDatapoint, error) {
+	dimensions := []cwtypes.Dimension{{Name: aws.String("TableName"), Value: aws.String(tableName)}}
+	if indexName != "" {
+		dimensions = append(dimensions, cwtypes.Dimension{Name: aws.String("GlobalSecondaryIndexName"), Value: aws.String(indexName)})
+	}
+
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(start),
+		EndTime:   aws.Time(end),
+		ScanBy:    cwtypes.ScanByTimestampAscending,
+		MetricDataQueries: []cwtypes.MetricDataQuery{{
+			Id: aws.String("m"),
+			MetricStat: &cwtypes.MetricStat{
+				Metric: &cwtypes.Metric{
+					Namespace:  aws.String(MetricsNamespace),
+					MetricName: aws.String(metricName),
+					Dimensions: dimensions,
+				},
+				Period: aws.Int32(int32(period.Seconds())),
+				Stat:   aws.String("Sum"),
+			},
+		}},
+	}
+
+	var datapoints []MetricDatapoint
+	paginator := cloudwatch.NewGetMetricDataPaginator(m.client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.Background())
+		if err != nil {
+			m.dbmgr.Logger.Errorf("Error calling GetMetricData for metric:%s of table:%s index:%s: %v", metricName, tableName, indexName, err)
+			return nil, err
+		}
+		for _, result := range output.MetricDataResults {
+			for i := range result.Timestamps {
+				datapoints = append(datapoints, MetricDatapoint{Timestamp: result.Timestamps[i], Sum: result.Values[i]})
+			}
+		}
+	}
+	return datapoints, nil
+}
+
+// ConsumedCapacity is the capacity a table and its global secondary indexes consumed within a time window.
+type ConsumedCapacity struct {
+	ReadCapacityUnits  float64
+	WriteCapacityUnits float64
+}
+
+// GetConsumedCapacity sums the capacity consumed by a table and its global secondary indexes between start and end.
+// It returns the ConsumedCapacity and an error if a metric can't be read.
+func GetConsumedCapacity(source MetricsSource, table *types.TableDescription, start time.Time, end time.Time, period time.Duration) (ConsumedCapacity, error) {
+	var consumed ConsumedCapacity
+	tableName := aws.ToString(table.TableName)
+	indexNames := []string{""}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		indexNames = append(indexNames, aws.ToString(gsi.IndexName))
+	}
+
+	for _, indexName := range indexNames {
+		for metricName, total := range map[string]*float64{
+			ConsumedReadCapacityUnits:  &consumed.ReadCapacityUnits,
+			ConsumedWriteCapacityUnits: &consumed.WriteCapacityUnits,
+		} {
+			datapoints, err := source.GetMetricSums(tableName, indexName, metricName, start, end, period)
+			if err != nil {
+				return consumed, err
+			}
+			for _, datapoint := range datapoints {
+				*total += datapoint.Sum
+			}
+		}
+	}
+	return consumed, nil
+}
+
+// RecordedMetrics is a MetricsSource replaying datapoints saved in a metrics file, regardless of the requested
+// time range, so that recommendations can be reproduced from fixtures.
+// When wrapping another source it records every datapoint that source returns instead.
+type RecordedMetrics struct {
+	Source  MetricsSource                `json:"-" yaml:"-"`
+	Metrics map[string][]MetricDatapoint `json:"metrics" yaml:"metrics"`
+}
+
+// metricKey identifies the metric of a table or index in a metrics file: TABLE/INDEX/METRIC
+func metricKey(tableName string, indexName string, metricName string) string {
+	return strings.Join([]string{tableName, indexName, metricName}, "/")
+}
+
+// GetMetricSums implements MetricsSource.
+func (r *RecordedMetrics) GetMetricSums(tableName string, indexName string, metricName string, start time.Time, end time.Time, period time.Duration) ([]MetricDatapoint, error) {
+	key := metricKey(tableName, indexName, metricName)
+	if r.Source == nil {
+		return r.Metrics[key], nil
+	}
+
+	datapoints, err := r.Source.GetMetricSums(tableName, indexName, metricName, start, end, period)
+	if err != nil {
+		return nil, err
+	}
+	if r.Metrics == nil {
+		r.Metrics = make(map[string][]MetricDatapoint)
+	}
+	r.Metrics[key] = datapoints
+	return datapoints, nil
+}
+
+// LoadRecordedMetrics reads a metrics file, parsed as JSON for a .json extension and as YAML otherwise.
+// It returns the RecordedMetrics replaying the file and an error.
+func LoadRecordedMetrics(path string) (*RecordedMetrics, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded RecordedMetrics
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &recorded)
+	} else {
+		err = yaml.Unmarshal(content, &recorded)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse metrics file:%s - error:%v", path, err))
+	}
+	for key := range recorded.Metrics {
+		datapoints := recorded.Metrics[key]
+		sort.Slice(datapoints, func(i, j int) bool { return datapoints[i].Timestamp.Before(datapoints[j].Timestamp) })
+	}
+	return &recorded, nil
+}
+
+// SaveRecordedMetrics writes the recorded datapoints to a metrics file, as JSON for a .json extension and as YAML otherwise.
+// It returns an error if the file can't be written.
+func SaveRecordedMetrics(path string, recorded *RecordedMetrics) error {
+	var content []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		content, err = json.MarshalIndent(recorded, "", "  ")
+	} else {
+		content, err = yaml.Marshal(recorded)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+)
+
+// HoursPerMonth is the number of hours AWS bills a month of provisioned capacity with.
+const HoursPerMonth = 730
+
+// Table classes prices differ by
+const (
+	StandardClass   = "STANDARD"
+	StandardIaClass = "STANDARD_INFREQUENT_ACCESS"
+)
+
+// ClassPrices are the DynamoDB prices of one table class in USD.
+type ClassPrices struct {
+	RcuHour                  float64 `json:"rcuHour" yaml:"rcuHour"`
+	WcuHour                  float64 `json:"wcuHour" yaml:"wcuHour"`
+	MillionReadRequestUnits  float64 `json:"millionReadRequestUnits" yaml:"millionReadRequestUnits"`
+	MillionWriteRequestUnits float64 `json:"millionWriteRequestUnits" yaml:"millionWriteRequestUnits"`
+	GbMonth                  float64 `json:"gbMonth" yaml:"gbMonth"`
+}
+
+// PriceTable holds the prices of the Standard and Standard-IA table classes.
+type PriceTable struct {
+	Standard   ClassPrices `json:"standard" yaml:"standard"`
+	StandardIa ClassPrices `json:"standardIa" yaml:"standardIa"`
+}
+
+// DefaultPricesRegion is the region whose prices are built in.
+const DefaultPricesRegion = "us-east-1"
+
+// DefaultPrices are the us-east-1 prices.
+var DefaultPrices = PriceTable{
+	Standard: ClassPrices{
+		RcuHour:                  0.00013,
+		WcuHour:                  0.00065,
+		MillionReadRequestUnits:  0.125,
+		MillionWriteRequestUnits: 0.625,
+		GbMonth:                  0.25,
+	},
+	StandardIa: ClassPrices{
+		RcuHour:                  0.00016,
+		WcuHour:                  0.00081,
+		MillionReadRequestUnits:  0.155,
+		MillionWriteRequestUnits: 0.78,
+		GbMonth:                  0.10,
+	},
+}
+
+// Class returns the prices of a table class, the Standard prices for an unknown class.
+func (p *PriceTable) Class(tableClass string) ClassPrices {
+	if tableClass == StandardIaClass {
+		return p.StandardIa
+	}
+	return p.Standard
+}
+
+// ProvisionedMonthlyCost returns the monthly cost of the given provisioned read and write capacity.
+func (p *PriceTable) ProvisionedMonthlyCost(tableClass string, rcu int64, wcu int64) float64 {
+	prices := p.Class(tableClass)
+	return (float64(rcu)*prices.RcuHour + float64(wcu)*prices.WcuHour) * HoursPerMonth
+}
+
+// OnDemandMonthlyCost returns the monthly cost of the given read and write request units per month.
+func (p *PriceTable) OnDemandMonthlyCost(tableClass string, readRequestUnits float64, writeRequestUnits float64) float64 {
+	prices := p.Class(tableClass)
+	return readRequestUnits/1e6*prices.MillionReadRequestUnits + writeRequestUnits/1e6*prices.MillionWriteRequestUnits
+}
+
+// StorageMonthlyCost returns the monthly cost of storing the given number of bytes.
+func (p *PriceTable) StorageMonthlyCost(tableClass string, sizeBytes int64) float64 {
+	return float64(sizeBytes) / (1 << 30) * p.Class(tableClass).GbMonth
+}
+
+// TableCost is the monthly cost of a table, split into capacity (provisioned or on-demand) and storage.
+type TableCost struct {
+	Capacity float64
+	Storage  float64
+}
+
+// Total returns the monthly cost of capacity and storage.
+func (c TableCost) Total() float64 {
+	return c.Capacity + c.Storage
+}
+
+// TableUsage is what a table and its global secondary indexes consume in a month:
+// the request units an on-demand table is billed for and the stored bytes.
+type TableUsage struct {
+	ReadRequestUnits  float64
+	WriteRequestUnits float64
+	SizeBytes         int64
+}
+
+// MonthlyCost returns the monthly cost of a table configuration, including its global secondary indexes,
+// in its billing mode and table class for the given usage.
+func (p *PriceTable) MonthlyCost(state *TableState, usage TableUsage) TableCost {
+	cost := TableCost{Storage: p.StorageMonthlyCost(state.TableClass, usage.SizeBytes)}
+	if state.BillingMode == string(types.BillingModePayPerRequest) {
+		cost.Capacity = p.OnDemandMonthlyCost(state.TableClass, usage.ReadRequestUnits, usage.WriteRequestUnits)
+		return cost
+	}
+
+	rcu, wcu := state.Rcu, state.Wcu
+	for _, gsi := range state.Gsis {
+		rcu, wcu = rcu+gsi.Rcu, wcu+gsi.Wcu
+	}
+	cost.Capacity = p.ProvisionedMonthlyCost(state.TableClass, rcu, wcu)
+	return cost
+}
+
+// PriceFile is the content of a price table file: the prices of every region by region name.
+type PriceFile struct {
+	Regions map[string]PriceTable `json:"regions" yaml:"regions"`
+}
+
+// DefaultPriceFile returns the price table file used when it exists and none is configured: ~/.dynamodb-manager/prices.yaml
+func DefaultPriceFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".dynamodb-manager", "prices.yaml")
+	}
+	return filepath.Join(home, ".dynamodb-manager", "prices.yaml")
+}
+
+// LoadPriceTable reads the prices of the region of the manager from its price table file, parsed as JSON
+// for a .json extension and as YAML otherwise. Without a price file, and if the default one doesn't exist,
+// the built-in us-east-1 prices are used.
+// It returns the PriceTable and an error if the file can't be read or has no prices for the region.
+func LoadPriceTable(dbmgr *DynamoDBManager) (*PriceTable, error) {
+	region := dbmgr.AwsConfig.Region
+	path := dbmgr.PriceFile
+	if path == "" {
+		if _, err := os.Stat(DefaultPriceFile()); err != nil {
+			if region != "" && region != DefaultPricesRegion {
+				dbmgr.Logger.Warnf("No price file for region:%s, estimating with the built-in %s prices", region, DefaultPricesRegion)
+			}
+			return &DefaultPrices, nil
+		}
+		path = DefaultPriceFile()
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var priceFile PriceFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &priceFile)
+	} else {
+		err = yaml.Unmarshal(content, &priceFile)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse price file:%s - error:%v", path, err))
+	}
+
+	prices, exists := priceFile.Regions[region]
+	if !exists {
+		return nil, errors.New(fmt.Sprintf("price file:%s has no prices for region:%s", path, region))
+	}
+	return &prices, nil
+}
+
+// GetTableUsage projects the capacity consumed by a table and its global secondary indexes over the lookback
+// window ending at end to a month, and adds up the bytes they store. A nil source only adds up the bytes.
+// It returns the TableUsage and an error if a metric can't be read.
+func GetTableUsage(source MetricsSource, table *types.TableDescription, lookback time.Duration, end time.Time) (TableUsage, error) {
+	usage := TableUsage{SizeBytes: aws.ToInt64(table.TableSizeBytes)}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		usage.SizeBytes += aws.ToInt64(gsi.IndexSizeBytes)
+	}
+
+	if source == nil {
+		return usage, nil
+	}
+
+	consumed, err := GetConsumedCapacity(source, table, end.Add(-lookback), end, MetricsPeriod)
+	if err != nil {
+		return usage, err
+	}
+
+	// Consumed capacity units and on-demand request units have the same size.
+	month := float64(HoursPerMonth) * float64(time.Hour) / float64(lookback)
+	usage.ReadRequestUnits = consumed.ReadCapacityUnits * month
+	usage.WriteRequestUnits = consumed.WriteCapacityUnits * month
+	return usage, nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// CheckDestructiveOperation enforces the deletion protection policy before a destructive operation on a table.
+// When the manager requires protection, tables without deletion protection are refused, unless the policy is
+// overridden with AllowUnprotected, in which case the operation is only reported.
+// It takes a DynamoDBManager, table name and a description of the operation (e.g. "remove tags of") as input.
+// It returns an error if the operation must be refused.
+func CheckDestructiveOperation(dbmgr *DynamoDBManager, tableName string, operation string) error {
+	if !dbmgr.RequireProtection {
+		return nil
+	}
+
+	table, err := DescribeTable(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+	if aws.ToBool(table.DeletionProtectionEnabled) {
+		return nil
+	}
+
+	if dbmgr.AllowUnprotected {
+		dbmgr.Logger.Warnf("Table:%s has no deletion protection - allowed to %s it as the protection policy is overridden", tableName, operation)
+		return nil
+	}
+	return errors.New(fmt.Sprintf("Refused to %s table:%s : deletion protection is disabled and the protection policy requires it", operation, tableName))
+}
+
+// CheckDisableProtection enforces the deletion protection policy before the deletion protection of a table is disabled.
+// When the manager requires protection, disabling it is refused, unless the policy is overridden with AllowUnprotected,
+// in which case it is only reported.
+// It returns an error if disabling must be refused.
+func CheckDisableProtection(dbmgr *DynamoDBManager, tableName string) error {
+	if !dbmgr.RequireProtection {
+		return nil
+	}
+
+	if dbmgr.AllowUnprotected {
+		dbmgr.Logger.Warnf("Table:%s - allowed to disable its deletion protection as the protection policy is overridden", tableName)
+		return nil
+	}
+	return errors.New(fmt.Sprintf("Refused to disable deletion protection of table:%s : the protection policy requires it", tableName))
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ReplicaRegionsOf returns the regions of the replicas of a global table, empty for a regional table.
+func ReplicaRegionsOf(table *types.TableDescription) []string {
+	regions := make([]string, 0, len(table.Replicas))
+	for _, replica := range table.Replicas {
+		regions = append(regions, aws.ToString(replica.RegionName))
+	}
+	return regions
+}
+
+// ReplicaOf returns the replica of a global table in a region, or nil if the table has no replica there.
+func ReplicaOf(table *types.TableDescription, region string) *types.ReplicaDescription {
+	for i := range table.Replicas {
+		if aws.ToString(table.Replicas[i].RegionName) == region {
+			return &table.Replicas[i]
+		}
+	}
+	return nil
+}
+
+// replicaSettings describes the per-replica settings of a replica update for the log.
+func replicaSettings(tableClass string, readCapacity int64) string {
+	var settings []string
+	if tableClass != "" {
+		settings = append(settings, "tableClass:"+tableClass)
+	}
+	if readCapacity > 0 {
+		settings = append(settings, fmt.Sprintf("readCapacity:%d", readCapacity))
+	}
+	if len(settings) == 0 {
+		return "settings of the table"
+	}
+	return strings.Join(settings, ", ")
+}
+
+// AddReplica adds a replica in another region to a table, turning it into a global table.
+// An empty table class and a zero read capacity use the settings of the table.
+// It returns an error if the replica can't be created.
+func AddReplica(dbmgr *DynamoDBManager, tableName string, region string, tableClass string, readCapacity int64) error {
+	create := &types.CreateReplicationGroupMemberAction{
+		RegionName:         aws.String(region),
+		TableClassOverride: types.TableClass(tableClass),
+	}
+	if readCapacity > 0 {
+		create.ProvisionedThroughputOverride = &types.ProvisionedThroughputOverride{ReadCapacityUnits: aws.Int64(readCapacity)}
+	}
+	input := &dynamodb.UpdateTableInput{
+		TableName:      aws.String(tableName),
+		ReplicaUpdates: []types.ReplicationGroupUpdate{{Create: create}},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error adding replica:%s to table:%s: %v", region, tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Replica:%s is being added to table:%s - %s", region, tableName, replicaSettings(tableClass, readCapacity))
+	}
+	return err
+}
+
+// UpdateReplica changes the table class or the read capacity override of a replica.
+// An empty table class or a zero read capacity leave the setting unchanged.
+// It returns an error if the replica can't be updated.
+func UpdateReplica(dbmgr *DynamoDBManager, tableName string, region string, tableClass string, readCapacity int64) error {
+	update := &types.UpdateReplicationGroupMemberAction{
+		RegionName:         aws.String(region),
+		TableClassOverride: types.TableClass(tableClass),
+	}
+	if readCapacity > 0 {
+		update.ProvisionedThroughputOverride = &types.ProvisionedThroughputOverride{ReadCapacityUnits: aws.Int64(readCapacity)}
+	}
+	input := &dynamodb.UpdateTableInput{
+		TableName:      aws.String(tableName),
+		ReplicaUpdates: []types.ReplicationGroupUpdate{{Update: update}},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error updating replica:%s of table:%s: %v", region, tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Replica:%s of table:%s updated - %s", region, tableName, replicaSettings(tableClass, readCapacity))
+	}
+	return err
+}
+
+// RemoveReplica removes the replica of a table in a region, deleting the replica table and its items there.
+// It returns an error if the replica can't be removed.
+func RemoveReplica(dbmgr *DynamoDBManager, tableName string, region string) error {
+	input := &dynamodb.UpdateTableInput{
+		TableName: aws.String(tableName),
+		ReplicaUpdates: []types.ReplicationGroupUpdate{{
+			Delete: &types.DeleteReplicationGroupMemberAction{RegionName: aws.String(region)},
+		}},
+	}
+
+	_, err := updateTable(dbmgr, input)
+	if err != nil {
+		dbmgr.Logger.Errorf("Error removing replica:%s of table:%s: %v", region, tableName, err)
+	} else {
+		dbmgr.Logger.Infof("Replica:%s of table:%s is being removed", region, tableName)
+	}
+	return err
+}
+
+// WaitForReplicaActive polls the replica of a table in a region until it is ACTIVE, logging the progress of its creation.
+// It returns an error if the table can't be described, the replica disappears or isn't active within maxWait.
+func WaitForReplicaActive(dbmgr *DynamoDBManager, tableName string, region string, maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	for {
+		output, err := dbmgr.DynamoDBClient.DescribeTable(context.Background(), &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to describe table:%s while waiting, Here's why: %v\n", tableName, err)
+			return err
+		}
+
+		replica := ReplicaOf(output.Table, region)
+		if replica == nil {
+			return errors.New(fmt.Sprintf("replica:%s not found on table:%s", region, tableName))
+		}
+		if replica.ReplicaStatus == types.ReplicaStatusActive {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("replica:%s of table:%s did not become active within %v", region, tableName, maxWait))
+		}
+		dbmgr.Logger.Infof("Waiting for replica:%s of table:%s - status:%s - progress:%s",
+			region, tableName, replica.ReplicaStatus, aws.ToString(replica.ReplicaStatusPercentProgress))
+		time.Sleep(WaitPollInterval)
+	}
+}
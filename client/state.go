@@ -0,0 +1,228 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// GsiState represents the capacity configuration of a global secondary index.
+type GsiState struct {
+	IndexName string `json:"indexName" yaml:"indexName"`
+	Rcu       int64  `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu       int64  `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+}
+
+// ReplicaState represents the settings of a replica of a global table that differ from the table's own.
+type ReplicaState struct {
+	Region       string `json:"region" yaml:"region"`
+	TableClass   string `json:"tableClass,omitempty" yaml:"tableClass,omitempty"`
+	ReadCapacity int64  `json:"readCapacity,omitempty" yaml:"readCapacity,omitempty"`
+}
+
+// TableState represents the live, user-manageable configuration of a DynamoDB table.
+// Capacity values are only set for provisioned tables and on-demand limits only for on-demand tables.
+// Replicas is always written, empty for tables that aren't global, so that states saved before replicas were
+// recorded can be told apart by their nil Replicas.
+type TableState struct {
+	TableName            string            `json:"tableName" yaml:"tableName"`
+	BillingMode          string            `json:"billingMode" yaml:"billingMode"`
+	Rcu                  int64             `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu                  int64             `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+	MaxReadRequestUnits  int64             `json:"maxReadRequestUnits,omitempty" yaml:"maxReadRequestUnits,omitempty"`
+	MaxWriteRequestUnits int64             `json:"maxWriteRequestUnits,omitempty" yaml:"maxWriteRequestUnits,omitempty"`
+	Gsis                 []GsiState        `json:"gsis,omitempty" yaml:"gsis,omitempty"`
+	TableClass           string            `json:"tableClass" yaml:"tableClass"`
+	DeletionProtection   bool              `json:"deletionProtection" yaml:"deletionProtection"`
+	PointInTimeRecovery  bool              `json:"pointInTimeRecovery,omitempty" yaml:"pointInTimeRecovery,omitempty"`
+	TimeToLiveAttribute  string            `json:"timeToLiveAttribute,omitempty" yaml:"timeToLiveAttribute,omitempty"`
+	StreamViewType       string            `json:"streamViewType,omitempty" yaml:"streamViewType,omitempty"`
+	Replicas             []ReplicaState    `json:"replicas" yaml:"replicas"`
+	Tags                 map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Gsi returns the state of the global secondary index with the given name, or nil if the table has no such index.
+func (s *TableState) Gsi(indexName string) *GsiState {
+	for i := range s.Gsis {
+		if s.Gsis[i].IndexName == indexName {
+			return &s.Gsis[i]
+		}
+	}
+	return nil
+}
+
+// Replica returns the state of the replica in the given region, or nil if the table has no replica there.
+func (s *TableState) Replica(region string) *ReplicaState {
+	for i := range s.Replicas {
+		if s.Replicas[i].Region == region {
+			return &s.Replicas[i]
+		}
+	}
+	return nil
+}
+
+// BillingModeOf returns the billing mode of a table description, defaulting to PROVISIONED
+// as DynamoDB omits the billing mode summary for tables that were always provisioned.
+func BillingModeOf(table *types.TableDescription) string {
+	if table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode != "" {
+		return fmt.Sprintf("%v", table.BillingModeSummary.BillingMode)
+	}
+	return string(types.BillingModeProvisioned)
+}
+
+// TableClassOf returns the table class of a table description, defaulting to STANDARD.
+func TableClassOf(table *types.TableDescription) string {
+	if table.TableClassSummary != nil && table.TableClassSummary.TableClass != "" {
+		return string(table.TableClassSummary.TableClass)
+	}
+	return string(types.TableClassStandard)
+}
+
+// ParseTableClass accepts a table class as given on the command line: standard, standard-ia or the DynamoDB name.
+// It returns the DynamoDB table class and an error if the class is unknown.
+func ParseTableClass(value string) (string, error) {
+	switch strings.ToLower(value) {
+	case "standard":
+		return string(types.TableClassStandard), nil
+	case "standard-ia", "standard_infrequent_access":
+		return string(types.TableClassStandardInfrequentAccess), nil
+	}
+	return "", errors.New(fmt.Sprintf("unknown table class:%s - expected standard or standard-ia", value))
+}
+
+// StreamViewTypeOf returns the view type of the stream of a table, or "DISABLED" if it has no enabled stream.
+func StreamViewTypeOf(table *types.TableDescription) string {
+	if table.StreamSpecification == nil || !aws.ToBool(table.StreamSpecification.StreamEnabled) {
+		return "DISABLED"
+	}
+	return string(table.StreamSpecification.StreamViewType)
+}
+
+// ParseStreamViewType accepts a stream view type as given on the command line, e.g. new-and-old-images, or the DynamoDB name.
+// It returns the DynamoDB stream view type and an error if the view type is unknown.
+func ParseStreamViewType(value string) (string, error) {
+	viewType := types.StreamViewType(strings.ToUpper(strings.ReplaceAll(value, "-", "_")))
+	for _, known := range viewType.Values() {
+		if viewType == known {
+			return string(viewType), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("unknown stream view type:%s - expected new-image, old-image, new-and-old-images or keys-only", value))
+}
+
+// ParseAge parses an age as given on the command line, in days (30d), weeks (2w) or any Go duration (12h).
+// It returns the duration and an error if the age is not valid or not positive.
+func ParseAge(value string) (time.Duration, error) {
+	var age time.Duration
+	var err error
+	if number, found := strings.CutSuffix(value, "d"); found {
+		var days int64
+		days, err = strconv.ParseInt(number, 10, 64)
+		age = time.Duration(days) * 24 * time.Hour
+	} else if number, found := strings.CutSuffix(value, "w"); found {
+		var weeks int64
+		weeks, err = strconv.ParseInt(number, 10, 64)
+		age = time.Duration(weeks) * 7 * 24 * time.Hour
+	} else {
+		age, err = time.ParseDuration(value)
+	}
+	if err != nil || age <= 0 {
+		return 0, errors.New(fmt.Sprintf("invalid age:%s - expected a positive number of days (30d), weeks (2w) or a duration (12h)", value))
+	}
+	return age, nil
+}
+
+// TagsToMap converts a slice of DynamoDB tags into a key/value map.
+func TagsToMap(tags []types.Tag) map[string]string {
+	tagMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tagMap
+}
+
+// TableStateFromDescription extracts the settings UpdateTable can change from a table description.
+// Unlike GetTableState it leaves out point-in-time recovery, Time to Live and tags, which the description lacks.
+func TableStateFromDescription(tableName string, table *types.TableDescription) *TableState {
+	state := &TableState{
+		TableName:          tableName,
+		BillingMode:        BillingModeOf(table),
+		TableClass:         TableClassOf(table),
+		DeletionProtection: aws.ToBool(table.DeletionProtectionEnabled),
+		StreamViewType:     StreamViewTypeOf(table),
+		Replicas:           []ReplicaState{},
+	}
+
+	if state.BillingMode == string(types.BillingModeProvisioned) && table.ProvisionedThroughput != nil {
+		state.Rcu = aws.ToInt64(table.ProvisionedThroughput.ReadCapacityUnits)
+		state.Wcu = aws.ToInt64(table.ProvisionedThroughput.WriteCapacityUnits)
+	}
+
+	if state.BillingMode == string(types.BillingModePayPerRequest) && table.OnDemandThroughput != nil {
+		state.MaxReadRequestUnits = aws.ToInt64(table.OnDemandThroughput.MaxReadRequestUnits)
+		state.MaxWriteRequestUnits = aws.ToInt64(table.OnDemandThroughput.MaxWriteRequestUnits)
+	}
+
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		gsiState := GsiState{IndexName: aws.ToString(gsi.IndexName)}
+		if state.BillingMode == string(types.BillingModeProvisioned) && gsi.ProvisionedThroughput != nil {
+			gsiState.Rcu = aws.ToInt64(gsi.ProvisionedThroughput.ReadCapacityUnits)
+			gsiState.Wcu = aws.ToInt64(gsi.ProvisionedThroughput.WriteCapacityUnits)
+		}
+		state.Gsis = append(state.Gsis, gsiState)
+	}
+	sort.Slice(state.Gsis, func(i, j int) bool { return state.Gsis[i].IndexName < state.Gsis[j].IndexName })
+
+	for _, replica := range table.Replicas {
+		replicaState := ReplicaState{Region: aws.ToString(replica.RegionName)}
+		if replica.ReplicaTableClassSummary != nil {
+			replicaState.TableClass = string(replica.ReplicaTableClassSummary.TableClass)
+		}
+		if replica.ProvisionedThroughputOverride != nil {
+			replicaState.ReadCapacity = aws.ToInt64(replica.ProvisionedThroughputOverride.ReadCapacityUnits)
+		}
+		state.Replicas = append(state.Replicas, replicaState)
+	}
+	sort.Slice(state.Replicas, func(i, j int) bool { return state.Replicas[i].Region < state.Replicas[j].Region })
+
+	return state
+}
+
+// GetTableState collects the billing mode, capacity, indexes, table features and tags of a DynamoDB table.
+// It returns the TableState and an error.
+func GetTableState(dbmgr *DynamoDBManager, tableName string) (*TableState, error) {
+	table, err := DescribeTable(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	state := TableStateFromDescription(tableName, table)
+
+	pitr, err := GetContinuousBackups(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+	state.PointInTimeRecovery = pitr.PointInTimeRecoveryStatus == types.PointInTimeRecoveryStatusEnabled
+
+	ttl, err := GetTimeToLive(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabled || ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabling {
+		state.TimeToLiveAttribute = aws.ToString(ttl.AttributeName)
+	}
+
+	tags, err := GetTableTags(dbmgr, aws.ToString(table.TableArn))
+	if err != nil {
+		return nil, err
+	}
+	state.Tags = TagsToMap(tags)
+
+	return state, nil
+}
@@ -0,0 +1,133 @@
+package cost
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/update"
+)
+
+var DescribeTableClient = client.DescribeTable
+
+// CostOptions holds the settings of the cost command.
+// Change is the proposed update, an empty one only estimates the current cost.
+// On-demand capacity is estimated from the consumed capacity over the Lookback window, read from CloudWatch
+// unless MetricsFile replays recorded metrics.
+type CostOptions struct {
+	Change      update.UpdateOptions
+	Lookback    time.Duration
+	MetricsFile string
+}
+
+// hasChange reports whether the options propose a change.
+func (o *CostOptions) hasChange() bool {
+	return !reflect.DeepEqual(o.Change, update.UpdateOptions{})
+}
+
+// describeState renders the billing mode, capacities and table class of a table state for the log.
+func describeState(state *client.TableState) string {
+	description := state.BillingMode
+	if state.BillingMode == Provisioned {
+		description = fmt.Sprintf("%s rcu:%d wcu:%d", Provisioned, state.Rcu, state.Wcu)
+		for _, gsi := range state.Gsis {
+			description += fmt.Sprintf(" gsi:%s %d/%d", gsi.IndexName, gsi.Rcu, gsi.Wcu)
+		}
+	}
+	return fmt.Sprintf("%s class:%s", description, state.TableClass)
+}
+
+// describeCost renders a monthly cost for the log.
+func describeCost(cost client.TableCost) string {
+	return fmt.Sprintf("$%.2f (capacity:$%.2f storage:$%.2f)", cost.Total(), cost.Capacity, cost.Storage)
+}
+
+// ExecuteCost estimates the monthly cost of the selected tables and, if a change is proposed, their cost after it,
+// logging both per table and in aggregate.
+// It takes a DynamoDBManager, the table names or the fuzzy name and tag value to search by and the CostOptions as input.
+// It returns an error listing the tables that couldn't be estimated.
+func ExecuteCost(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, opts CostOptions) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+
+	prices, err := LoadPriceTableClient(dbmgr)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load the prices due to: %v", err)
+		return err
+	}
+
+	var source client.MetricsSource
+	if opts.MetricsFile != "" {
+		if source, err = client.LoadRecordedMetrics(opts.MetricsFile); err != nil {
+			dbmgr.Logger.Errorf("Failed to load the recorded metrics due to: %v", err)
+			return err
+		}
+	} else {
+		source = NewMetricsSourceClient(dbmgr)
+	}
+
+	var failedTables []string
+	estimated := 0
+	var currentTotal, proposedTotal float64
+	end := time.Now().Truncate(client.MetricsPeriod)
+	for _, tableName := range targets {
+		table, err := DescribeTableClient(dbmgr, tableName)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		state := client.TableStateFromDescription(tableName, table)
+
+		proposed := state
+		if opts.hasChange() {
+			if proposed, err = update.ProposedState(state, opts.Change); err != nil {
+				dbmgr.Logger.Errorf("Failed to apply the change to table:%s due to: %v", tableName, err)
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+		}
+
+		// Only on-demand capacity depends on the consumed capacity, provisioned tables need no metrics.
+		var usageSource client.MetricsSource
+		if state.BillingMode == OnDemand || proposed.BillingMode == OnDemand {
+			usageSource = source
+		}
+		usage, err := client.GetTableUsage(usageSource, table, opts.Lookback, end)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to get the usage of table:%s due to: %v", tableName, err)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		currentCost := prices.MonthlyCost(state, usage)
+		dbmgr.Logger.Infof("Table:%s - %s - monthly cost:%s", tableName, describeState(state), describeCost(currentCost))
+		proposedCost := currentCost
+		if opts.hasChange() {
+			proposedCost = prices.MonthlyCost(proposed, usage)
+			dbmgr.Logger.Infof("Table:%s - proposed %s - monthly cost:%s - difference:%+.2f", tableName, describeState(proposed), describeCost(proposedCost), proposedCost.Total()-currentCost.Total())
+		}
+		estimated++
+		currentTotal += currentCost.Total()
+		proposedTotal += proposedCost.Total()
+	}
+
+	if opts.hasChange() {
+		dbmgr.Logger.Infof("Monthly cost of %d tables - current:$%.2f proposed:$%.2f - difference:%+.2f", estimated, currentTotal, proposedTotal, proposedTotal-currentTotal)
+	} else {
+		dbmgr.Logger.Infof("Monthly cost of %d tables - $%.2f", estimated, currentTotal)
+	}
+
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to estimate the cost of %d of %d tables: %s", len(failedTables), len(targets), strings.Join(failedTables, ",")))
+	}
+	return nil
+}
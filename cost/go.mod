@@ -0,0 +1,42 @@
+module github.com/bazelgo/dynamodb-manager/cost
+
+go 1.20
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.30.3
+	github.com/bazelgo/dynamodb-manager/client v0.0.0-20240222085729-e3c1b60177b1
+	github.com/bazelgo/dynamodb-manager/search v0.0.0-20240222080558-382a7685411e
+	github.com/bazelgo/dynamodb-manager/update v0.0.0-20240222080558-382a7685411e
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/config v1.27.2 // indirect
+	github.com/aws/aws-sdk-go-v2/credentials v1.17.2 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.15.1 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.15 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.15 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/applicationautoscaling v1.31.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/cloudwatch v1.40.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.16 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.19.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.22.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.27.2 // indirect
+	github.com/aws/smithy-go v1.20.3 // indirect
+	github.com/bazelgo/dynamodb-manager/logging v0.0.0-20240222085729-e3c1b60177b1 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/texttheater/golang-levenshtein/levenshtein v0.0.0-20200805054039-cae8b0eaed6c // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/bazelgo/dynamodb-manager/client => ../client
+	github.com/bazelgo/dynamodb-manager/logging => ../logging
+	github.com/bazelgo/dynamodb-manager/search => ../search
+	github.com/bazelgo/dynamodb-manager/update => ../update
+)
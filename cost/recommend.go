@@ -0,0 +1,362 @@
+package cost
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+	"github.com/bazelgo/dynamodb-manager/update"
+)
+
+// Billing modes a recommendation can suggest
+const (
+	Provisioned = "PROVISIONED"
+	OnDemand    = "PAY_PER_REQUEST"
+)
+
+// Defaults of the recommend command
+const (
+	DefaultLookback          = 14 * 24 * time.Hour
+	DefaultTargetUtilization = 70.0
+	// SwitchSavingsThreshold is the share of the monthly cost a mode switch has to save to be suggested,
+	// so that tables with an even trade-off aren't switched back and forth.
+	SwitchSavingsThreshold = 0.2
+)
+
+// ApplyWaitTimeout bounds how long applying a recommendation waits for a table to become ACTIVE between two updates.
+var ApplyWaitTimeout = 30 * time.Minute
+
+var (
+	GetTableStateClient    = client.GetTableState
+	WaitForActiveClient    = client.WaitForTableActive
+	UpdateIndexTask        = update.UpdateIndexCapacity
+	ExecuteUpdateTask      = update.ExecuteUpdate
+	ResolveTablesTask      = search.ResolveTables
+	NewMetricsSourceClient = client.NewCloudWatchMetrics
+	LoadPriceTableClient   = client.LoadPriceTable
+)
+
+// Usage summarizes the consumed capacity and throttling of a table or index over the lookback window.
+// Averages and peaks are in capacity units per second, totals in capacity units.
+type Usage struct {
+	AverageRead    float64
+	AverageWrite   float64
+	PeakRead       float64
+	PeakWrite      float64
+	TotalRead      float64
+	TotalWrite     float64
+	ReadThrottles  float64
+	WriteThrottles float64
+}
+
+// CapacityRecommendation is the provisioned capacity recommended for a table or one of its global secondary indexes.
+type CapacityRecommendation struct {
+	IndexName  string
+	Usage      Usage
+	CurrentRcu int64
+	CurrentWcu int64
+	Rcu        int64
+	Wcu        int64
+}
+
+// Recommendation holds the suggested billing mode and capacities of a table with their projected monthly costs.
+// Capacities lists the table itself first, followed by its global secondary indexes.
+type Recommendation struct {
+	TableName       string
+	TableClass      string
+	BillingMode     string
+	SuggestedMode   string
+	Capacities      []CapacityRecommendation
+	CurrentCost     float64
+	ProvisionedCost float64
+	OnDemandCost    float64
+}
+
+// ProposedCost returns the projected monthly cost of the suggested billing mode.
+func (r *Recommendation) ProposedCost() float64 {
+	if r.SuggestedMode == OnDemand {
+		return r.OnDemandCost
+	}
+	return r.ProvisionedCost
+}
+
+// summarize computes the usage of a table or index from the consumed capacity and throttle metrics.
+// It returns the Usage and an error if a metric can't be read.
+func summarize(source client.MetricsSource, tableName string, indexName string, start time.Time, end time.Time) (Usage, error) {
+	var usage Usage
+	metrics := []struct {
+		name  string
+		peak  *float64
+		total *float64
+	}{
+		{client.ConsumedReadCapacityUnits, &usage.PeakRead, &usage.TotalRead},
+		{client.ConsumedWriteCapacityUnits, &usage.PeakWrite, &usage.TotalWrite},
+		{client.ReadThrottleEvents, nil, &usage.ReadThrottles},
+		{client.WriteThrottleEvents, nil, &usage.WriteThrottles},
+	}
+
+	for _, metric := range metrics {
+		datapoints, err := source.GetMetricSums(tableName, indexName, metric.name, start, end, client.MetricsPeriod)
+		if err != nil {
+			return usage, err
+		}
+		for _, datapoint := range datapoints {
+			*metric.total += datapoint.Sum
+			if metric.peak != nil {
+				*metric.peak = math.Max(*metric.peak, datapoint.Sum/client.MetricsPeriod.Seconds())
+			}
+		}
+	}
+
+	seconds := end.Sub(start).Seconds()
+	usage.AverageRead = usage.TotalRead / seconds
+	usage.AverageWrite = usage.TotalWrite / seconds
+	return usage, nil
+}
+
+// recommendCapacity returns the capacity that serves the peak at the target utilization, at least 1.
+// A throttled provisioned capacity was too small to show the real peak, so it is raised at least as if it were fully used.
+func recommendCapacity(peak float64, current int64, throttles float64, targetUtilization float64) int64 {
+	capacity := math.Ceil(peak * 100 / targetUtilization)
+	if throttles > 0 && current > 0 {
+		capacity = math.Max(capacity, math.Ceil(float64(current)*100/targetUtilization))
+	}
+	return int64(math.Max(capacity, update.MinCapacityUnits))
+}
+
+// Recommend analyzes the metrics of a table and its global secondary indexes over the lookback window.
+// It takes a DynamoDBManager, a MetricsSource, the price table, the table name, the lookback window, the target
+// utilization in percent and the end of the window as input.
+// It returns the Recommendation and an error.
+func Recommend(dbmgr *client.DynamoDBManager, source client.MetricsSource, prices *client.PriceTable, tableName string, lookback time.Duration, targetUtilization float64, end time.Time) (*Recommendation, error) {
+	state, err := GetTableStateClient(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendation := &Recommendation{
+		TableName:     tableName,
+		TableClass:    state.TableClass,
+		BillingMode:   state.BillingMode,
+		SuggestedMode: state.BillingMode,
+	}
+
+	targets := []CapacityRecommendation{{CurrentRcu: state.Rcu, CurrentWcu: state.Wcu}}
+	for _, gsi := range state.Gsis {
+		targets = append(targets, CapacityRecommendation{IndexName: gsi.IndexName, CurrentRcu: gsi.Rcu, CurrentWcu: gsi.Wcu})
+	}
+
+	start := end.Add(-lookback)
+	var currentRcu, currentWcu, rcu, wcu int64
+	var totalRead, totalWrite float64
+	for _, target := range targets {
+		target.Usage, err = summarize(source, tableName, target.IndexName, start, end)
+		if err != nil {
+			return nil, err
+		}
+		target.Rcu = recommendCapacity(target.Usage.PeakRead, target.CurrentRcu, target.Usage.ReadThrottles, targetUtilization)
+		target.Wcu = recommendCapacity(target.Usage.PeakWrite, target.CurrentWcu, target.Usage.WriteThrottles, targetUtilization)
+		recommendation.Capacities = append(recommendation.Capacities, target)
+
+		currentRcu, currentWcu = currentRcu+target.CurrentRcu, currentWcu+target.CurrentWcu
+		rcu, wcu = rcu+target.Rcu, wcu+target.Wcu
+		totalRead, totalWrite = totalRead+target.Usage.TotalRead, totalWrite+target.Usage.TotalWrite
+	}
+
+	// On-demand reads are billed per request unit, while the metrics count capacity units of the same size.
+	month := float64(client.HoursPerMonth) * float64(time.Hour) / float64(lookback)
+	recommendation.OnDemandCost = prices.OnDemandMonthlyCost(state.TableClass, totalRead*month, totalWrite*month)
+	recommendation.ProvisionedCost = prices.ProvisionedMonthlyCost(state.TableClass, rcu, wcu)
+
+	if state.BillingMode == Provisioned {
+		recommendation.CurrentCost = prices.ProvisionedMonthlyCost(state.TableClass, currentRcu, currentWcu)
+		if recommendation.OnDemandCost < recommendation.ProvisionedCost*(1-SwitchSavingsThreshold) {
+			recommendation.SuggestedMode = OnDemand
+		}
+	} else {
+		recommendation.CurrentCost = recommendation.OnDemandCost
+		if recommendation.ProvisionedCost < recommendation.OnDemandCost*(1-SwitchSavingsThreshold) {
+			recommendation.SuggestedMode = Provisioned
+		}
+	}
+	return recommendation, nil
+}
+
+// describeUsage renders the usage of a table or index for the log.
+func describeUsage(target CapacityRecommendation) string {
+	name := "table"
+	if target.IndexName != "" {
+		name = "gsi:" + target.IndexName
+	}
+	return fmt.Sprintf("%s - read avg:%.1f peak:%.1f throttled:%.0f - write avg:%.1f peak:%.1f throttled:%.0f",
+		name, target.Usage.AverageRead, target.Usage.PeakRead, target.Usage.ReadThrottles,
+		target.Usage.AverageWrite, target.Usage.PeakWrite, target.Usage.WriteThrottles)
+}
+
+// logRecommendation logs the usage, the suggested changes and the projected monthly costs of a table.
+func logRecommendation(dbmgr *client.DynamoDBManager, r *Recommendation) {
+	for _, target := range r.Capacities {
+		dbmgr.Logger.Infof("Table:%s - %s", r.TableName, describeUsage(target))
+	}
+
+	switch {
+	case r.SuggestedMode != r.BillingMode:
+		dbmgr.Logger.Infof("Table:%s - switch from %s to %s", r.TableName, r.BillingMode, r.SuggestedMode)
+	case r.BillingMode == OnDemand:
+		dbmgr.Logger.Infof("Table:%s - keep %s", r.TableName, OnDemand)
+	}
+	if r.SuggestedMode == Provisioned {
+		for _, target := range r.Capacities {
+			name := "table"
+			if target.IndexName != "" {
+				name = "gsi:" + target.IndexName
+			}
+			dbmgr.Logger.Infof("Table:%s - %s rcu:%s -> %d wcu:%s -> %d", r.TableName, name,
+				formatCapacity(target.CurrentRcu), target.Rcu, formatCapacity(target.CurrentWcu), target.Wcu)
+		}
+	}
+	dbmgr.Logger.Infof("Table:%s - monthly cost current:$%.2f proposed:$%.2f (provisioned:$%.2f on-demand:$%.2f)",
+		r.TableName, r.CurrentCost, r.ProposedCost(), r.ProvisionedCost, r.OnDemandCost)
+}
+
+// formatCapacity renders a capacity value, showing unset values as "-".
+func formatCapacity(value int64) string {
+	if value == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+// ApplyRecommendation changes the billing mode and capacities of a table as recommended.
+// The table goes through update.ExecuteUpdate, so decrease budgets and cooldowns are respected. A switch to
+// provisioned sets the index capacities in the same update, otherwise the global secondary indexes are updated
+// one by one through update.UpdateIndexCapacity once the table is ACTIVE again, leaving reserveDecreases unused as well.
+// It returns an error on the first failed change.
+func ApplyRecommendation(dbmgr *client.DynamoDBManager, r *Recommendation, reserveDecreases int) error {
+	if r.SuggestedMode == OnDemand {
+		if r.BillingMode == OnDemand {
+			return nil
+		}
+		return ExecuteUpdateTask(dbmgr, r.TableName, update.UpdateOptions{SwitchToOnDemand: true})
+	}
+
+	table := r.Capacities[0]
+	switching := r.BillingMode == OnDemand
+	opts := update.UpdateOptions{
+		Rcu:                 fmt.Sprintf("%d", table.Rcu),
+		Wcu:                 fmt.Sprintf("%d", table.Wcu),
+		SwitchToProvisioned: switching,
+		ReserveDecreases:    reserveDecreases,
+	}
+	if switching {
+		for _, gsi := range r.Capacities[1:] {
+			opts.Gsis = append(opts.Gsis, client.GsiState{IndexName: gsi.IndexName, Rcu: gsi.Rcu, Wcu: gsi.Wcu})
+		}
+	}
+	if err := ExecuteUpdateTask(dbmgr, r.TableName, opts); err != nil || switching {
+		return err
+	}
+
+	for _, gsi := range r.Capacities[1:] {
+		if gsi.Rcu == gsi.CurrentRcu && gsi.Wcu == gsi.CurrentWcu {
+			continue
+		}
+		if err := WaitForActiveClient(dbmgr, r.TableName, ApplyWaitTimeout); err != nil {
+			return err
+		}
+		if err := UpdateIndexTask(dbmgr, r.TableName, gsi.IndexName, gsi.Rcu, gsi.Wcu, reserveDecreases); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecommendOptions holds the settings of the recommend command.
+// Metrics are read from CloudWatch unless MetricsFile replays recorded ones, and RecordFile saves the
+// metrics that were read so that the analysis can be reproduced later.
+type RecommendOptions struct {
+	Lookback          time.Duration
+	TargetUtilization float64 // in percent
+	MetricsFile       string
+	RecordFile        string
+	Apply             bool
+	ReserveDecreases  int // daily capacity decreases that applying must leave unused
+}
+
+// ExecuteRecommend analyzes the CloudWatch metrics of the selected tables and logs the suggested provisioned
+// capacities or billing mode switches with their projected monthly costs, applying them if requested.
+// It takes a DynamoDBManager, the table names or the fuzzy name and tag value to search by and the RecommendOptions as input.
+// It returns an error listing the tables that couldn't be analyzed or updated.
+func ExecuteRecommend(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, opts RecommendOptions) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+
+	var source client.MetricsSource
+	if opts.MetricsFile != "" {
+		if source, err = client.LoadRecordedMetrics(opts.MetricsFile); err != nil {
+			dbmgr.Logger.Errorf("Failed to load the recorded metrics due to: %v", err)
+			return err
+		}
+	} else {
+		source = NewMetricsSourceClient(dbmgr)
+	}
+	recorder := &client.RecordedMetrics{Source: source}
+	if opts.RecordFile != "" {
+		source = recorder
+	}
+
+	prices, err := LoadPriceTableClient(dbmgr)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load the prices due to: %v", err)
+		return err
+	}
+
+	var failedTables []string
+	analyzed := 0
+	var currentTotal, proposedTotal float64
+	end := time.Now().Truncate(client.MetricsPeriod)
+	for _, tableName := range targets {
+		recommendation, err := Recommend(dbmgr, source, prices, tableName, opts.Lookback, opts.TargetUtilization, end)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to analyze table:%s due to: %v", tableName, err)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		logRecommendation(dbmgr, recommendation)
+		analyzed++
+		currentTotal += recommendation.CurrentCost
+		proposedTotal += recommendation.ProposedCost()
+
+		if opts.Apply {
+			dbmgr.Logger.Infof("Applying the recommendation of table:%s ...", tableName)
+			if err := ApplyRecommendation(dbmgr, recommendation, opts.ReserveDecreases); err != nil {
+				dbmgr.Logger.Errorf("Failed to apply the recommendation of table:%s due to: %v", tableName, err)
+				failedTables = append(failedTables, tableName)
+			}
+		}
+	}
+	dbmgr.Logger.Infof("Monthly cost of %d tables - current:$%.2f proposed:$%.2f", analyzed, currentTotal, proposedTotal)
+
+	if opts.RecordFile != "" {
+		if err := client.SaveRecordedMetrics(opts.RecordFile, recorder); err != nil {
+			dbmgr.Logger.Errorf("Failed to save the metrics to:%s due to: %v", opts.RecordFile, err)
+			return err
+		}
+		dbmgr.Logger.Infof("Metrics saved to:%s", opts.RecordFile)
+	}
+
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to recommend for %d of %d tables: %s", len(failedTables), len(targets), strings.Join(failedTables, ",")))
+	}
+	return nil
+}
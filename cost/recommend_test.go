@@ -0,0 +1,123 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+var fixtureEnd = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+// fixtureMetrics records an hour of metrics of the orders table: reads peak at 20 units per second,
+// writes at 1 unit per second while being throttled, and its byCustomer index is idle.
+func fixtureMetrics() *client.RecordedMetrics {
+	datapoint := func(minutes int, sum float64) client.MetricDatapoint {
+		return client.MetricDatapoint{Timestamp: fixtureEnd.Add(time.Duration(minutes-60) * time.Minute), Sum: sum}
+	}
+	return &client.RecordedMetrics{Metrics: map[string][]client.MetricDatapoint{
+		"orders//" + client.ConsumedReadCapacityUnits:  {datapoint(0, 3000), datapoint(5, 6000)},
+		"orders//" + client.ConsumedWriteCapacityUnits: {datapoint(0, 300)},
+		"orders//" + client.WriteThrottleEvents:        {datapoint(5, 2)},
+	}}
+}
+
+func fixtureState(dbmgr *client.DynamoDBManager, tableName string) (*client.TableState, error) {
+	return &client.TableState{
+		TableName:   tableName,
+		BillingMode: Provisioned,
+		Rcu:         100,
+		Wcu:         10,
+		Gsis:        []client.GsiState{{IndexName: "byCustomer", Rcu: 20, Wcu: 5}},
+	}, nil
+}
+
+func assertCost(t *testing.T, name string, got float64, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-6 {
+		t.Errorf("%s = %.6f, want %.6f", name, got, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	usage, err := summarize(fixtureMetrics(), "orders", "", fixtureEnd.Add(-time.Hour), fixtureEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Usage{
+		AverageRead:    2.5,
+		AverageWrite:   300.0 / 3600,
+		PeakRead:       20,
+		PeakWrite:      1,
+		TotalRead:      9000,
+		TotalWrite:     300,
+		ReadThrottles:  0,
+		WriteThrottles: 2,
+	}
+	if usage != want {
+		t.Errorf("summarize() = %+v, want %+v", usage, want)
+	}
+
+	idle, err := summarize(fixtureMetrics(), "orders", "byCustomer", fixtureEnd.Add(-time.Hour), fixtureEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idle != (Usage{}) {
+		t.Errorf("summarize() of an index without metrics = %+v, want no usage", idle)
+	}
+}
+
+func TestRecommendCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		peak      float64
+		current   int64
+		throttles float64
+		want      int64
+	}{
+		{"peak at target utilization", 20, 100, 0, 29},
+		{"idle stays at the minimum", 0, 100, 0, 1},
+		{"throttled raises to the current capacity", 1, 10, 2, 15},
+		{"throttled peak above the current capacity", 20, 10, 2, 29},
+		{"throttled on-demand keeps the peak", 1, 0, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recommendCapacity(tt.peak, tt.current, tt.throttles, DefaultTargetUtilization); got != tt.want {
+				t.Errorf("recommendCapacity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	defer func(original func(*client.DynamoDBManager, string) (*client.TableState, error)) {
+		GetTableStateClient = original
+	}(GetTableStateClient)
+	GetTableStateClient = fixtureState
+
+	r, err := Recommend(&client.DynamoDBManager{}, fixtureMetrics(), &client.DefaultPrices, "orders", time.Hour, DefaultTargetUtilization, fixtureEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.Capacities) != 2 {
+		t.Fatalf("Recommend() returned %d capacities, want the table and its index", len(r.Capacities))
+	}
+	table, gsi := r.Capacities[0], r.Capacities[1]
+	if table.IndexName != "" || table.Rcu != 29 || table.Wcu != 15 {
+		t.Errorf("table capacity = %s rcu:%d wcu:%d, want rcu:29 wcu:15", table.IndexName, table.Rcu, table.Wcu)
+	}
+	if gsi.IndexName != "byCustomer" || gsi.CurrentRcu != 20 || gsi.CurrentWcu != 5 || gsi.Rcu != 1 || gsi.Wcu != 1 {
+		t.Errorf("index capacity = %+v, want byCustomer rcu:20 -> 1 wcu:5 -> 1", gsi)
+	}
+
+	assertCost(t, "CurrentCost", r.CurrentCost, (120*0.00013+15*0.00065)*730)
+	assertCost(t, "ProvisionedCost", r.ProvisionedCost, (30*0.00013+16*0.00065)*730)
+	assertCost(t, "OnDemandCost", r.OnDemandCost, 9000*730/1e6*0.125+300*730/1e6*0.625)
+	if r.SuggestedMode != OnDemand {
+		t.Errorf("SuggestedMode = %s, want %s", r.SuggestedMode, OnDemand)
+	}
+	assertCost(t, "ProposedCost", r.ProposedCost(), r.OnDemandCost)
+}
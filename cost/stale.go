@@ -0,0 +1,145 @@
+package cost
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// DefaultStaleLookback is the window without reads and writes after which a table is reported as stale.
+const DefaultStaleLookback = 30 * 24 * time.Hour
+
+var GetTableListClient = client.GetTableList
+
+// StaleOptions holds the settings of the stale report.
+// A table is stale if it consumed no capacity within the Lookback window, read from CloudWatch unless
+// MetricsFile replays recorded metrics. Tables created within the window are too new to tell.
+type StaleOptions struct {
+	Lookback    time.Duration
+	MetricsFile string
+}
+
+// StaleTable is a table without reads and writes and what keeping it costs.
+type StaleTable struct {
+	TableName   string
+	Created     *time.Time
+	ItemCount   int64
+	SizeBytes   int64
+	MonthlyCost client.TableCost
+}
+
+// CheckStale checks whether a table consumed no capacity within the lookback window ending at end.
+// It returns the StaleTable with the monthly cost of keeping the table, nil if the table is in use or too new to tell,
+// and an error if the table or its metrics can't be read.
+func CheckStale(dbmgr *client.DynamoDBManager, source client.MetricsSource, prices *client.PriceTable, tableName string, lookback time.Duration, end time.Time) (*StaleTable, error) {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if table.CreationDateTime != nil && table.CreationDateTime.After(end.Add(-lookback)) {
+		dbmgr.Logger.Debugf("Table:%s - created within the last %v, too new to tell", tableName, lookback)
+		return nil, nil
+	}
+
+	consumed, err := client.GetConsumedCapacity(source, table, end.Add(-lookback), end, client.ActivityPeriod)
+	if err != nil {
+		return nil, err
+	}
+	if consumed.ReadCapacityUnits > 0 || consumed.WriteCapacityUnits > 0 {
+		dbmgr.Logger.Debugf("Table:%s - consumed %.0f read and %.0f write capacity units", tableName, consumed.ReadCapacityUnits, consumed.WriteCapacityUnits)
+		return nil, nil
+	}
+
+	// Without reads and writes an on-demand table only costs its storage.
+	usage, err := client.GetTableUsage(nil, table, lookback, end)
+	if err != nil {
+		return nil, err
+	}
+	return &StaleTable{
+		TableName:   tableName,
+		Created:     table.CreationDateTime,
+		ItemCount:   aws.ToInt64(table.ItemCount),
+		SizeBytes:   aws.ToInt64(table.TableSizeBytes),
+		MonthlyCost: prices.MonthlyCost(client.TableStateFromDescription(tableName, table), usage),
+	}, nil
+}
+
+// ExecuteStale reports the tables without reads and writes within the lookback window, with the monthly cost
+// of keeping them, most expensive first.
+// It takes a DynamoDBManager, the table names or the fuzzy name and tag value to search by and the StaleOptions as input.
+// With no table names and no search conditions every table is checked.
+// It returns an error listing the tables that couldn't be checked.
+func ExecuteStale(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, opts StaleOptions) error {
+	var targets []string
+	var err error
+	if len(tableNames) == 0 && tableFuzzyName == "" && tagValue == "" {
+		targets, err = GetTableListClient(dbmgr)
+	} else {
+		targets, err = ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	}
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+
+	prices, err := LoadPriceTableClient(dbmgr)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load the prices due to: %v", err)
+		return err
+	}
+
+	var source client.MetricsSource
+	if opts.MetricsFile != "" {
+		if source, err = client.LoadRecordedMetrics(opts.MetricsFile); err != nil {
+			dbmgr.Logger.Errorf("Failed to load the recorded metrics due to: %v", err)
+			return err
+		}
+	} else {
+		source = NewMetricsSourceClient(dbmgr)
+	}
+
+	var staleTables []*StaleTable
+	var failedTables []string
+	end := time.Now().Truncate(client.ActivityPeriod)
+	for _, tableName := range targets {
+		stale, err := CheckStale(dbmgr, source, prices, tableName, opts.Lookback, end)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to check table:%s due to: %v", tableName, err)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		if stale != nil {
+			staleTables = append(staleTables, stale)
+		}
+	}
+
+	sort.SliceStable(staleTables, func(i, j int) bool {
+		return staleTables[i].MonthlyCost.Total() > staleTables[j].MonthlyCost.Total()
+	})
+	days := int(opts.Lookback.Hours() / 24)
+	var total float64
+	for _, stale := range staleTables {
+		created := "-"
+		if stale.Created != nil {
+			created = stale.Created.UTC().Format(time.RFC3339)
+		}
+		dbmgr.Logger.Warnf("Table:%s - no reads or writes in %d days - created:%s - items:%d - size:%d - monthly cost:%s",
+			stale.TableName, days, created, stale.ItemCount, stale.SizeBytes, describeCost(stale.MonthlyCost))
+		total += stale.MonthlyCost.Total()
+	}
+	dbmgr.Logger.Infof("Stale tables: %d of %d - monthly cost of keeping them: $%.2f", len(staleTables), len(targets), total)
+
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to check %d of %d tables: %s", len(failedTables), len(targets), strings.Join(failedTables, ",")))
+	}
+	return nil
+}
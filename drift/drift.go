@@ -0,0 +1,298 @@
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+// ErrDriftDetected is returned by ExecuteCheck when the live tables differ from the snapshot.
+var ErrDriftDetected = errors.New("drift detected")
+
+var (
+	GetTableListClient  = client.GetTableList
+	GetTableStateClient = client.GetTableState
+	ExecuteSearchTask   = search.ExecuteSearch
+)
+
+const missingValue = "-"
+
+// Snapshot is the saved baseline of table configurations.
+// AllTables is set when the snapshot covers the whole account, so that new tables count as drift.
+type Snapshot struct {
+	CreatedAt time.Time           `json:"createdAt" yaml:"createdAt"`
+	AllTables bool                `json:"allTables" yaml:"allTables"`
+	Tables    []client.TableState `json:"tables" yaml:"tables"`
+}
+
+// Difference is a single setting of a table that changed since the snapshot was taken.
+type Difference struct {
+	TableName string
+	Field     string
+	Snapshot  string
+	Current   string
+}
+
+// isJSON reports whether a snapshot file should be written as JSON instead of YAML.
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// SaveSnapshot writes a snapshot to a file, as JSON for a .json extension and as YAML otherwise.
+// It returns an error if the file can't be written.
+func SaveSnapshot(path string, snapshot *Snapshot) error {
+	var content []byte
+	var err error
+	if isJSON(path) {
+		content, err = json.MarshalIndent(snapshot, "", "  ")
+	} else {
+		content, err = yaml.Marshal(snapshot)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// LoadSnapshot reads a snapshot file written by SaveSnapshot.
+// It returns the Snapshot and an error.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if isJSON(path) {
+		err = json.Unmarshal(content, &snapshot)
+	} else {
+		err = yaml.Unmarshal(content, &snapshot)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse snapshot file:%s - error:%v", path, err))
+	}
+	return &snapshot, nil
+}
+
+// selectTables returns the names of the tables matched by the search conditions, or all tables when both are empty.
+// It returns the table names, whether all tables were selected, and an error.
+func selectTables(dbmgr *client.DynamoDBManager, tableFuzzyName string, tagValue string) ([]string, bool, error) {
+	if tableFuzzyName == "" && tagValue == "" {
+		tableNames, err := GetTableListClient(dbmgr)
+		return tableNames, true, err
+	}
+
+	var tableNames []string
+	for _, table := range ExecuteSearchTask(dbmgr, tableFuzzyName, tagValue) {
+		tableNames = append(tableNames, table["Name"])
+	}
+	return tableNames, false, nil
+}
+
+// CompareStates compares the snapshot state of a table with its current state.
+// It returns the differences in billing mode, capacity, indexes, features, replicas and tags.
+func CompareStates(saved *client.TableState, current *client.TableState) []Difference {
+	var diffs []Difference
+	add := func(field string, savedValue string, currentValue string) {
+		if savedValue != currentValue {
+			diffs = append(diffs, Difference{saved.TableName, field, savedValue, currentValue})
+		}
+	}
+
+	add("billingMode", saved.BillingMode, current.BillingMode)
+	add("rcu", fmt.Sprintf("%d", saved.Rcu), fmt.Sprintf("%d", current.Rcu))
+	add("wcu", fmt.Sprintf("%d", saved.Wcu), fmt.Sprintf("%d", current.Wcu))
+	add("maxReadRequestUnits", fmt.Sprintf("%d", saved.MaxReadRequestUnits), fmt.Sprintf("%d", current.MaxReadRequestUnits))
+	add("maxWriteRequestUnits", fmt.Sprintf("%d", saved.MaxWriteRequestUnits), fmt.Sprintf("%d", current.MaxWriteRequestUnits))
+	add("tableClass", saved.TableClass, current.TableClass)
+	add("deletionProtection", fmt.Sprintf("%t", saved.DeletionProtection), fmt.Sprintf("%t", current.DeletionProtection))
+	add("pointInTimeRecovery", fmt.Sprintf("%t", saved.PointInTimeRecovery), fmt.Sprintf("%t", current.PointInTimeRecovery))
+	add("timeToLiveAttribute", orMissing(saved.TimeToLiveAttribute), orMissing(current.TimeToLiveAttribute))
+	if saved.StreamViewType != "" {
+		// Snapshots taken before streams were recorded don't know the stream, so they can't have drifted from it.
+		add("streamViewType", saved.StreamViewType, current.StreamViewType)
+	}
+	if saved.Replicas != nil {
+		// Likewise for snapshots taken before replicas were recorded.
+		regions := make(map[string]bool)
+		for _, replica := range saved.Replicas {
+			regions[replica.Region] = true
+		}
+		for _, replica := range current.Replicas {
+			regions[replica.Region] = true
+		}
+		for _, region := range sortedKeys(regions) {
+			add("replica:"+region, formatReplica(saved.Replica(region)), formatReplica(current.Replica(region)))
+		}
+	}
+
+	gsiNames := make(map[string]bool)
+	for _, gsi := range saved.Gsis {
+		gsiNames[gsi.IndexName] = true
+	}
+	for _, gsi := range current.Gsis {
+		gsiNames[gsi.IndexName] = true
+	}
+	for _, name := range sortedKeys(gsiNames) {
+		add("gsi:"+name, formatGsi(saved.Gsi(name)), formatGsi(current.Gsi(name)))
+	}
+
+	tagKeys := make(map[string]bool)
+	for key := range saved.Tags {
+		tagKeys[key] = true
+	}
+	for key := range current.Tags {
+		tagKeys[key] = true
+	}
+	for _, key := range sortedKeys(tagKeys) {
+		add("tag:"+key, tagOrMissing(saved.Tags, key), tagOrMissing(current.Tags, key))
+	}
+
+	return diffs
+}
+
+// orMissing renders an empty value as "-".
+func orMissing(value string) string {
+	if value == "" {
+		return missingValue
+	}
+	return value
+}
+
+// tagOrMissing renders the value of a tag, or "-" if the tag isn't set.
+func tagOrMissing(tags map[string]string, key string) string {
+	if value, exists := tags[key]; exists {
+		return value
+	}
+	return missingValue
+}
+
+// formatGsi renders the capacity of an index, or "-" if the index doesn't exist.
+func formatGsi(gsi *client.GsiState) string {
+	if gsi == nil {
+		return missingValue
+	}
+	return fmt.Sprintf("%d/%d", gsi.Rcu, gsi.Wcu)
+}
+
+// formatReplica renders the settings of a replica, or "-" if there is no replica.
+// Settings the replica takes over from the table are shown as "-".
+func formatReplica(replica *client.ReplicaState) string {
+	if replica == nil {
+		return missingValue
+	}
+	readCapacity := missingValue
+	if replica.ReadCapacity != 0 {
+		readCapacity = fmt.Sprintf("%d", replica.ReadCapacity)
+	}
+	return fmt.Sprintf("class:%s readCapacity:%s", orMissing(replica.TableClass), readCapacity)
+}
+
+// sortedKeys returns the keys of a set in ascending order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ExecuteSnapshot saves the current configuration of the selected tables as a drift baseline.
+// It takes a DynamoDBManager, the snapshot file path and the search conditions (fuzzy table name and tag value)
+// as input; with empty search conditions every table is included.
+// It returns an error if the tables can't be read or the snapshot can't be written.
+func ExecuteSnapshot(dbmgr *client.DynamoDBManager, snapshotFile string, tableFuzzyName string, tagValue string) error {
+	tableNames, allTables, err := selectTables(dbmgr, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to list the tables to snapshot due to: %v", err)
+		return err
+	}
+
+	snapshot := &Snapshot{CreatedAt: time.Now().UTC(), AllTables: allTables}
+	for _, tableName := range tableNames {
+		state, err := GetTableStateClient(dbmgr, tableName)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to get the state of table:%s due to: %v", tableName, err)
+			return err
+		}
+		snapshot.Tables = append(snapshot.Tables, *state)
+	}
+
+	if err := SaveSnapshot(snapshotFile, snapshot); err != nil {
+		dbmgr.Logger.Errorf("Failed to write snapshot file:%s due to: %v", snapshotFile, err)
+		return err
+	}
+	dbmgr.Logger.Infof("Saved the configuration of %d tables to:%s", len(snapshot.Tables), snapshotFile)
+	return nil
+}
+
+// ExecuteCheck compares the tables of a snapshot with their current configuration and logs every difference.
+// Tables deleted since the snapshot, and new tables for snapshots of the whole account, are reported as drift too.
+// It takes a DynamoDBManager and the snapshot file path as input.
+// It returns ErrDriftDetected if any difference was found, or another error if the check couldn't be done.
+func ExecuteCheck(dbmgr *client.DynamoDBManager, snapshotFile string) error {
+	snapshot, err := LoadSnapshot(snapshotFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load snapshot due to: %v", err)
+		return err
+	}
+
+	tableList, err := GetTableListClient(dbmgr)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to list tables due to: %v", err)
+		return err
+	}
+	liveTables := make(map[string]bool, len(tableList))
+	for _, tableName := range tableList {
+		liveTables[tableName] = true
+	}
+
+	driftCount := 0
+	savedTables := make(map[string]bool, len(snapshot.Tables))
+	for i := range snapshot.Tables {
+		saved := &snapshot.Tables[i]
+		savedTables[saved.TableName] = true
+		if !liveTables[saved.TableName] {
+			dbmgr.Logger.Warnf("Drift on table:%s - table was deleted", saved.TableName)
+			driftCount++
+			continue
+		}
+
+		current, err := GetTableStateClient(dbmgr, saved.TableName)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to get the state of table:%s due to: %v", saved.TableName, err)
+			return err
+		}
+		for _, diff := range CompareStates(saved, current) {
+			dbmgr.Logger.Warnf("Drift on table:%s - %s: %s -> %s", diff.TableName, diff.Field, diff.Snapshot, diff.Current)
+			driftCount++
+		}
+	}
+
+	if snapshot.AllTables {
+		for _, tableName := range tableList {
+			if !savedTables[tableName] {
+				dbmgr.Logger.Warnf("Drift on table:%s - table was created", tableName)
+				driftCount++
+			}
+		}
+	}
+
+	if driftCount > 0 {
+		dbmgr.Logger.Warnf("Found %d differences against the snapshot taken at %v", driftCount, snapshot.CreatedAt)
+		return ErrDriftDetected
+	}
+	dbmgr.Logger.Infof("No drift against the snapshot taken at %v", snapshot.CreatedAt)
+	return nil
+}
@@ -3,30 +3,43 @@ module github.com/bazelgo/dynamodb-manager
 go 1.20
 
 require (
+	github.com/bazelgo/dynamodb-manager/backup v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/cleanup v0.0.0-00010101000000-000000000000
 	github.com/bazelgo/dynamodb-manager/client v0.0.0-20240222085729-e3c1b60177b1
+	github.com/bazelgo/dynamodb-manager/cost v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/drift v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/plan v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/replica v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/scaling v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/schedule v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/schema v0.0.0-00010101000000-000000000000
 	github.com/bazelgo/dynamodb-manager/search v0.0.0-20240222080558-382a7685411e
+	github.com/bazelgo/dynamodb-manager/tags v0.0.0-00010101000000-000000000000
+	github.com/bazelgo/dynamodb-manager/ttl v0.0.0-00010101000000-000000000000
 	github.com/bazelgo/dynamodb-manager/update v0.0.0-20240222080558-382a7685411e
 	github.com/spf13/cobra v1.8.0
 	github.com/spf13/viper v1.18.2
 )
 
 require (
-	github.com/aws/aws-sdk-go-v2 v1.25.1 // indirect
+	github.com/aws/aws-sdk-go-v2 v1.30.3 // indirect
 	github.com/aws/aws-sdk-go-v2/config v1.27.2 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.17.2 // indirect
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.15.1 // indirect
-	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.1 // indirect
-	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.1 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.15 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.15 // indirect
 	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.0 // indirect
-	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.29.2 // indirect
-	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.1 // indirect
-	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.1 // indirect
+	github.com/aws/aws-sdk-go-v2/service/applicationautoscaling v1.31.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/cloudwatch v1.40.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.34.4 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/endpoint-discovery v1.9.16 // indirect
 	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.1 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sso v1.19.2 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.22.2 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.27.2 // indirect
-	github.com/aws/smithy-go v1.20.1 // indirect
-	github.com/bazelgo/dynamodb-manager/logging v0.0.0-20240222080558-382a7685411e // indirect
+	github.com/aws/smithy-go v1.20.3 // indirect
+	github.com/bazelgo/dynamodb-manager/logging v0.0.0-20240222085729-e3c1b60177b1 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -34,6 +47,7 @@ require (
 	github.com/magiconair/properties v1.8.7 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/pelletier/go-toml/v2 v2.1.0 // indirect
+	github.com/robfig/cron/v3 v3.0.1 // indirect
 	github.com/sagikazarmark/locafero v0.4.0 // indirect
 	github.com/sagikazarmark/slog-shim v0.1.0 // indirect
 	github.com/sourcegraph/conc v0.3.0 // indirect
@@ -50,3 +64,21 @@ require (
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace (
+	github.com/bazelgo/dynamodb-manager/backup => ./backup
+	github.com/bazelgo/dynamodb-manager/cleanup => ./cleanup
+	github.com/bazelgo/dynamodb-manager/client => ./client
+	github.com/bazelgo/dynamodb-manager/cost => ./cost
+	github.com/bazelgo/dynamodb-manager/drift => ./drift
+	github.com/bazelgo/dynamodb-manager/logging => ./logging
+	github.com/bazelgo/dynamodb-manager/plan => ./plan
+	github.com/bazelgo/dynamodb-manager/replica => ./replica
+	github.com/bazelgo/dynamodb-manager/scaling => ./scaling
+	github.com/bazelgo/dynamodb-manager/schedule => ./schedule
+	github.com/bazelgo/dynamodb-manager/schema => ./schema
+	github.com/bazelgo/dynamodb-manager/search => ./search
+	github.com/bazelgo/dynamodb-manager/tags => ./tags
+	github.com/bazelgo/dynamodb-manager/ttl => ./ttl
+	github.com/bazelgo/dynamodb-manager/update => ./update
+)
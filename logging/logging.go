@@ -69,7 +69,7 @@ func (l Logger) sanitize(msg string) string {
 // Debug wraps Sugar Debugf
 func (l Logger) Debugf(msg string, args ...interface{}) {
 	sanitized := l.sanitize(msg)
-	l.writer().Sugar().Debugf(sanitized, args)
+	l.writer().Sugar().Debugf(sanitized, args...)
 }
 
 // Info wraps Sugar Infof
@@ -81,13 +81,13 @@ func (l Logger) Infof(msg string, args ...interface{}) {
 // Warn wraps Sugar Warnf
 func (l Logger) Warnf(msg string, args ...interface{}) {
 	sanitized := l.sanitize(msg)
-	l.writer().Sugar().Warnf(sanitized, args)
+	l.writer().Sugar().Warnf(sanitized, args...)
 }
 
 // Error wraps Sugar Errorf
 func (l Logger) Errorf(msg string, args ...interface{}) {
 	sanitized := l.sanitize(msg)
-	l.writer().Sugar().Errorf(sanitized, args)
+	l.writer().Sugar().Errorf(sanitized, args...)
 }
 
 // Debug wraps Sugar Debug
@@ -4,13 +4,25 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/bazelgo/dynamodb-manager/backup"
+	"github.com/bazelgo/dynamodb-manager/cleanup"
 	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/cost"
+	"github.com/bazelgo/dynamodb-manager/drift"
+	"github.com/bazelgo/dynamodb-manager/plan"
+	"github.com/bazelgo/dynamodb-manager/replica"
+	"github.com/bazelgo/dynamodb-manager/scaling"
+	"github.com/bazelgo/dynamodb-manager/schedule"
+	"github.com/bazelgo/dynamodb-manager/schema"
 	"github.com/bazelgo/dynamodb-manager/search"
+	"github.com/bazelgo/dynamodb-manager/tags"
+	"github.com/bazelgo/dynamodb-manager/ttl"
 	"github.com/bazelgo/dynamodb-manager/update"
 )
 
@@ -18,10 +30,149 @@ import (
 const (
 	Search string = "search"
 	Update string = "update"
+	Plan   string = "plan"
+	Apply  string = "apply"
+
+	DriftSnapshot string = "drift-snapshot"
+	DriftCheck    string = "drift-check"
+
+	History  string = "history"
+	Rollback string = "rollback"
+
+	Tag      string = "tag"
+	TagAudit string = "tag-audit"
+
+	Describe string = "describe"
+	List     string = "list"
+	Create   string = "create"
+
+	SchemaExport string = "schema-export"
+	SchemaDiff   string = "schema-diff"
+
+	Delete string = "delete"
+
+	IndexList   string = "index-list"
+	IndexAdd    string = "index-add"
+	IndexDelete string = "index-delete"
+
+	ScalingShow   string = "scaling-show"
+	ScalingSet    string = "scaling-set"
+	ScalingRemove string = "scaling-remove"
+
+	ScheduleRun  string = "schedule-run"
+	ScheduleShow string = "schedule-show"
+
+	Recommend string = "recommend"
+	Cost      string = "cost"
+	Stale     string = "stale"
+
+	PitrShow    string = "pitr-show"
+	PitrEnable  string = "pitr-enable"
+	PitrDisable string = "pitr-disable"
+	PitrReport  string = "pitr-report"
+
+	BackupCreate  string = "backup-create"
+	BackupList    string = "backup-list"
+	BackupDelete  string = "backup-delete"
+	BackupRestore string = "backup-restore"
+	BackupPrune   string = "backup-prune"
+
+	TtlShow    string = "ttl-show"
+	TtlEnable  string = "ttl-enable"
+	TtlDisable string = "ttl-disable"
+
+	ReplicaShow   string = "replica-show"
+	ReplicaAdd    string = "replica-add"
+	ReplicaRemove string = "replica-remove"
+	ReplicaUpdate string = "replica-update"
 )
 
 var ExecuteSearchTask = search.ExecuteSearch
-var ExecuteUpdateTask = update.ExecuteUpdate
+var ExecuteUpdateTask = update.ExecuteBulkUpdate
+var ExecutePlanTask = plan.ExecutePlan
+var ExecuteApplyTask = plan.ExecuteApply
+var ExecuteDriftSnapshotTask = drift.ExecuteSnapshot
+var ExecuteDriftCheckTask = drift.ExecuteCheck
+var ExecuteHistoryTask = update.ExecuteHistory
+var ExecuteRollbackTask = update.ExecuteRollback
+var ExecuteTagTask = tags.ExecuteTag
+var ExecuteTagAuditTask = tags.ExecuteAudit
+var ExecuteDescribeTask = schema.ExecuteDescribe
+var ExecuteListTask = search.ExecuteList
+var ExecuteCreateTask = schema.ExecuteCreate
+var ExecuteSchemaExportTask = schema.ExecuteExport
+var ExecuteSchemaDiffTask = schema.ExecuteDiff
+var ExecuteDeleteTask = cleanup.ExecuteDelete
+var ExecuteIndexListTask = schema.ExecuteIndexList
+var ExecuteIndexAddTask = schema.ExecuteIndexAdd
+var ExecuteIndexDeleteTask = schema.ExecuteIndexDelete
+var ExecuteFilterTask = search.FilterTables
+var ExecuteScalingShowTask = scaling.ExecuteScalingShow
+var ExecuteScalingSetTask = scaling.ExecuteScalingSet
+var ExecuteScalingRemoveTask = scaling.ExecuteScalingRemove
+var ExecuteScheduleRunTask = schedule.ExecuteScheduleRun
+var ExecuteScheduleShowTask = schedule.ExecuteScheduleShow
+var ExecuteRecommendTask = cost.ExecuteRecommend
+var ExecuteCostTask = cost.ExecuteCost
+var ExecuteStaleTask = cost.ExecuteStale
+var ExecutePitrShowTask = backup.ExecutePitrShow
+var ExecutePitrUpdateTask = backup.ExecutePitrUpdate
+var ExecutePitrReportTask = backup.ExecutePitrReport
+var ExecuteBackupCreateTask = backup.ExecuteBackupCreate
+var ExecuteBackupListTask = backup.ExecuteBackupList
+var ExecuteBackupDeleteTask = backup.ExecuteBackupDelete
+var ExecuteBackupRestoreTask = backup.ExecuteBackupRestore
+var ExecuteBackupPruneTask = backup.ExecuteBackupPrune
+var ExecuteTtlShowTask = ttl.ExecuteTtlShow
+var ExecuteTtlUpdateTask = ttl.ExecuteTtlUpdate
+var ExecuteReplicaShowTask = replica.ExecuteReplicaShow
+var ExecuteReplicaAddTask = replica.ExecuteReplicaAdd
+var ExecuteReplicaRemoveTask = replica.ExecuteReplicaRemove
+var ExecuteReplicaUpdateTask = replica.ExecuteReplicaUpdate
+
+var action string
+var specFile string
+var snapshotFile string
+var historyTable string
+var historyLimit int
+var changeID string
+var targetTables string
+var dryRun bool
+var tagOperation tags.TagOperation
+var policyFile string
+var fixTags bool
+var describeTable string
+var outputFormat string
+var createTable string
+var exportFile string
+var diffTarget schema.DiffTarget
+var diffTags bool
+var deleteAge string
+var deleteOptions cleanup.DeleteOptions
+var indexName string
+var listColumns string
+var searchFilters []string
+var sortBy string
+var reverseSort bool
+var scalingChange scaling.ScalingChange
+var scalingDimensions string
+var scheduleFile string
+var stateFile string
+var runOnce bool
+var recommendOptions cost.RecommendOptions
+var lookbackDays int
+var costOptions cost.CostOptions
+var staleOptions cost.StaleOptions
+var backupLabel string
+var backupArns []string
+var restoreTable string
+var waitDone bool
+var pruneAge string
+var pruneOptions backup.PruneOptions
+var ttlAttribute string
+var replicaRegion string
+var replicaTableClass string
+var replicaReadCapacity int64
 
 var searchTerm string
 var tagValue string
@@ -30,10 +181,62 @@ var rcuValueStr string
 var wcuValueStr string
 var provisioned bool
 var onDemand bool
+var reserveDecreases int
+var minCapacity int64
+var maxCapacity int64
+var tableClass string
+var protect bool
+var unprotect bool
+var streamViewType string
 
 var usageStr string = `./dynamodb-manager [--help]
-./dynamodb-manager [--level LOG_LVL] [--profile NAME] search TABLE [--tag TAG]
-./dynamodb-manager [--level LOG_LVL] [--profile NAME] update TABLE [--ondemand|--provisioned] [--rcu READ_CAP] [--wcu WRITE_CAP]`
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] search TABLE [--tag TAG] [--filter COL=VALUE]... [--unprotected] [--usage-days N]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] update TABLE[,TABLE...] [--ondemand|--provisioned] [--rcu READ_CAP] [--wcu WRITE_CAP] [--min-capacity N] [--max-capacity N] [--table-class standard|standard-ia] [--protect|--unprotect] [--stream-view-type TYPE|--disable-stream [--event-source-mappings FILE]] [--backup-first] [--reserve-decreases N]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] plan --spec FILE
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] apply --spec FILE [--reserve-decreases N]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] drift snapshot --file FILE [--search TABLE] [--tag TAG]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] drift check --file FILE
+./dynamodb-manager [--level LOG_LVL] [--journal FILE] history [--table TABLE] [--limit N]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] [--require-protection [--allow-unprotected]] rollback CHANGE_ID [--reserve-decreases N]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] tag add|set KEY=VALUE... (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] tag remove KEY... (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] tag rename-key OLD_KEY NEW_KEY (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] tags audit --policy FILE [--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]] [--fix [--dry-run]]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] describe TABLE [--output text|json|yaml]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] create --spec FILE [--table TABLE] [--wait]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] schema export TABLE [--output json|yaml] [--file FILE]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] schema diff TABLE [OTHER_TABLE] [--other-profile NAME] [--other-region REGION] [--tags]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] schema diff TABLE --spec FILE [--tags]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] index list TABLE
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] index add TABLE --spec FILE [--wait]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] [--require-protection [--allow-unprotected]] index delete TABLE INDEX [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] delete [--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]] [--name-prefix PREFIX] [--older-than AGE] [--protected-tag KEY[=VALUE]]... [--final-backup] [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] list [--columns COL[,COL...]] [--filter COL=VALUE]... [--usage-days N] [--sort-by COL [--reverse]] [--output text|csv|json] [--search TABLE] [--tag TAG]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] scaling show (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG])
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] scaling set [--index INDEX] [--dimension read,write] [--min N] [--max N] [--target PERCENT] (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] scaling remove [--index INDEX] [--dimension read,write] (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] schedule run --file FILE [--state FILE] [--once]
+./dynamodb-manager [--level LOG_LVL] schedule show --file FILE [--state FILE]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] recommend (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--days N] [--target PERCENT] [--metrics-file FILE | --record-metrics FILE] [--apply [--reserve-decreases N]]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--prices FILE] cost (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--ondemand|--provisioned] [--rcu READ_CAP] [--wcu WRITE_CAP] [--min-capacity N] [--max-capacity N] [--table-class standard|standard-ia] [--days N] [--metrics-file FILE]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--prices FILE] stale [--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]] [--days N] [--metrics-file FILE]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] pitr show (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG])
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] pitr enable|disable (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] pitr report [--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] backup create (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--label LABEL] [--wait]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] backup list [--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] backup delete BACKUP_ARN... [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] backup restore BACKUP_ARN --target-table TABLE [--wait]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] backup prune (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--older-than AGE] [--keep N] [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] ttl show (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG])
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--require-protection [--allow-unprotected]] ttl enable --attribute NAME (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] ttl disable (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]) [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] replica show (--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG])
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] replica add TABLE REGION [--table-class standard|standard-ia] [--read-capacity N] [--wait]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] replica update TABLE REGION [--table-class standard|standard-ia] [--read-capacity N] [--dry-run]
+./dynamodb-manager [--level LOG_LVL] [--profile NAME] [--journal FILE] [--require-protection [--allow-unprotected]] replica remove TABLE REGION [--dry-run]
+
+READ_CAP and WRITE_CAP are absolute (100) or relative to the current capacity: delta (+50, -200), percent (+50%, -20%) or factor (x2, x0.5)`
 
 var rootCmd = &cobra.Command{
 	Use:   usageStr,
@@ -47,8 +250,43 @@ var rootCmd = &cobra.Command{
 		wcuValueStr = viper.GetString("wcu")
 		provisioned = viper.GetBool("provisioned")
 		onDemand = viper.GetBool("ondemand")
+		reserveDecreases = viper.GetInt("reserve-decreases")
+		minCapacity = viper.GetInt64("min-capacity")
+		maxCapacity = viper.GetInt64("max-capacity")
+		tableClass = viper.GetString("table-class")
+		protect = viper.GetBool("protect")
+		unprotect = viper.GetBool("unprotect")
+		streamViewType = viper.GetString("stream-view-type")
+		if viper.GetBool("unprotected") {
+			searchFilters = append(searchFilters, "deletion-protection=false")
+		}
+
+		if err := checkCommand(); err != nil {
+			return err
+		}
+		action = Search
+		if updateTable != "" {
+			action = Update
+		}
+		return nil
+	},
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan --spec FILE",
+	Short: "Show the changes needed to converge the tables to a desired-state file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Plan
+		return checkSpecCommand()
+	},
+}
 
-		return checkCommand()
+var applyCmd = &cobra.Command{
+	Use:   "apply --spec FILE",
+	Short: "Converge the tables to a desired-state file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Apply
+		return checkSpecCommand()
 	},
 }
 
@@ -78,23 +316,91 @@ func checkCommand() error {
 		return errors.New("Invalid command line arguments: update can't be used together with search or tag!")
 	}
 
-	if updateTable != "" && rcuValueStr == "" && wcuValueStr == "" && !provisioned && !onDemand {
-		return errors.New("Invalid command line arguments: no rcu or wcu or provisioned or onDemand is provided!")
+	if updateTable != "" && rcuValueStr == "" && wcuValueStr == "" && !provisioned && !onDemand && tableClass == "" && !protect && !unprotect &&
+		streamViewType == "" && !viper.GetBool("disable-stream") {
+		return errors.New("Invalid command line arguments: no rcu or wcu or provisioned or onDemand or table-class or protect or unprotect or stream is provided!")
+	}
+
+	if streamViewType != "" && viper.GetBool("disable-stream") {
+		return errors.New("Invalid command line arguments: stream-view-type can't be used together with disable-stream!")
+	}
+
+	if updateTable == "" && (streamViewType != "" || viper.GetBool("disable-stream") || viper.GetString("event-source-mappings") != "") {
+		return errors.New("Invalid command line arguments: stream-view-type, disable-stream and event-source-mappings can only be used together with update!")
+	}
+
+	if streamViewType != "" {
+		parsedViewType, err := client.ParseStreamViewType(streamViewType)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+		}
+		streamViewType = parsedViewType
+	}
+	if viper.GetBool("disable-stream") {
+		streamViewType = update.DisabledStream
+	}
+
+	if protect && unprotect {
+		return errors.New("Invalid command line arguments: protect can't be used together with unprotect!")
+	}
+
+	if updateTable == "" && viper.GetBool("backup-first") {
+		return errors.New("Invalid command line arguments: backup-first can only be used together with update!")
+	}
+
+	if updateTable == "" && (protect || unprotect) {
+		return errors.New("Invalid command line arguments: protect and unprotect can only be used together with update, search unprotected tables with --unprotected!")
+	}
+
+	if updateTable == "" && tableClass != "" {
+		return errors.New("Invalid command line arguments: table-class can only be used together with update, search filters with --filter class=VALUE!")
+	}
+
+	if tableClass != "" {
+		parsedClass, err := client.ParseTableClass(tableClass)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+		}
+		tableClass = parsedClass
+	}
+
+	if updateTable != "" && len(searchFilters) > 0 {
+		return errors.New("Invalid command line arguments: filter can't be used together with update!")
+	}
+
+	if err := checkFilters(); err != nil {
+		return err
 	}
 
 	if updateTable != "" && onDemand && (rcuValueStr != "" || wcuValueStr != "") {
 		return errors.New("Invalid command line arguments: ondemand model does not support rcu or wcu!")
 	}
 
+	if reserveDecreases < 0 {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: reserve-decreases:%d - must not be negative", reserveDecreases))
+	}
+
+	if updateTable == "" && reserveDecreases != 0 {
+		return errors.New("Invalid command line arguments: reserve-decreases can only be used together with update!")
+	}
+
+	if minCapacity < 0 || maxCapacity < 0 || (maxCapacity > 0 && minCapacity > maxCapacity) {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: min-capacity:%d - max-capacity:%d", minCapacity, maxCapacity))
+	}
+
+	if updateTable == "" && (minCapacity != 0 || maxCapacity != 0) {
+		return errors.New("Invalid command line arguments: min-capacity and max-capacity can only be used together with update!")
+	}
+
 	if rcuValueStr != "" {
-		_, err := strconv.ParseInt(rcuValueStr, 10, 64)
+		_, err := update.ParseCapacityChange(rcuValueStr)
 		if err != nil {
 			return errors.New(fmt.Sprintf("Invalid command line arguments: rcuValue:%s - error:%v", rcuValueStr, err))
 		}
 	}
 
 	if wcuValueStr != "" {
-		_, err := strconv.ParseInt(wcuValueStr, 10, 64)
+		_, err := update.ParseCapacityChange(wcuValueStr)
 		if err != nil {
 			return errors.New(fmt.Sprintf("Invalid command line arguments: wcuValue:%s - error:%v", wcuValueStr, err))
 		}
@@ -102,6 +408,873 @@ func checkCommand() error {
 	return nil
 }
 
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Detect configuration changes against a saved baseline",
+}
+
+var driftSnapshotCmd = &cobra.Command{
+	Use:   "snapshot --file FILE [--search TABLE] [--tag TAG]",
+	Short: "Save the configuration of all or the searched tables as baseline",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = DriftSnapshot
+		return checkDriftCommand()
+	},
+}
+
+var driftCheckCmd = &cobra.Command{
+	Use:   "check --file FILE",
+	Short: "Report differences between the baseline and the current configuration, exiting with 2 on drift",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = DriftCheck
+		return checkDriftCommand()
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history [--table TABLE] [--limit N]",
+	Short: "List the table changes recorded in the journal, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = History
+		if historyLimit < 0 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: limit:%d - must not be negative", historyLimit))
+		}
+		return nil
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback CHANGE_ID",
+	Short: "Restore the billing mode and capacities a table had before a recorded change",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Rollback
+		changeID = args[0]
+		if viper.GetInt("reserve-decreases") < 0 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: reserve-decreases:%d - must not be negative", viper.GetInt("reserve-decreases")))
+		}
+		return nil
+	},
+}
+
+var tagCmd = &cobra.Command{
+	Use:     "tag",
+	Aliases: []string{"tags"},
+	Short:   "Manage the tags of one table or of every table matched by a search",
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add KEY=VALUE...",
+	Short: "Add tags whose keys are not set yet",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTagOperation(tags.Add, args)
+	},
+}
+
+var tagSetCmd = &cobra.Command{
+	Use:   "set KEY=VALUE...",
+	Short: "Add tags, overwriting the values of existing keys",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTagOperation(tags.Set, args)
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:   "remove KEY...",
+	Short: "Remove tags by key",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTagOperation(tags.Remove, args)
+	},
+}
+
+var tagRenameKeyCmd = &cobra.Command{
+	Use:   "rename-key OLD_KEY NEW_KEY",
+	Short: "Move the value of a tag to a new key",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return setTagOperation(tags.RenameKey, args)
+	},
+}
+
+var tagAuditCmd = &cobra.Command{
+	Use:   "audit --policy FILE",
+	Short: "Report tables violating a tag policy, exiting with 2 on violations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = TagAudit
+		if policyFile == "" {
+			return errors.New("Invalid command line arguments: no policy file is provided!")
+		}
+		if dryRun && !fixTags {
+			return errors.New("Invalid command line arguments: dry-run can only be used together with fix!")
+		}
+		if targetTables != "" && (viper.GetString("search") != "" || viper.GetString("tag") != "") {
+			return errors.New("Invalid command line arguments: table can't be used together with search or tag!")
+		}
+		return nil
+	},
+}
+
+var describeCmd = &cobra.Command{
+	Use:   "describe TABLE [--output text|json|yaml]",
+	Short: "Show the complete configuration of a table",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Describe
+		describeTable = args[0]
+		return checkOutputFormat()
+	},
+}
+
+var createCmd = &cobra.Command{
+	Use:   "create --spec FILE [--table TABLE] [--wait]",
+	Short: "Create a table from a table definition file, as emitted by describe --output json|yaml",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Create
+		return checkSpecCommand()
+	},
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Export table schemas and compare them across tables, profiles, regions and definition files",
+}
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export TABLE [--output json|yaml] [--file FILE]",
+	Short: "Export the portable definition of a table, without runtime fields, as accepted by create",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = SchemaExport
+		describeTable = args[0]
+		if outputFormat != schema.JsonFormat && outputFormat != schema.YamlFormat {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: output:%s - must be json or yaml", outputFormat))
+		}
+		return nil
+	},
+}
+
+var schemaDiffCmd = &cobra.Command{
+	Use:   "diff TABLE [OTHER_TABLE] [--other-profile NAME] [--other-region REGION] | diff TABLE --spec FILE",
+	Short: "Compare the schema of a table with another table or a definition file, exiting with 2 if they differ",
+	Long: `Compare the schema of a table with another table or a definition file, exiting with 2 if they differ.
+
+The other table defaults to the same name and lives in --other-profile and --other-region, which default to the
+profile and region of the table. Keys, attributes, capacity, indexes, projections and features are compared,
+tags only with --tags.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = SchemaDiff
+		describeTable = args[0]
+		if len(args) == 2 {
+			diffTarget.TableName = args[1]
+		}
+		if diffTarget.SpecFile != "" && (diffTarget.TableName != "" || diffTarget.Profile != "" || diffTarget.Region != "") {
+			return errors.New("Invalid command line arguments: spec can't be used together with another table, profile or region!")
+		}
+		return nil
+	},
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [--table TABLE[,TABLE...] | [--search TABLE] [--tag TAG]] [--name-prefix PREFIX] [--older-than AGE] [--final-backup] [--dry-run]",
+	Short: "Delete tables after typing their names to confirm",
+	Long: `Delete tables after typing their names to confirm.
+
+Tables with deletion protection, with replicas or carrying a protected tag are refused. With only --name-prefix,
+every table starting with the prefix is selected, e.g. --name-prefix pr- --older-than 30d to clean up stale test
+tables. Use --dry-run to list the tables first.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Delete
+		if targetTables == "" && viper.GetString("search") == "" && viper.GetString("tag") == "" && deleteOptions.NamePrefix == "" {
+			return errors.New("Invalid command line arguments: no table, search, tag or name prefix is provided!")
+		}
+		if deleteAge != "" {
+			age, err := client.ParseAge(deleteAge)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+			}
+			deleteOptions.OlderThan = age
+		}
+		for _, tag := range deleteOptions.ProtectedTags {
+			if key, _, _ := strings.Cut(tag, "="); key == "" {
+				return errors.New(fmt.Sprintf("Invalid command line arguments: protected-tag:%s - must be KEY or KEY=VALUE", tag))
+			}
+		}
+		deleteOptions.DryRun = dryRun
+		return checkOptionalTargetCommand()
+	},
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the global secondary indexes of a table",
+}
+
+var indexListCmd = &cobra.Command{
+	Use:   "list TABLE",
+	Short: "Show the indexes of a table with their status, backfilling, keys, projection, throughput and size",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = IndexList
+		describeTable = args[0]
+		return nil
+	},
+}
+
+var indexAddCmd = &cobra.Command{
+	Use:   "add TABLE --spec FILE [--wait]",
+	Short: "Add a global secondary index from a spec file, in the globalSecondaryIndexes format of describe",
+	Long: `Add a global secondary index from a spec file, in the globalSecondaryIndexes format of describe.
+
+The spec holds indexName, keySchema, projection, rcu and wcu for provisioned tables or optional on-demand limits,
+and attributes defining the key attributes the table doesn't have yet. DynamoDB backfills the new index from the
+existing items, --wait tracks the backfill until the index is ACTIVE.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = IndexAdd
+		describeTable = args[0]
+		if specFile == "" {
+			return errors.New("Invalid command line arguments: no spec file is provided!")
+		}
+		return nil
+	},
+}
+
+var indexDeleteCmd = &cobra.Command{
+	Use:   "delete TABLE INDEX [--dry-run]",
+	Short: "Delete a global secondary index after typing its name to confirm",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = IndexDelete
+		describeTable = args[0]
+		indexName = args[1]
+		return nil
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list [--columns COL[,COL...]] [--filter COL=VALUE]... [--sort-by COL [--reverse]] [--output text|csv|json]",
+	Short: "List every table, or the searched tables, with the chosen columns",
+	Long: `List every table, or the searched tables, with the chosen columns.
+
+Columns: name, region, arn, status, billing, rcu, wcu, items, size, class, gsis, deletion-protection, pitr, stream,
+stream-arn, stream-label, global, replicas, ttl, ttl-attribute, created, age-days, reads, writes and tag:KEY.
+The reads and writes columns sum the consumed capacity units of the last --usage-days days from CloudWatch.
+Filters compare a column with =, !=, >, >=, < or <=, e.g. billing=PROVISIONED, size>1000000, name=pr-*, tag:owner=
+or reads=0`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = List
+		if viper.GetString("update") != "" {
+			return errors.New("Invalid command line arguments: list can't be used together with update!")
+		}
+		for _, name := range append(splitList(listColumns), sortBy) {
+			if _, err := search.GetColumn(name); name != "" && err != nil {
+				return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+			}
+		}
+		if err := checkFilters(); err != nil {
+			return err
+		}
+		if outputFormat != search.TextFormat && outputFormat != search.CsvFormat && outputFormat != search.JsonFormat {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: output:%s - must be text, csv or json", outputFormat))
+		}
+		return nil
+	},
+}
+
+var scalingCmd = &cobra.Command{
+	Use:   "scaling",
+	Short: "Manage the Application Auto Scaling of provisioned tables and their indexes",
+}
+
+var scalingShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the auto scaling limits and target utilization of tables and their indexes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ScalingShow
+		return checkTargetCommand()
+	},
+}
+
+var scalingSetCmd = &cobra.Command{
+	Use:   "set [--index INDEX] [--dimension read,write] [--min N] [--max N] [--target PERCENT]",
+	Short: "Enable target tracking auto scaling or change its limits and target utilization",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ScalingSet
+		if scalingChange.MinCapacity == 0 && scalingChange.MaxCapacity == 0 && scalingChange.TargetUtilization == 0 {
+			return errors.New("Invalid command line arguments: no min, max or target is provided!")
+		}
+		if scalingChange.MinCapacity < 0 || scalingChange.MaxCapacity < 0 || (scalingChange.MaxCapacity > 0 && scalingChange.MinCapacity > scalingChange.MaxCapacity) {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: min:%d - max:%d", scalingChange.MinCapacity, scalingChange.MaxCapacity))
+		}
+		if scalingChange.TargetUtilization != 0 && (scalingChange.TargetUtilization < scaling.MinTargetUtilization || scalingChange.TargetUtilization > scaling.MaxTargetUtilization) {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: target:%.1f - must be between %d and %d", scalingChange.TargetUtilization, scaling.MinTargetUtilization, scaling.MaxTargetUtilization))
+		}
+		return checkScalingDimensions()
+	},
+}
+
+var scalingRemoveCmd = &cobra.Command{
+	Use:   "remove [--index INDEX] [--dimension read,write]",
+	Short: "Stop auto scaling, keeping the current provisioned capacity",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ScalingRemove
+		return checkScalingDimensions()
+	},
+}
+
+var pitrCmd = &cobra.Command{
+	Use:   "pitr",
+	Short: "Manage the point-in-time recovery (continuous backups) of tables",
+}
+
+var pitrShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the point-in-time recovery status and the earliest and latest restorable times",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = PitrShow
+		return checkTargetCommand()
+	},
+}
+
+var pitrEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Enable point-in-time recovery",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = PitrEnable
+		return checkTargetCommand()
+	},
+}
+
+var pitrDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable point-in-time recovery, dropping the restore window",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = PitrDisable
+		return checkTargetCommand()
+	},
+}
+
+var pitrReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the tables without point-in-time recovery, exiting with 2 if there are any",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = PitrReport
+		if dryRun {
+			return errors.New("Invalid command line arguments: dry-run can only be used together with enable or disable!")
+		}
+		return checkOptionalTargetCommand()
+	},
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Create, list, delete, restore and prune on-demand backups",
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create [--label LABEL] [--wait]",
+	Short: "Create an on-demand backup named TABLE-LABEL-TIMESTAMP of each table",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = BackupCreate
+		if dryRun {
+			return errors.New("Invalid command line arguments: dry-run can only be used together with delete or prune!")
+		}
+		return checkTargetCommand()
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the on-demand backups of the tables, or of all tables, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = BackupList
+		if dryRun {
+			return errors.New("Invalid command line arguments: dry-run can only be used together with delete or prune!")
+		}
+		return checkOptionalTargetCommand()
+	},
+}
+
+var backupDeleteCmd = &cobra.Command{
+	Use:   "delete BACKUP_ARN...",
+	Short: "Delete on-demand backups",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = BackupDelete
+		backupArns = args
+		if targetTables != "" || viper.GetString("search") != "" || viper.GetString("tag") != "" {
+			return errors.New("Invalid command line arguments: backup delete takes backup ARNs, table, search and tag are not supported!")
+		}
+		return nil
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore BACKUP_ARN --target-table TABLE [--wait]",
+	Short: "Restore an on-demand backup to a new table",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = BackupRestore
+		backupArns = args
+		if restoreTable == "" {
+			return errors.New("Invalid command line arguments: no target table is provided!")
+		}
+		if dryRun || targetTables != "" || viper.GetString("search") != "" || viper.GetString("tag") != "" {
+			return errors.New("Invalid command line arguments: backup restore doesn't support dry-run, table, search or tag!")
+		}
+		return nil
+	},
+}
+
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune [--older-than AGE] [--keep N]",
+	Short: "Delete the on-demand backups older than AGE or beyond the newest N of each table",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = BackupPrune
+		if pruneAge == "" && pruneOptions.Keep == 0 {
+			return errors.New("Invalid command line arguments: no older-than or keep is provided!")
+		}
+		if pruneOptions.Keep < 0 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: keep:%d - must not be negative", pruneOptions.Keep))
+		}
+		if pruneAge != "" {
+			age, err := client.ParseAge(pruneAge)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+			}
+			pruneOptions.MaxAge = age
+		}
+		pruneOptions.DryRun = dryRun
+		return checkTargetCommand()
+	},
+}
+
+var ttlCmd = &cobra.Command{
+	Use:   "ttl",
+	Short: "Manage the Time to Live of tables",
+}
+
+var ttlShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the Time to Live status and attribute",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = TtlShow
+		if dryRun {
+			return errors.New("Invalid command line arguments: dry-run can only be used together with enable or disable!")
+		}
+		return checkTargetCommand()
+	},
+}
+
+var ttlEnableCmd = &cobra.Command{
+	Use:   "enable --attribute NAME",
+	Short: "Enable Time to Live on an attribute, expired items get deleted",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = TtlEnable
+		if ttlAttribute == "" {
+			return errors.New("Invalid command line arguments: no attribute is provided!")
+		}
+		return checkTargetCommand()
+	},
+}
+
+var ttlDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Disable Time to Live",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = TtlDisable
+		return checkTargetCommand()
+	},
+}
+
+var replicaCmd = &cobra.Command{
+	Use:   "replica",
+	Short: "Manage the replicas of global tables",
+}
+
+var replicaShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the replica regions of tables with the status and settings of each replica",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ReplicaShow
+		return checkTargetCommand()
+	},
+}
+
+// checkReplicaSettings validates the per-replica table class and read capacity of replica add and update.
+// It returns an error if the table class is unknown or the read capacity is negative.
+func checkReplicaSettings() error {
+	replicaTableClass = ""
+	if value := viper.GetString("table-class"); value != "" {
+		parsedClass, err := client.ParseTableClass(value)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+		}
+		replicaTableClass = parsedClass
+	}
+	if replicaReadCapacity < 0 {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: read-capacity:%d - must not be negative", replicaReadCapacity))
+	}
+	return nil
+}
+
+var replicaAddCmd = &cobra.Command{
+	Use:   "add TABLE REGION [--table-class standard|standard-ia] [--read-capacity N] [--wait]",
+	Short: "Add a replica in another region, turning the table into a global table",
+	Long: `Add a replica in another region, turning the table into a global table.
+
+The replica uses the table class and capacity of the table, unless --table-class or --read-capacity, for provisioned
+tables, override them in that region. The write capacity is always shared by all replicas. DynamoDB copies the
+existing items to the new replica, --wait tracks the copy until the replica is ACTIVE.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ReplicaAdd
+		describeTable = args[0]
+		replicaRegion = args[1]
+		return checkReplicaSettings()
+	},
+}
+
+var replicaUpdateCmd = &cobra.Command{
+	Use:   "update TABLE REGION [--table-class standard|standard-ia] [--read-capacity N] [--dry-run]",
+	Short: "Change the table class or read capacity of a replica",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ReplicaUpdate
+		describeTable = args[0]
+		replicaRegion = args[1]
+		if err := checkReplicaSettings(); err != nil {
+			return err
+		}
+		if replicaTableClass == "" && replicaReadCapacity == 0 {
+			return errors.New("Invalid command line arguments: no table-class or read-capacity is provided!")
+		}
+		return nil
+	},
+}
+
+var replicaRemoveCmd = &cobra.Command{
+	Use:   "remove TABLE REGION [--dry-run]",
+	Short: "Remove a replica after typing its region to confirm, deleting its items in that region",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ReplicaRemove
+		describeTable = args[0]
+		replicaRegion = args[1]
+		return nil
+	},
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Apply capacity changes on a cron schedule",
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run --file FILE [--state FILE] [--once]",
+	Short: "Run the scheduler until interrupted, retrying changes blocked by cooldowns",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ScheduleRun
+		return checkScheduleCommand()
+	},
+}
+
+var scheduleShowCmd = &cobra.Command{
+	Use:   "show --file FILE [--state FILE]",
+	Short: "Show the next and last runs of every schedule entry",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = ScheduleShow
+		return checkScheduleCommand()
+	},
+}
+
+var recommendCmd = &cobra.Command{
+	Use:   "recommend [--days N] [--target PERCENT] [--metrics-file FILE | --record-metrics FILE] [--apply]",
+	Short: "Suggest provisioned capacities or billing mode switches from CloudWatch metrics with their monthly cost",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Recommend
+		if lookbackDays < 1 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: days:%d - must be at least 1", lookbackDays))
+		}
+		recommendOptions.Lookback = time.Duration(lookbackDays) * 24 * time.Hour
+		if recommendOptions.TargetUtilization < scaling.MinTargetUtilization || recommendOptions.TargetUtilization > scaling.MaxTargetUtilization {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: target:%.1f - must be between %d and %d", recommendOptions.TargetUtilization, scaling.MinTargetUtilization, scaling.MaxTargetUtilization))
+		}
+		if recommendOptions.MetricsFile != "" && recommendOptions.RecordFile != "" {
+			return errors.New("Invalid command line arguments: metrics-file can't be used together with record-metrics!")
+		}
+		recommendOptions.ReserveDecreases = viper.GetInt("reserve-decreases")
+		if recommendOptions.ReserveDecreases < 0 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: reserve-decreases:%d - must not be negative", recommendOptions.ReserveDecreases))
+		}
+		if !recommendOptions.Apply && recommendOptions.ReserveDecreases != 0 {
+			return errors.New("Invalid command line arguments: reserve-decreases can only be used together with apply!")
+		}
+		return checkTargetCommand()
+	},
+}
+
+var costCmd = &cobra.Command{
+	Use:   "cost [--ondemand|--provisioned] [--rcu READ_CAP] [--wcu WRITE_CAP] [--days N] [--metrics-file FILE]",
+	Short: "Estimate the monthly cost of tables, before and after a proposed billing mode or capacity change",
+	Long: `Estimate the monthly cost of tables, before and after a proposed billing mode or capacity change.
+
+Prices are read for the region of the profile from the --prices file, which holds per region the prices of the
+standard and standardIa table classes: rcuHour, wcuHour, millionReadRequestUnits, millionWriteRequestUnits and gbMonth.
+On-demand capacity is estimated from the consumed capacity of the last --days days.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Cost
+		costOptions.Change = update.UpdateOptions{
+			Rcu:                 viper.GetString("rcu"),
+			Wcu:                 viper.GetString("wcu"),
+			SwitchToOnDemand:    viper.GetBool("ondemand"),
+			SwitchToProvisioned: viper.GetBool("provisioned"),
+			MinCapacity:         viper.GetInt64("min-capacity"),
+			MaxCapacity:         viper.GetInt64("max-capacity"),
+		}
+		if value := viper.GetString("table-class"); value != "" {
+			parsedClass, err := client.ParseTableClass(value)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+			}
+			costOptions.Change.TableClass = parsedClass
+		}
+		if err := checkCostChange(costOptions.Change); err != nil {
+			return err
+		}
+		if lookbackDays < 1 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: days:%d - must be at least 1", lookbackDays))
+		}
+		costOptions.Lookback = time.Duration(lookbackDays) * 24 * time.Hour
+		return checkTargetCommand()
+	},
+}
+
+var staleCmd = &cobra.Command{
+	Use:   "stale [--days N] [--metrics-file FILE]",
+	Short: "Report the tables without reads and writes, with the monthly cost of keeping them",
+	Long: `Report the tables without reads and writes, with the monthly cost of keeping them.
+
+Every table, or the selected ones, that consumed no read or write capacity in the last --days days is reported,
+most expensive first. Tables created within that window are too new to tell and skipped.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		action = Stale
+		if lookbackDays < 1 {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: days:%d - must be at least 1", lookbackDays))
+		}
+		staleOptions.Lookback = time.Duration(lookbackDays) * 24 * time.Hour
+		return checkOptionalTargetCommand()
+	},
+}
+
+// deletionProtectionChange returns the deletion protection requested with protect or unprotect, nil if none is.
+func deletionProtectionChange() *bool {
+	if !protect && !unprotect {
+		return nil
+	}
+	return &protect
+}
+
+// checkFilters checks the filter expressions of search and list.
+// It returns an error if a filter is not valid.
+func checkFilters() error {
+	for _, expression := range searchFilters {
+		if _, err := search.ParseFilter(expression); err != nil {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+		}
+	}
+	if viper.GetInt("usage-days") < 1 {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: usage-days:%d - must be at least 1", viper.GetInt("usage-days")))
+	}
+	return nil
+}
+
+// checkCostChange checks the change proposed to the cost command.
+// It returns an error if the arguments are not valid.
+func checkCostChange(change update.UpdateOptions) error {
+	if change.SwitchToOnDemand && change.SwitchToProvisioned {
+		return errors.New("Invalid command line arguments: ondemand can't be used together with provisioned!")
+	}
+
+	if change.SwitchToOnDemand && (change.Rcu != "" || change.Wcu != "") {
+		return errors.New("Invalid command line arguments: ondemand model does not support rcu or wcu!")
+	}
+
+	if change.MinCapacity < 0 || change.MaxCapacity < 0 || (change.MaxCapacity > 0 && change.MinCapacity > change.MaxCapacity) {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: min-capacity:%d - max-capacity:%d", change.MinCapacity, change.MaxCapacity))
+	}
+
+	for _, value := range []string{change.Rcu, change.Wcu} {
+		if value == "" {
+			continue
+		}
+		if _, err := update.ParseCapacityChange(value); err != nil {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: capacity:%s - error:%v", value, err))
+		}
+	}
+	return nil
+}
+
+// checkScheduleCommand checks the validity of the schedule command line arguments.
+// It returns an error if the arguments are not valid.
+func checkScheduleCommand() error {
+	if scheduleFile == "" {
+		return errors.New("Invalid command line arguments: no schedule file is provided!")
+	}
+
+	if stateFile == "" {
+		return errors.New("Invalid command line arguments: no state file is provided!")
+	}
+
+	if viper.GetString("search") != "" || viper.GetString("tag") != "" || viper.GetString("update") != "" {
+		return errors.New("Invalid command line arguments: schedule selects its tables in the schedule file, search, tag and update are not supported!")
+	}
+	return nil
+}
+
+// checkScalingDimensions parses the auto scaling dimensions and checks the table selection.
+// It returns an error if the arguments are not valid.
+func checkScalingDimensions() error {
+	scalingChange.Dimensions = splitList(scalingDimensions)
+	if len(scalingChange.Dimensions) == 0 {
+		return errors.New("Invalid command line arguments: no dimension is provided!")
+	}
+	for _, dimension := range scalingChange.Dimensions {
+		if dimension != scaling.Read && dimension != scaling.Write {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: dimension:%s - must be read or write", dimension))
+		}
+	}
+	return checkTargetCommand()
+}
+
+// checkOutputFormat checks that the output format is one of text, json or yaml.
+// It returns an error if the format is not supported.
+func checkOutputFormat() error {
+	switch outputFormat {
+	case schema.TextFormat, schema.JsonFormat, schema.YamlFormat:
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("Invalid command line arguments: output:%s - must be text, json or yaml", outputFormat))
+	}
+}
+
+// setTagOperation builds the tag operation from the subcommand arguments and checks the table selection.
+// It returns an error if the arguments are not valid.
+func setTagOperation(tagAction string, args []string) error {
+	action = Tag
+	tagOperation = tags.TagOperation{Action: tagAction}
+
+	switch tagAction {
+	case tags.Add, tags.Set:
+		parsedTags, err := tags.ParseTagArgs(args)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Invalid command line arguments: %v", err))
+		}
+		tagOperation.Tags = parsedTags
+	case tags.Remove:
+		tagOperation.Keys = args
+	case tags.RenameKey:
+		if args[0] == args[1] {
+			return errors.New("Invalid command line arguments: old and new tag key must differ!")
+		}
+		tagOperation.OldKey, tagOperation.NewKey = args[0], args[1]
+	}
+	return checkTargetCommand()
+}
+
+// checkTargetCommand checks that the tables to act on are selected either by name or by search, but not both.
+// It returns an error if the arguments are not valid.
+func checkTargetCommand() error {
+	hasSearch := viper.GetString("search") != "" || viper.GetString("tag") != ""
+	if targetTables == "" && !hasSearch {
+		return errors.New("Invalid command line arguments: no table or search conditions are provided!")
+	}
+
+	if targetTables != "" && hasSearch {
+		return errors.New("Invalid command line arguments: table can't be used together with search or tag!")
+	}
+
+	if viper.GetString("update") != "" {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: %s can't be used together with update!", action))
+	}
+	return nil
+}
+
+// checkOptionalTargetCommand checks the table selection of commands acting on all tables when none are selected.
+// It returns an error if the arguments are not valid.
+func checkOptionalTargetCommand() error {
+	if targetTables != "" && (viper.GetString("search") != "" || viper.GetString("tag") != "") {
+		return errors.New("Invalid command line arguments: table can't be used together with search or tag!")
+	}
+
+	if viper.GetString("update") != "" {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: %s can't be used together with update!", action))
+	}
+	return nil
+}
+
+// checkDriftCommand checks the validity of the drift command line arguments.
+// It returns an error if the arguments are not valid.
+func checkDriftCommand() error {
+	if snapshotFile == "" {
+		return errors.New("Invalid command line arguments: no snapshot file is provided!")
+	}
+
+	if viper.GetString("update") != "" {
+		return errors.New("Invalid command line arguments: drift can't be used together with update!")
+	}
+
+	if action == DriftCheck && (viper.GetString("search") != "" || viper.GetString("tag") != "") {
+		return errors.New("Invalid command line arguments: drift check uses the tables of the snapshot, search and tag are not supported!")
+	}
+	return nil
+}
+
+// checkSpecCommand checks the validity of the plan, apply and create command line arguments.
+// It returns an error if the arguments are not valid.
+func checkSpecCommand() error {
+	if specFile == "" {
+		return errors.New("Invalid command line arguments: no spec file is provided!")
+	}
+
+	if viper.GetString("search") != "" || viper.GetString("tag") != "" || viper.GetString("update") != "" {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: %s can't be used together with search, tag or update!", action))
+	}
+
+	if viper.GetInt("reserve-decreases") < 0 {
+		return errors.New(fmt.Sprintf("Invalid command line arguments: reserve-decreases:%d - must not be negative", viper.GetInt("reserve-decreases")))
+	}
+	return nil
+}
+
 // dumpParams logs the passed arguments to the logger in debug mode.
 func dumpParams(dbmgr *client.DynamoDBManager) {
 	dbmgr.Logger.Debugf("Debug info - passed args listed here:")
@@ -112,22 +1285,165 @@ func dumpParams(dbmgr *client.DynamoDBManager) {
 	dbmgr.Logger.Debugf("WCU Value: %s\n", wcuValueStr)
 	dbmgr.Logger.Debugf("Provisioned: %t\n", provisioned)
 	dbmgr.Logger.Debugf("On-Demand: %t\n", onDemand)
+	dbmgr.Logger.Debugf("Reserve Decreases: %d\n", reserveDecreases)
+	dbmgr.Logger.Debugf("Min Capacity: %d\n", minCapacity)
+	dbmgr.Logger.Debugf("Max Capacity: %d\n", maxCapacity)
+	dbmgr.Logger.Debugf("Table Class: %s\n", tableClass)
+	dbmgr.Logger.Debugf("Protect: %t - unprotect: %t\n", protect, unprotect)
+	dbmgr.Logger.Debugf("Stream View Type: %s\n", streamViewType)
+	dbmgr.Logger.Debugf("Search Filters: %v\n", searchFilters)
+	dbmgr.Logger.Debugf("Spec File: %s\n", specFile)
+	dbmgr.Logger.Debugf("Snapshot File: %s\n", snapshotFile)
+	dbmgr.Logger.Debugf("Journal File: %s\n", dbmgr.JournalFile)
+	dbmgr.Logger.Debugf("Change ID: %s\n", changeID)
+	dbmgr.Logger.Debugf("Target Tables: %s\n", targetTables)
+	dbmgr.Logger.Debugf("Dry Run: %t\n", dryRun)
+	dbmgr.Logger.Debugf("Describe Table: %s\n", describeTable)
+	dbmgr.Logger.Debugf("Output Format: %s\n", outputFormat)
+	dbmgr.Logger.Debugf("Create Table: %s - wait: %t\n", createTable, waitDone)
+	dbmgr.Logger.Debugf("Export File: %s\n", exportFile)
+	dbmgr.Logger.Debugf("Diff Target: %+v - tags: %t\n", diffTarget, diffTags)
+	dbmgr.Logger.Debugf("Delete Options: %+v\n", deleteOptions)
+	dbmgr.Logger.Debugf("Index Name: %s\n", indexName)
+	dbmgr.Logger.Debugf("List Columns: %s\n", listColumns)
+	dbmgr.Logger.Debugf("Sort By: %s - reverse: %t\n", sortBy, reverseSort)
+	dbmgr.Logger.Debugf("Scaling Change: %+v\n", scalingChange)
+	dbmgr.Logger.Debugf("Schedule File: %s - state: %s - once: %t\n", scheduleFile, stateFile, runOnce)
+	dbmgr.Logger.Debugf("Recommend Options: %+v\n", recommendOptions)
+	dbmgr.Logger.Debugf("Cost Options: %+v\n", costOptions)
+	dbmgr.Logger.Debugf("Stale Options: %+v\n", staleOptions)
+	dbmgr.Logger.Debugf("Usage Lookback: %v\n", search.UsageLookback)
+	dbmgr.Logger.Debugf("Price File: %s\n", dbmgr.PriceFile)
+	dbmgr.Logger.Debugf("Backup Label: %s - ARNs: %v - restore table: %s - wait: %t\n", backupLabel, backupArns, restoreTable, waitDone)
+	dbmgr.Logger.Debugf("Prune Options: %+v\n", pruneOptions)
+	dbmgr.Logger.Debugf("TTL Attribute: %s\n", ttlAttribute)
+	dbmgr.Logger.Debugf("Replica Region: %s - table class: %s - read capacity: %d\n", replicaRegion, replicaTableClass, replicaReadCapacity)
+	dbmgr.Logger.Debugf("Require Protection: %t - allow unprotected: %t\n", dbmgr.RequireProtection, dbmgr.AllowUnprotected)
 }
 
 // initCommand initializes the command-line flags, parses them, and binds them to viper.
 // It returns an error if there's any issue with the command line arguments.
 func initCommand() error {
 	rootCmd.PersistentFlags().StringP("level", "", "Info", "Setup the log level")
+	rootCmd.PersistentFlags().StringP("profile", "", "", "Name of the AWS profile to use")
+	rootCmd.PersistentFlags().StringP("journal", "", client.DefaultJournalFile(), "Journal file recording every table update, empty disables it")
 	rootCmd.PersistentFlags().StringP("search", "", "", "Search term for DynamoDB table names")
 	rootCmd.PersistentFlags().StringP("tag", "", "", "Value of the tag for DynamoDB table search")
-	rootCmd.PersistentFlags().StringP("update", "", "", "Name of the DynamoDB table to update")
-	rootCmd.PersistentFlags().StringP("rcu", "", "", "Read Capacity Units")
-	rootCmd.PersistentFlags().StringP("wcu", "", "", "Write Capacity Units")
+	rootCmd.PersistentFlags().StringP("update", "", "", "Comma separated names of the DynamoDB tables to update")
+	rootCmd.PersistentFlags().StringP("rcu", "", "", "Read Capacity Units, absolute or relative (+50, -20%, x2) - omitted keeps the current value, or 5 when switching to provisioned")
+	rootCmd.PersistentFlags().StringP("wcu", "", "", "Write Capacity Units, absolute or relative (+50, -20%, x2) - omitted keeps the current value, or 5 when switching to provisioned")
+	rootCmd.PersistentFlags().Int64("min-capacity", 0, "Lower limit for the resolved rcu and wcu")
+	rootCmd.PersistentFlags().Int64("max-capacity", 0, "Upper limit for the resolved rcu and wcu, 0 means no limit")
 	rootCmd.PersistentFlags().Bool("provisioned", false, "Provisioned capacity mode")
 	rootCmd.PersistentFlags().Bool("ondemand", false, "On-Demand capacity mode")
+	rootCmd.PersistentFlags().String("table-class", "", "Table class to switch the updated tables, or the added and updated replicas, to: standard or standard-ia")
+	rootCmd.PersistentFlags().StringArrayVar(&searchFilters, "filter", nil, "Only keep the tables matching COL=VALUE, COL!=VALUE, COL>N, ... (repeatable), e.g. class=standard-ia")
+	rootCmd.PersistentFlags().Int("usage-days", int(search.UsageLookback.Hours()/24), "Number of days the reads and writes filter columns sum the consumed capacity over")
+	rootCmd.PersistentFlags().Bool("protect", false, "Enable the deletion protection of the updated tables")
+	rootCmd.PersistentFlags().Bool("unprotect", false, "Disable the deletion protection of the updated tables")
+	rootCmd.PersistentFlags().Bool("unprotected", false, "Only keep the tables without deletion protection, same as --filter deletion-protection=false")
+	rootCmd.PersistentFlags().String("stream-view-type", "", "Enable the stream of the updated tables, or change its view type: new-image, old-image, new-and-old-images or keys-only")
+	rootCmd.PersistentFlags().Bool("disable-stream", false, "Disable the stream of the updated tables")
+	rootCmd.PersistentFlags().String("event-source-mappings", "", "Output of aws lambda list-event-source-mappings (JSON or YAML) to warn about consumers of a stream going away")
+	rootCmd.PersistentFlags().Bool("backup-first", false, "Create an on-demand backup of each updated table and wait for it before changing the table")
+	rootCmd.PersistentFlags().Bool("require-protection", false, "Refuse destructive operations on tables without deletion protection and disabling deletion protection")
+	rootCmd.PersistentFlags().Bool("allow-unprotected", false, "Override --require-protection, destructive operations on unprotected tables are only reported")
+	rootCmd.PersistentFlags().Int("reserve-decreases", 0, "Number of daily capacity decreases that an update must leave unused")
+	rootCmd.PersistentFlags().StringP("prices", "", "", "YAML or JSON price table file for cost estimates, defaults to "+client.DefaultPriceFile()+" or the built-in us-east-1 prices")
 
 	viper.BindPFlags(rootCmd.PersistentFlags())
 
+	planCmd.Flags().StringVar(&specFile, "spec", "", "Path of the YAML or JSON desired-state file")
+	applyCmd.Flags().StringVar(&specFile, "spec", "", "Path of the YAML or JSON desired-state file")
+	driftSnapshotCmd.Flags().StringVar(&snapshotFile, "file", "", "Path of the YAML or JSON snapshot file")
+	driftCheckCmd.Flags().StringVar(&snapshotFile, "file", "", "Path of the YAML or JSON snapshot file")
+	driftCmd.AddCommand(driftSnapshotCmd, driftCheckCmd)
+	historyCmd.Flags().StringVar(&historyTable, "table", "", "Only list the changes of this table")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 0, "Maximum number of changes to list, 0 lists all")
+	tagCmd.PersistentFlags().StringVar(&targetTables, "table", "", "Comma separated names of the tables to tag, instead of --search/--tag")
+	tagCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only show the tag changes")
+	tagAuditCmd.Flags().StringVar(&policyFile, "policy", "", "Path of the YAML or JSON tag policy file")
+	tagAuditCmd.Flags().BoolVar(&fixTags, "fix", false, "Fix missing tags with defaults and wrongly cased keys and values")
+	tagCmd.AddCommand(tagAddCmd, tagSetCmd, tagRemoveCmd, tagRenameKeyCmd, tagAuditCmd)
+	describeCmd.Flags().StringVar(&outputFormat, "output", schema.TextFormat, "Output format: text, json or yaml")
+	createCmd.Flags().StringVar(&specFile, "spec", "", "Path of the YAML or JSON table definition file")
+	createCmd.Flags().StringVar(&createTable, "table", "", "Name of the new table, instead of the tableName of the file")
+	createCmd.Flags().BoolVar(&waitDone, "wait", false, "Wait until the table is ACTIVE")
+	schemaExportCmd.Flags().StringVar(&outputFormat, "output", schema.YamlFormat, "Output format: json or yaml")
+	schemaExportCmd.Flags().StringVar(&exportFile, "file", "", "Write the definition to this file instead of the standard output")
+	schemaDiffCmd.Flags().StringVar(&diffTarget.Profile, "other-profile", "", "AWS profile of the other table")
+	schemaDiffCmd.Flags().StringVar(&diffTarget.Region, "other-region", "", "Region of the other table")
+	schemaDiffCmd.Flags().StringVar(&diffTarget.SpecFile, "spec", "", "Compare with this YAML or JSON table definition file instead of a table")
+	schemaDiffCmd.Flags().BoolVar(&diffTags, "tags", false, "Compare the tags too")
+	schemaCmd.AddCommand(schemaExportCmd, schemaDiffCmd)
+	deleteCmd.Flags().StringVar(&targetTables, "table", "", "Comma separated names of the tables to delete, instead of --search/--tag")
+	deleteCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the tables that would be deleted")
+	deleteCmd.Flags().StringVar(&deleteAge, "older-than", "", "Only delete the tables created longer ago than this age, e.g. 30d, 2w or 12h")
+	deleteCmd.Flags().StringVar(&deleteOptions.NamePrefix, "name-prefix", "", "Only delete the tables whose name starts with this prefix")
+	deleteCmd.Flags().StringArrayVar(&deleteOptions.ProtectedTags, "protected-tag", cleanup.DefaultProtectedTags, "Never delete tables carrying this tag, KEY or KEY=VALUE")
+	deleteCmd.Flags().BoolVar(&deleteOptions.FinalBackup, "final-backup", false, "Take an on-demand backup and wait until it is available before deleting each table")
+	indexAddCmd.Flags().StringVar(&specFile, "spec", "", "Path of the YAML or JSON index spec file")
+	indexAddCmd.Flags().BoolVar(&waitDone, "wait", false, "Wait until the index is backfilled and ACTIVE")
+	indexDeleteCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the index that would be deleted")
+	indexCmd.AddCommand(indexListCmd, indexAddCmd, indexDeleteCmd)
+	listCmd.Flags().StringVar(&listColumns, "columns", strings.Join(search.DefaultColumns, ","), "Comma separated columns to show")
+	listCmd.Flags().StringVar(&sortBy, "sort-by", "name", "Column to sort the tables by")
+	listCmd.Flags().BoolVar(&reverseSort, "reverse", false, "Sort in descending order")
+	listCmd.Flags().StringVar(&outputFormat, "output", search.TextFormat, "Output format: text, csv or json")
+	scalingCmd.PersistentFlags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	scalingCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only show the auto scaling changes")
+	for _, cmd := range []*cobra.Command{scalingSetCmd, scalingRemoveCmd} {
+		cmd.Flags().StringVar(&scalingChange.IndexName, "index", "", "Global secondary index to change instead of the table")
+		cmd.Flags().StringVar(&scalingDimensions, "dimension", "read,write", "Comma separated capacity dimensions: read, write")
+	}
+	scalingSetCmd.Flags().Int32Var(&scalingChange.MinCapacity, "min", 0, "Minimum capacity units")
+	scalingSetCmd.Flags().Int32Var(&scalingChange.MaxCapacity, "max", 0, "Maximum capacity units")
+	scalingSetCmd.Flags().Float64Var(&scalingChange.TargetUtilization, "target", 0, "Target utilization in percent")
+	scalingCmd.AddCommand(scalingShowCmd, scalingSetCmd, scalingRemoveCmd)
+	scheduleCmd.PersistentFlags().StringVar(&scheduleFile, "file", "", "Path of the YAML or JSON schedule file")
+	scheduleCmd.PersistentFlags().StringVar(&stateFile, "state", schedule.DefaultStateFile(), "Path of the scheduler state file")
+	scheduleRunCmd.Flags().BoolVar(&runOnce, "once", false, "Handle the due entries and retries once and exit")
+	scheduleCmd.AddCommand(scheduleRunCmd, scheduleShowCmd)
+	recommendCmd.Flags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	recommendCmd.Flags().IntVar(&lookbackDays, "days", int(cost.DefaultLookback.Hours()/24), "Number of days of metrics to analyze")
+	recommendCmd.Flags().Float64Var(&recommendOptions.TargetUtilization, "target", cost.DefaultTargetUtilization, "Target utilization of the recommended provisioned capacity in percent")
+	recommendCmd.Flags().StringVar(&recommendOptions.MetricsFile, "metrics-file", "", "Replay the metrics recorded in this YAML or JSON file instead of reading CloudWatch")
+	recommendCmd.Flags().StringVar(&recommendOptions.RecordFile, "record-metrics", "", "Save the metrics read from CloudWatch to this YAML or JSON file")
+	recommendCmd.Flags().BoolVar(&recommendOptions.Apply, "apply", false, "Apply the recommendations through the update path")
+	costCmd.Flags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	costCmd.Flags().IntVar(&lookbackDays, "days", int(cost.DefaultLookback.Hours()/24), "Number of days of consumed capacity to estimate on-demand costs from")
+	costCmd.Flags().StringVar(&costOptions.MetricsFile, "metrics-file", "", "Replay the metrics recorded in this YAML or JSON file instead of reading CloudWatch")
+	staleCmd.Flags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	staleCmd.Flags().IntVar(&lookbackDays, "days", int(cost.DefaultStaleLookback.Hours()/24), "Number of days without reads and writes after which a table is stale")
+	staleCmd.Flags().StringVar(&staleOptions.MetricsFile, "metrics-file", "", "Replay the metrics recorded in this YAML or JSON file instead of reading CloudWatch")
+	pitrCmd.PersistentFlags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	pitrCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only show the point-in-time recovery changes")
+	pitrCmd.AddCommand(pitrShowCmd, pitrEnableCmd, pitrDisableCmd, pitrReportCmd)
+	backupCmd.PersistentFlags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	backupCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only show the backups that would be deleted")
+	backupCreateCmd.Flags().StringVar(&backupLabel, "label", backup.ManualLabel, "Label in the backup names, e.g. before-migration")
+	for _, cmd := range []*cobra.Command{backupCreateCmd, backupRestoreCmd} {
+		cmd.Flags().BoolVar(&waitDone, "wait", false, "Wait until the backups are available or the restored table is ACTIVE")
+	}
+	backupRestoreCmd.Flags().StringVar(&restoreTable, "target-table", "", "Name of the new table to restore the backup to")
+	backupPruneCmd.Flags().StringVar(&pruneAge, "older-than", "", "Delete the backups older than this age, e.g. 30d, 2w or 12h")
+	backupPruneCmd.Flags().IntVar(&pruneOptions.Keep, "keep", 0, "Keep only the newest N backups of each table")
+	backupCmd.AddCommand(backupCreateCmd, backupListCmd, backupDeleteCmd, backupRestoreCmd, backupPruneCmd)
+	ttlCmd.PersistentFlags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	ttlCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Only show the Time to Live changes")
+	ttlEnableCmd.Flags().StringVar(&ttlAttribute, "attribute", "", "Name of the attribute holding the expiry time in epoch seconds")
+	ttlCmd.AddCommand(ttlShowCmd, ttlEnableCmd, ttlDisableCmd)
+	replicaShowCmd.Flags().StringVar(&targetTables, "table", "", "Comma separated names of the tables, instead of --search/--tag")
+	for _, cmd := range []*cobra.Command{replicaAddCmd, replicaUpdateCmd} {
+		cmd.Flags().Int64Var(&replicaReadCapacity, "read-capacity", 0, "Provisioned read capacity of the replica, 0 uses the one of the table")
+	}
+	replicaAddCmd.Flags().BoolVar(&waitDone, "wait", false, "Wait until the replica is created and ACTIVE")
+	for _, cmd := range []*cobra.Command{replicaUpdateCmd, replicaRemoveCmd} {
+		cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only show the replica change")
+	}
+	replicaCmd.AddCommand(replicaShowCmd, replicaAddCmd, replicaUpdateCmd, replicaRemoveCmd)
+	rootCmd.AddCommand(planCmd, applyCmd, driftCmd, historyCmd, rollbackCmd, tagCmd, describeCmd, createCmd, schemaCmd, deleteCmd, indexCmd, listCmd, scalingCmd, scheduleCmd, recommendCmd, costCmd, staleCmd, pitrCmd, backupCmd, ttlCmd, replicaCmd)
+
 	cobra.EnableCommandSorting = false
 	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
 		return errors.New(fmt.Sprintf("Failed to parse command line args:%v", err))
@@ -139,22 +1455,177 @@ func initCommand() error {
 	return nil
 }
 
+// splitList splits a comma separated list such as table names or columns, dropping empty entries.
+func splitList(tableNames string) []string {
+	var names []string
+	for _, name := range strings.Split(tableNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // run configures and executes the program's workflow based on the specified action.
 //
 // It takes a DynamoDB manager, 'dbmgr', and an action string as parameters.
-// The action string determines the specific workflow to be executed, either 'Search' or 'Update'.
+// The action string determines the specific workflow to be executed: 'Search', 'Update', 'Plan', 'Apply',
+// 'DriftSnapshot', 'DriftCheck', 'History', 'Rollback', 'Tag', 'TagAudit', 'Describe', 'Create', 'SchemaExport', 'SchemaDiff', 'Delete', 'IndexList', 'IndexAdd', 'IndexDelete', 'List',
+// 'ScalingShow', 'ScalingSet', 'ScalingRemove', 'ScheduleRun', 'ScheduleShow', 'Recommend', 'Cost', 'Stale', 'PitrShow',
+// 'PitrEnable', 'PitrDisable', 'PitrReport', 'BackupCreate', 'BackupList', 'BackupDelete', 'BackupRestore', 'BackupPrune',
+// 'TtlShow', 'TtlEnable', 'TtlDisable', 'ReplicaShow', 'ReplicaAdd', 'ReplicaUpdate' or 'ReplicaRemove'.
 //
-// If the action is 'Search', it calls ExecuteSearchTask with the search term and tag retrieved from command-line flags.
-// If the action is 'Update', it calls ExecuteUpdateTask with the update table name, read and write capacity units,
-// on-demand and provisioned flags retrieved from command-line flags.
+// If the action is 'Search', it calls ExecuteSearchTask with the search term and tag retrieved from command-line flags,
+// and ExecuteFilterTask with the search results if filters are given.
+// If the action is 'Update', it calls ExecuteUpdateTask with the update table names and the update options
+// (read and write capacity units, on-demand and provisioned flags, capacity clamps, table class, deletion protection,
+// stream, backup first and reserved decreases)
+// retrieved from command-line flags.
+// If the action is 'Plan' or 'Apply', it calls ExecutePlanTask or ExecuteApplyTask with the spec file.
+// If the action is 'DriftSnapshot' or 'DriftCheck', it calls ExecuteDriftSnapshotTask with the snapshot file and
+// search conditions, or ExecuteDriftCheckTask with the snapshot file.
+// If the action is 'History' or 'Rollback', it calls ExecuteHistoryTask with the table filter and limit, or
+// ExecuteRollbackTask with the change id.
+// If the action is 'Tag', it calls ExecuteTagTask with the tag operation on the selected tables.
+// If the action is 'TagAudit', it calls ExecuteTagAuditTask with the policy file on the selected tables.
+// If the action is 'Describe', it calls ExecuteDescribeTask with the table name and output format.
+// If the action is 'Create', it calls ExecuteCreateTask with the spec file, table name and wait flag.
+// If the action is 'SchemaExport' or 'SchemaDiff', it calls ExecuteSchemaExportTask with the table name, output format
+// and file, or ExecuteSchemaDiffTask with the table name, diff target and tags flag.
+// If the action is 'Delete', it calls ExecuteDeleteTask with the delete options on the selected tables.
+// If the action is 'IndexList', 'IndexAdd' or 'IndexDelete', it calls ExecuteIndexListTask with the table name,
+// ExecuteIndexAddTask with the table name, spec file and wait flag, or ExecuteIndexDeleteTask with the table and index names.
+// If the action is 'List', it calls ExecuteListTask with the search conditions and the list options.
+// If the action is 'ScalingShow', 'ScalingSet' or 'ScalingRemove', it calls ExecuteScalingShowTask,
+// ExecuteScalingSetTask with the scaling change or ExecuteScalingRemoveTask with the index and dimensions
+// on the selected tables.
+// If the action is 'ScheduleRun' or 'ScheduleShow', it calls ExecuteScheduleRunTask with the schedule file,
+// state file and once flag, or ExecuteScheduleShowTask with the schedule and state file.
+// If the action is 'Recommend', it calls ExecuteRecommendTask with the recommend options on the selected tables.
+// If the action is 'Cost', it calls ExecuteCostTask with the proposed change on the selected tables.
+// If the action is 'Stale', it calls ExecuteStaleTask with the stale options on the selected tables.
+// If the action is 'PitrShow', 'PitrEnable', 'PitrDisable' or 'PitrReport', it calls ExecutePitrShowTask,
+// ExecutePitrUpdateTask with the requested state or ExecutePitrReportTask on the selected tables.
+// If the action is 'BackupCreate', 'BackupList' or 'BackupPrune', it calls ExecuteBackupCreateTask with the label,
+// ExecuteBackupListTask or ExecuteBackupPruneTask with the retention on the selected tables.
+// If the action is 'BackupDelete' or 'BackupRestore', it calls ExecuteBackupDeleteTask with the backup ARNs, or
+// ExecuteBackupRestoreTask with the backup ARN and target table.
+// If the action is 'TtlShow', 'TtlEnable' or 'TtlDisable', it calls ExecuteTtlShowTask, or ExecuteTtlUpdateTask
+// with the attribute and requested state on the selected tables.
+// If the action is 'ReplicaShow', it calls ExecuteReplicaShowTask on the selected tables.
+// If the action is 'ReplicaAdd', 'ReplicaUpdate' or 'ReplicaRemove', it calls ExecuteReplicaAddTask with the table name,
+// region, table class, read capacity and wait flag, ExecuteReplicaUpdateTask with the table name, region, table class,
+// read capacity and dry-run flag, or ExecuteReplicaRemoveTask with the table name and region.
 //
 // Returns an error if the action is unrecognized or if there's an error during execution.
 func run(dbmgr *client.DynamoDBManager, action string) error {
 	switch action {
 	case Search:
-		ExecuteSearchTask(dbmgr, viper.GetString("search"), viper.GetString("tag"))
+		matchingTables := ExecuteSearchTask(dbmgr, viper.GetString("search"), viper.GetString("tag"))
+		if len(searchFilters) > 0 {
+			_, err := ExecuteFilterTask(dbmgr, matchingTables, searchFilters)
+			return err
+		}
 	case Update:
-		ExecuteUpdateTask(dbmgr, viper.GetString("update"), viper.GetString("rcu"), viper.GetString("wcu"), viper.GetBool("ondemand"), viper.GetBool("provisioned"))
+		return ExecuteUpdateTask(dbmgr, splitList(viper.GetString("update")), update.UpdateOptions{
+			Rcu:                 viper.GetString("rcu"),
+			Wcu:                 viper.GetString("wcu"),
+			SwitchToOnDemand:    viper.GetBool("ondemand"),
+			SwitchToProvisioned: viper.GetBool("provisioned"),
+			ReserveDecreases:    viper.GetInt("reserve-decreases"),
+			MinCapacity:         viper.GetInt64("min-capacity"),
+			MaxCapacity:         viper.GetInt64("max-capacity"),
+			TableClass:          tableClass,
+			DeletionProtection:  deletionProtectionChange(),
+			BackupFirst:         viper.GetBool("backup-first"),
+			StreamViewType:      streamViewType,
+			EventSourceMappings: viper.GetString("event-source-mappings"),
+		})
+	case Plan:
+		_, err := ExecutePlanTask(dbmgr, specFile)
+		return err
+	case Apply:
+		return ExecuteApplyTask(dbmgr, specFile, viper.GetInt("reserve-decreases"))
+	case DriftSnapshot:
+		return ExecuteDriftSnapshotTask(dbmgr, snapshotFile, viper.GetString("search"), viper.GetString("tag"))
+	case DriftCheck:
+		return ExecuteDriftCheckTask(dbmgr, snapshotFile)
+	case History:
+		return ExecuteHistoryTask(dbmgr, historyTable, historyLimit)
+	case Rollback:
+		return ExecuteRollbackTask(dbmgr, changeID, viper.GetInt("reserve-decreases"))
+	case Tag:
+		return ExecuteTagTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), tagOperation, dryRun)
+	case TagAudit:
+		return ExecuteTagAuditTask(dbmgr, policyFile, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), fixTags, dryRun)
+	case Describe:
+		return ExecuteDescribeTask(dbmgr, describeTable, outputFormat)
+	case Create:
+		return ExecuteCreateTask(dbmgr, specFile, createTable, waitDone)
+	case SchemaExport:
+		return ExecuteSchemaExportTask(dbmgr, describeTable, outputFormat, exportFile)
+	case SchemaDiff:
+		return ExecuteSchemaDiffTask(dbmgr, describeTable, diffTarget, diffTags)
+	case Delete:
+		return ExecuteDeleteTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), deleteOptions)
+	case IndexList:
+		return ExecuteIndexListTask(dbmgr, describeTable)
+	case IndexAdd:
+		return ExecuteIndexAddTask(dbmgr, describeTable, specFile, waitDone)
+	case IndexDelete:
+		return ExecuteIndexDeleteTask(dbmgr, describeTable, indexName, dryRun)
+	case List:
+		return ExecuteListTask(dbmgr, viper.GetString("search"), viper.GetString("tag"), search.ListOptions{
+			Columns: splitList(listColumns),
+			Filters: searchFilters,
+			SortBy:  sortBy,
+			Reverse: reverseSort,
+			Format:  outputFormat,
+		})
+	case ScalingShow:
+		return ExecuteScalingShowTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"))
+	case ScalingSet:
+		return ExecuteScalingSetTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), scalingChange, dryRun)
+	case ScalingRemove:
+		return ExecuteScalingRemoveTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), scalingChange.IndexName, scalingChange.Dimensions, dryRun)
+	case ScheduleRun:
+		return ExecuteScheduleRunTask(dbmgr, scheduleFile, stateFile, runOnce)
+	case ScheduleShow:
+		return ExecuteScheduleShowTask(dbmgr, scheduleFile, stateFile)
+	case Recommend:
+		return ExecuteRecommendTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), recommendOptions)
+	case Cost:
+		return ExecuteCostTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), costOptions)
+	case Stale:
+		return ExecuteStaleTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), staleOptions)
+	case PitrShow:
+		return ExecutePitrShowTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"))
+	case PitrEnable, PitrDisable:
+		return ExecutePitrUpdateTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), action == PitrEnable, dryRun)
+	case PitrReport:
+		return ExecutePitrReportTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"))
+	case BackupCreate:
+		return ExecuteBackupCreateTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), backupLabel, waitDone)
+	case BackupList:
+		return ExecuteBackupListTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"))
+	case BackupDelete:
+		return ExecuteBackupDeleteTask(dbmgr, backupArns, dryRun)
+	case BackupRestore:
+		return ExecuteBackupRestoreTask(dbmgr, backupArns[0], restoreTable, waitDone)
+	case BackupPrune:
+		return ExecuteBackupPruneTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), pruneOptions)
+	case TtlShow:
+		return ExecuteTtlShowTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"))
+	case TtlEnable, TtlDisable:
+		return ExecuteTtlUpdateTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"), ttlAttribute, action == TtlEnable, dryRun)
+	case ReplicaShow:
+		return ExecuteReplicaShowTask(dbmgr, splitList(targetTables), viper.GetString("search"), viper.GetString("tag"))
+	case ReplicaAdd:
+		return ExecuteReplicaAddTask(dbmgr, describeTable, replicaRegion, replicaTableClass, replicaReadCapacity, waitDone)
+	case ReplicaUpdate:
+		return ExecuteReplicaUpdateTask(dbmgr, describeTable, replicaRegion, replicaTableClass, replicaReadCapacity, dryRun)
+	case ReplicaRemove:
+		return ExecuteReplicaRemoveTask(dbmgr, describeTable, replicaRegion, dryRun)
 	default:
 		return errors.New(fmt.Sprintf("unrecognized action provided:%s", action))
 	}
@@ -162,6 +1633,8 @@ func run(dbmgr *client.DynamoDBManager, action string) error {
 }
 
 // main invokes the program's workflow and handles errors by returning an exit status of 1.
+// Detected drift, tag policy violations, tables without point-in-time recovery and schema differences are reported
+// with an exit status of 2.
 func main() {
 	err_cmd := initCommand()
 	if err_cmd != nil {
@@ -169,6 +1642,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Nothing to run, e.g. only the help was requested
+	if action == "" {
+		os.Exit(0)
+	}
+
 	dbmgr, err := client.CreateNewDynamoDBManager(viper.GetString("profile"))
 	if err != nil {
 		fmt.Printf("Failed to create DynamoDB client due to: %v", err)
@@ -181,18 +1659,28 @@ func main() {
 		os.Exit(1)
 	}
 
+	dbmgr.JournalFile = viper.GetString("journal")
+	dbmgr.PriceFile = viper.GetString("prices")
+	dbmgr.RequireProtection = viper.GetBool("require-protection")
+	dbmgr.AllowUnprotected = viper.GetBool("allow-unprotected")
+	search.UsageLookback = time.Duration(viper.GetInt("usage-days")) * 24 * time.Hour
+
 	dumpParams(dbmgr)
 
-	if viper.GetString("update") != "" {
-		err := run(dbmgr, "update")
-		if err != nil {
+	err = run(dbmgr, action)
+	if errors.Is(err, drift.ErrDriftDetected) || errors.Is(err, tags.ErrNonCompliant) || errors.Is(err, backup.ErrPitrDisabled) || errors.Is(err, schema.ErrSchemaDiffers) {
+		os.Exit(2)
+	}
+	if err != nil {
+		switch action {
+		case Update:
 			dbmgr.Logger.Errorf("Failed to update the dynamodb table:%s , due to: %v", viper.GetString("update"), err)
-		}
-	} else {
-		err := run(dbmgr, "search")
-		if err != nil {
+		case Search:
 			dbmgr.Logger.Errorf("Failed to search dynamodb table due to: %v", err)
+		default:
+			dbmgr.Logger.Errorf("Failed to %s the dynamodb tables due to: %v", action, err)
 		}
+		os.Exit(1)
 	}
-	os.Exit(1)
+	os.Exit(0)
 }
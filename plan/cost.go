@@ -0,0 +1,124 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// CostLookback is the window of consumed capacity the on-demand cost of a plan is estimated from.
+var CostLookback = 14 * 24 * time.Hour
+
+var (
+	DescribeTableClient    = client.DescribeTable
+	LoadPriceTableClient   = client.LoadPriceTable
+	NewMetricsSourceClient = client.NewCloudWatchMetrics
+)
+
+// changesCost reports whether the plan changes the billing mode, capacities or table class of the table.
+func (tp *TablePlan) changesCost() bool {
+	for _, change := range tp.Changes {
+		if strings.HasPrefix(change.Field, GsiField+":") {
+			return true
+		}
+	}
+	return tp.hasChange(BillingModeField, RcuField, WcuField, TableClassField)
+}
+
+// desiredState returns the billing mode, capacities and table class the table has once the plan is applied.
+// Like the update it is applied with, a switch to provisioned mode defaults capacities the spec leaves unset.
+func (tp *TablePlan) desiredState() *client.TableState {
+	desired := *tp.State
+	desired.Gsis = append([]client.GsiState(nil), tp.State.Gsis...)
+
+	for _, change := range tp.Changes {
+		switch {
+		case change.Field == BillingModeField:
+			desired.BillingMode = change.Desired
+		case change.Field == RcuField:
+			fmt.Sscanf(change.Desired, "%d", &desired.Rcu)
+		case change.Field == WcuField:
+			fmt.Sscanf(change.Desired, "%d", &desired.Wcu)
+		case change.Field == TableClassField:
+			desired.TableClass = change.Desired
+		case strings.HasPrefix(change.Field, GsiField+":"):
+			if gsi := desired.Gsi(strings.TrimPrefix(change.Field, GsiField+":")); gsi != nil {
+				fmt.Sscanf(change.Desired, capacityFormat, &gsi.Rcu, &gsi.Wcu)
+			}
+		}
+	}
+
+	if desired.BillingMode == OnDemand {
+		desired.Rcu, desired.Wcu = 0, 0
+		for i := range desired.Gsis {
+			desired.Gsis[i].Rcu, desired.Gsis[i].Wcu = 0, 0
+		}
+	} else if tp.State.BillingMode != Provisioned {
+		if desired.Rcu == 0 {
+			desired.Rcu = client.DefaultRcu
+		}
+		if desired.Wcu == 0 {
+			desired.Wcu = client.DefaultWcu
+		}
+		for i := range desired.Gsis {
+			if desired.Gsis[i].Rcu == 0 && desired.Gsis[i].Wcu == 0 {
+				desired.Gsis[i].Rcu, desired.Gsis[i].Wcu = client.DefaultRcu, client.DefaultWcu
+			}
+		}
+	}
+	return &desired
+}
+
+// printCost logs the monthly cost before and after the plan of every table whose billing mode, capacities or
+// table class change, together with the aggregate. On-demand costs are estimated from the consumed capacity
+// of the last CostLookback. A table that can't be estimated only produces a warning.
+func printCost(dbmgr *client.DynamoDBManager, plans []TablePlan) {
+	prices, err := LoadPriceTableClient(dbmgr)
+	if err != nil {
+		dbmgr.Logger.Warnf("Skipping the cost estimate, failed to load the prices due to: %v", err)
+		return
+	}
+
+	var source client.MetricsSource
+	var currentTotal, desiredTotal float64
+	estimated := 0
+	end := time.Now().Truncate(client.MetricsPeriod)
+	for i := range plans {
+		tp := &plans[i]
+		if !tp.changesCost() {
+			continue
+		}
+
+		table, err := DescribeTableClient(dbmgr, tp.TableName)
+		if err != nil {
+			dbmgr.Logger.Warnf("Skipping the cost estimate of table:%s due to: %v", tp.TableName, err)
+			continue
+		}
+
+		desired := tp.desiredState()
+		var usageSource client.MetricsSource
+		if tp.State.BillingMode == OnDemand || desired.BillingMode == OnDemand {
+			if source == nil {
+				source = NewMetricsSourceClient(dbmgr)
+			}
+			usageSource = source
+		}
+		usage, err := client.GetTableUsage(usageSource, table, CostLookback, end)
+		if err != nil {
+			dbmgr.Logger.Warnf("Skipping the cost estimate of table:%s due to: %v", tp.TableName, err)
+			continue
+		}
+
+		current, next := prices.MonthlyCost(tp.State, usage).Total(), prices.MonthlyCost(desired, usage).Total()
+		dbmgr.Logger.Infof("Table:%s - monthly cost: $%.2f -> $%.2f (%+.2f)", tp.TableName, current, next, next-current)
+		estimated++
+		currentTotal += current
+		desiredTotal += next
+	}
+
+	if estimated > 0 {
+		dbmgr.Logger.Infof("Monthly cost of %d changed tables: $%.2f -> $%.2f (%+.2f)", estimated, currentTotal, desiredTotal, desiredTotal-currentTotal)
+	}
+}
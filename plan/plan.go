@@ -0,0 +1,505 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/update"
+)
+
+// ApplyWaitTimeout bounds how long apply waits for a table to become ACTIVE between two updates.
+var ApplyWaitTimeout = 30 * time.Minute
+
+var (
+	GetTableListClient   = client.GetTableList
+	GetTableArnClient    = client.GetTableArn
+	GetTableTagsClient   = client.GetTableTags
+	GetTableStateClient  = client.GetTableState
+	WaitForActiveClient  = client.WaitForTableActive
+	ExecuteUpdateTask    = update.ExecuteUpdate
+	UpdateOnDemandClient = client.UpdateOnDemandThroughput
+	UpdateIndexTask      = update.UpdateIndexCapacity
+	CheckClassTask       = update.CheckTableClassChange
+	UpdateProtectClient  = client.UpdateDeletionProtection
+	UpdatePitrClient     = client.UpdateContinuousBackups
+	UpdateTtlClient      = client.UpdateTimeToLive
+	TagTableClient       = client.TagTable
+	CheckPolicyClient    = client.CheckDestructiveOperation
+	CheckUnprotectClient = client.CheckDisableProtection
+)
+
+// Fields a plan can change, used as Change.Field
+const (
+	BillingModeField = "billingMode"
+	RcuField         = "rcu"
+	WcuField         = "wcu"
+	MaxReadField     = "maxReadRequestUnits"
+	MaxWriteField    = "maxWriteRequestUnits"
+	GsiField         = "gsi"
+	TableClassField  = "tableClass"
+	ProtectionField  = "deletionProtection"
+	PitrField        = "pointInTimeRecovery"
+	TimeToLiveField  = "timeToLive"
+	TagField         = "tag"
+)
+
+const (
+	missingValue      = "-"
+	capacityFormat    = "%d/%d"
+	defaultSuffix     = " (default)"
+	timeToLiveDisable = "disabled"
+)
+
+// Change is a single difference between the desired and the live state of a table.
+type Change struct {
+	Field   string
+	Current string
+	Desired string
+}
+
+// TablePlan holds the changes needed to converge one table to a table spec.
+type TablePlan struct {
+	TableName string
+	Spec      TableSpec
+	State     *client.TableState
+	Changes   []Change
+}
+
+// hasChange reports whether the plan contains a change of any of the given fields.
+func (tp *TablePlan) hasChange(fields ...string) bool {
+	for _, change := range tp.Changes {
+		for _, field := range fields {
+			if change.Field == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveTables finds the tables selected by a table spec, using tagCache to look up the tags of every table once.
+// It returns the selected table names and an error.
+func resolveTables(dbmgr *client.DynamoDBManager, ts TableSpec, tagCache map[string]map[string]string) ([]string, error) {
+	if ts.Table != "" {
+		return []string{ts.Table}, nil
+	}
+
+	if len(tagCache) == 0 {
+		tableList, err := GetTableListClient(dbmgr)
+		if err != nil {
+			return nil, err
+		}
+		for _, tableName := range tableList {
+			tableArn, err := GetTableArnClient(dbmgr, tableName)
+			if err != nil {
+				dbmgr.Logger.Warnf("Error getting table ARN: %v", err)
+				continue
+			}
+			tags, err := GetTableTagsClient(dbmgr, tableArn)
+			if err != nil {
+				dbmgr.Logger.Warnf("Get tags for arn:%s, failed due to:%v", tableArn, err)
+				continue
+			}
+			tagCache[tableName] = client.TagsToMap(tags)
+		}
+	}
+
+	var tableNames []string
+	for tableName, tags := range tagCache {
+		if ts.Matches(tableName, tags) {
+			tableNames = append(tableNames, tableName)
+		}
+	}
+	sort.Strings(tableNames)
+	return tableNames, nil
+}
+
+// Diff compares a table spec with the live state of a table.
+// It returns the changes needed to converge the table, skipping settings that don't apply to its billing mode.
+func Diff(dbmgr *client.DynamoDBManager, ts TableSpec, state *client.TableState) []Change {
+	var changes []Change
+
+	billingMode := state.BillingMode
+	if ts.BillingMode != "" && ts.BillingMode != state.BillingMode {
+		changes = append(changes, Change{BillingModeField, state.BillingMode, ts.BillingMode})
+		billingMode = ts.BillingMode
+	}
+
+	if billingMode == Provisioned && state.BillingMode != Provisioned {
+		// The switch sets the capacity of the table and of every index at once, defaulting what the spec leaves unset.
+		changes = append(changes, Change{RcuField, missingValue, desiredCapacity(ts.Rcu, client.DefaultRcu)})
+		changes = append(changes, Change{WcuField, missingValue, desiredCapacity(ts.Wcu, client.DefaultWcu)})
+		for _, gsi := range ts.Gsis {
+			if state.Gsi(gsi.IndexName) == nil {
+				dbmgr.Logger.Warnf("Table:%s has no index:%s, skipping its capacity", state.TableName, gsi.IndexName)
+			}
+		}
+		for _, current := range state.Gsis {
+			desired := fmt.Sprintf(capacityFormat, client.DefaultRcu, client.DefaultWcu) + defaultSuffix
+			if gsi := ts.Gsi(current.IndexName); gsi != nil && gsi.Rcu != nil {
+				desired = fmt.Sprintf(capacityFormat, *gsi.Rcu, *gsi.Wcu)
+			}
+			changes = append(changes, Change{GsiField + ":" + current.IndexName, missingValue, desired})
+		}
+	} else if billingMode == Provisioned {
+		if ts.Rcu != nil && *ts.Rcu != state.Rcu {
+			changes = append(changes, Change{RcuField, formatCapacity(state.Rcu), fmt.Sprintf("%d", *ts.Rcu)})
+		}
+		if ts.Wcu != nil && *ts.Wcu != state.Wcu {
+			changes = append(changes, Change{WcuField, formatCapacity(state.Wcu), fmt.Sprintf("%d", *ts.Wcu)})
+		}
+		for _, gsi := range ts.Gsis {
+			current := state.Gsi(gsi.IndexName)
+			if current == nil {
+				dbmgr.Logger.Warnf("Table:%s has no index:%s, skipping its capacity", state.TableName, gsi.IndexName)
+				continue
+			}
+			if gsi.Rcu != nil && (*gsi.Rcu != current.Rcu || *gsi.Wcu != current.Wcu) {
+				changes = append(changes, Change{GsiField + ":" + gsi.IndexName, fmt.Sprintf(capacityFormat, current.Rcu, current.Wcu), fmt.Sprintf(capacityFormat, *gsi.Rcu, *gsi.Wcu)})
+			}
+		}
+	} else if ts.Rcu != nil || ts.Wcu != nil || len(ts.Gsis) > 0 {
+		dbmgr.Logger.Warnf("Table:%s is in billing mode:%s, skipping rcu, wcu and gsi capacity", state.TableName, billingMode)
+	}
+
+	if billingMode == OnDemand {
+		if ts.MaxReadRequestUnits != nil && unlimitedIfUnset(*ts.MaxReadRequestUnits) != unlimitedIfUnset(state.MaxReadRequestUnits) {
+			changes = append(changes, Change{MaxReadField, formatCapacity(state.MaxReadRequestUnits), fmt.Sprintf("%d", *ts.MaxReadRequestUnits)})
+		}
+		if ts.MaxWriteRequestUnits != nil && unlimitedIfUnset(*ts.MaxWriteRequestUnits) != unlimitedIfUnset(state.MaxWriteRequestUnits) {
+			changes = append(changes, Change{MaxWriteField, formatCapacity(state.MaxWriteRequestUnits), fmt.Sprintf("%d", *ts.MaxWriteRequestUnits)})
+		}
+	} else if ts.MaxReadRequestUnits != nil || ts.MaxWriteRequestUnits != nil {
+		dbmgr.Logger.Warnf("Table:%s is in billing mode:%s, skipping on-demand limits", state.TableName, billingMode)
+	}
+
+	if ts.TableClass != "" && ts.TableClass != state.TableClass {
+		changes = append(changes, Change{TableClassField, state.TableClass, ts.TableClass})
+	}
+
+	if ts.DeletionProtection != nil && *ts.DeletionProtection != state.DeletionProtection {
+		changes = append(changes, Change{ProtectionField, fmt.Sprintf("%t", state.DeletionProtection), fmt.Sprintf("%t", *ts.DeletionProtection)})
+	}
+
+	if ts.PointInTimeRecovery != nil && *ts.PointInTimeRecovery != state.PointInTimeRecovery {
+		changes = append(changes, Change{PitrField, fmt.Sprintf("%t", state.PointInTimeRecovery), fmt.Sprintf("%t", *ts.PointInTimeRecovery)})
+	}
+
+	if ts.TimeToLive != nil {
+		current := formatTimeToLive(state.TimeToLiveAttribute)
+		desired := timeToLiveDisable
+		if ts.TimeToLive.Enabled {
+			desired = ts.TimeToLive.AttributeName
+		}
+		switch {
+		case current == desired:
+		case state.TimeToLiveAttribute != "" && ts.TimeToLive.Enabled:
+			// DynamoDB refuses another TTL change while the disable is processed, which can take up to an hour.
+			dbmgr.Logger.Warnf("Table:%s has TTL enabled on attribute:%s, disable it before enabling it on attribute:%s, skipping the TTL change",
+				state.TableName, state.TimeToLiveAttribute, ts.TimeToLive.AttributeName)
+		default:
+			changes = append(changes, Change{TimeToLiveField, current, desired})
+		}
+	}
+
+	tagKeys := make([]string, 0, len(ts.Tags))
+	for key := range ts.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		current, exists := state.Tags[key]
+		if !exists {
+			current = missingValue
+		}
+		if !exists || current != ts.Tags[key] {
+			changes = append(changes, Change{TagField + ":" + key, current, ts.Tags[key]})
+		}
+	}
+
+	return changes
+}
+
+// desiredCapacity renders the capacity a switch to provisioned mode sets, marking the default for unset values.
+func desiredCapacity(value *int64, defaultValue int64) string {
+	if value == nil {
+		return fmt.Sprintf("%d", defaultValue) + defaultSuffix
+	}
+	return fmt.Sprintf("%d", *value)
+}
+
+// formatCapacity renders a capacity value, showing unset values as "-".
+func formatCapacity(value int64) string {
+	if value == 0 {
+		return missingValue
+	}
+	return fmt.Sprintf("%d", value)
+}
+
+// formatTimeToLive renders the Time to Live attribute of a table, or "disabled".
+func formatTimeToLive(attributeName string) string {
+	if attributeName == "" {
+		return timeToLiveDisable
+	}
+	return attributeName
+}
+
+// BuildPlan resolves the tables selected by a spec and diffs each of them against its live state.
+// It returns the table plans in spec order and an error if the tables can't be resolved.
+func BuildPlan(dbmgr *client.DynamoDBManager, spec *Spec) ([]TablePlan, error) {
+	var plans []TablePlan
+	tagCache := make(map[string]map[string]string)
+	states := make(map[string]*client.TableState)
+
+	for _, ts := range spec.Tables {
+		tableNames, err := resolveTables(dbmgr, ts, tagCache)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to resolve the tables of the spec due to: %v", err)
+			return nil, err
+		}
+		if len(tableNames) == 0 {
+			dbmgr.Logger.Warnf("No table matches the selector:%v", ts.Selector)
+		}
+
+		for _, tableName := range tableNames {
+			state, exists := states[tableName]
+			if !exists {
+				state, err = GetTableStateClient(dbmgr, tableName)
+				if err != nil {
+					dbmgr.Logger.Errorf("Failed to get the state of table:%s due to: %v", tableName, err)
+					return nil, err
+				}
+				states[tableName] = state
+			} else {
+				dbmgr.Logger.Warnf("Table:%s is selected by more than one spec entry, later entries win", tableName)
+			}
+			plans = append(plans, TablePlan{TableName: tableName, Spec: ts, State: state, Changes: Diff(dbmgr, ts, state)})
+		}
+	}
+	return plans, nil
+}
+
+// printPlan logs the changes of every table plan together with a summary.
+// It returns the number of changes.
+func printPlan(dbmgr *client.DynamoDBManager, plans []TablePlan) int {
+	changeCount, tableCount := 0, 0
+	for _, tp := range plans {
+		if len(tp.Changes) == 0 {
+			dbmgr.Logger.Infof("Table:%s is up to date", tp.TableName)
+			continue
+		}
+		tableCount++
+		for _, change := range tp.Changes {
+			changeCount++
+			dbmgr.Logger.Infof("Table:%s - %s: %s -> %s", tp.TableName, change.Field, change.Current, change.Desired)
+		}
+	}
+	dbmgr.Logger.Infof("Plan: %d changes on %d tables", changeCount, tableCount)
+	return changeCount
+}
+
+// applyTablePlan converges a single table, waiting for it to become ACTIVE after every UpdateTable call
+// since DynamoDB only accepts one table update at a time.
+// It returns an error on the first failed change.
+func applyTablePlan(dbmgr *client.DynamoDBManager, tp TablePlan, reserveDecreases int) error {
+	ts := tp.Spec
+	wait := func() error { return WaitForActiveClient(dbmgr, tp.TableName, ApplyWaitTimeout) }
+	switching := tp.hasChange(BillingModeField) && ts.BillingMode == Provisioned
+
+	if tp.hasChange(BillingModeField, RcuField, WcuField) {
+		opts := update.UpdateOptions{
+			SwitchToOnDemand:    ts.BillingMode == OnDemand,
+			SwitchToProvisioned: ts.BillingMode == Provisioned,
+			ReserveDecreases:    reserveDecreases,
+		}
+		if ts.Rcu != nil {
+			opts.Rcu = fmt.Sprintf("%d", *ts.Rcu)
+		}
+		if ts.Wcu != nil {
+			opts.Wcu = fmt.Sprintf("%d", *ts.Wcu)
+		}
+		if switching {
+			// The index capacities go into the same update, DynamoDB refuses the switch without them.
+			for _, gsi := range ts.Gsis {
+				if gsi.Rcu != nil && tp.State.Gsi(gsi.IndexName) != nil {
+					opts.Gsis = append(opts.Gsis, client.GsiState{IndexName: gsi.IndexName, Rcu: *gsi.Rcu, Wcu: *gsi.Wcu})
+				}
+			}
+		}
+		if err := ExecuteUpdateTask(dbmgr, tp.TableName, opts); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	if tp.hasChange(MaxReadField, MaxWriteField) {
+		maxRead, maxWrite := tp.State.MaxReadRequestUnits, tp.State.MaxWriteRequestUnits
+		if ts.MaxReadRequestUnits != nil {
+			maxRead = *ts.MaxReadRequestUnits
+		}
+		if ts.MaxWriteRequestUnits != nil {
+			maxWrite = *ts.MaxWriteRequestUnits
+		}
+		if err := UpdateOnDemandClient(dbmgr, tp.TableName, unlimitedIfUnset(maxRead), unlimitedIfUnset(maxWrite)); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	for _, gsi := range ts.Gsis {
+		if switching || !tp.hasChange(GsiField+":"+gsi.IndexName) {
+			continue
+		}
+		if err := UpdateIndexTask(dbmgr, tp.TableName, gsi.IndexName, *gsi.Rcu, *gsi.Wcu, reserveDecreases); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	if tp.hasChange(TableClassField) {
+		// Goes through the update to respect the class-change restrictions.
+		if err := ExecuteUpdateTask(dbmgr, tp.TableName, update.UpdateOptions{TableClass: ts.TableClass}); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	if tp.hasChange(ProtectionField) {
+		if !*ts.DeletionProtection {
+			if err := CheckUnprotectClient(dbmgr, tp.TableName); err != nil {
+				return err
+			}
+		}
+		if err := UpdateProtectClient(dbmgr, tp.TableName, *ts.DeletionProtection); err != nil {
+			return err
+		}
+	}
+
+	if tp.hasChange(PitrField) {
+		if !*ts.PointInTimeRecovery {
+			if err := CheckPolicyClient(dbmgr, tp.TableName, "disable point-in-time recovery of"); err != nil {
+				return err
+			}
+		}
+		if err := UpdatePitrClient(dbmgr, tp.TableName, *ts.PointInTimeRecovery); err != nil {
+			return err
+		}
+	}
+
+	if tp.hasChange(TimeToLiveField) {
+		// The plan never switches attributes, so this either enables TTL or disables the currently enabled attribute.
+		if ts.TimeToLive.Enabled {
+			if err := CheckPolicyClient(dbmgr, tp.TableName, "enable TTL, deleting expired items, on"); err != nil {
+				return err
+			}
+			if err := UpdateTtlClient(dbmgr, tp.TableName, ts.TimeToLive.AttributeName, true); err != nil {
+				return err
+			}
+		} else if err := UpdateTtlClient(dbmgr, tp.TableName, tp.State.TimeToLiveAttribute, false); err != nil {
+			return err
+		}
+	}
+
+	var tags []types.Tag
+	for _, change := range tp.Changes {
+		if key, isTag := strings.CutPrefix(change.Field, TagField+":"); isTag {
+			tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(change.Desired)})
+		}
+	}
+	if len(tags) > 0 {
+		tableArn, err := GetTableArnClient(dbmgr, tp.TableName)
+		if err != nil {
+			return err
+		}
+		if err := TagTableClient(dbmgr, tableArn, tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTableClassChanges warns about the table class changes of a plan that DynamoDB would refuse for now.
+func checkTableClassChanges(dbmgr *client.DynamoDBManager, plans []TablePlan) {
+	for _, tp := range plans {
+		if !tp.hasChange(TableClassField) {
+			continue
+		}
+		if _, err := CheckClassTask(dbmgr, tp.TableName, tp.Spec.TableClass); err != nil {
+			dbmgr.Logger.Warnf("Table:%s - %s change can't be applied: %v", tp.TableName, TableClassField, err)
+		}
+	}
+}
+
+// unlimitedIfUnset maps an unset on-demand limit to -1, which DynamoDB uses for "no limit".
+func unlimitedIfUnset(value int64) int64 {
+	if value == 0 {
+		return -1
+	}
+	return value
+}
+
+// ExecutePlan loads a desired-state file and logs the changes needed to converge the live tables to it,
+// with the monthly cost before and after the changes of billing mode, capacities and table class.
+// It takes a DynamoDBManager and the spec file path as input and returns the table plans and an error.
+func ExecutePlan(dbmgr *client.DynamoDBManager, specFile string) ([]TablePlan, error) {
+	spec, err := LoadSpec(specFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load spec due to: %v", err)
+		return nil, err
+	}
+
+	plans, err := BuildPlan(dbmgr, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if printPlan(dbmgr, plans) > 0 {
+		checkTableClassChanges(dbmgr, plans)
+		printCost(dbmgr, plans)
+	}
+	return plans, nil
+}
+
+// ExecuteApply loads a desired-state file and converges every selected table to it, continuing with the
+// next table after a failure.
+// It takes a DynamoDBManager, the spec file path and the number of daily capacity decreases to keep in reserve as input.
+// It returns an error listing the tables that couldn't be converged.
+func ExecuteApply(dbmgr *client.DynamoDBManager, specFile string, reserveDecreases int) error {
+	plans, err := ExecutePlan(dbmgr, specFile)
+	if err != nil {
+		return err
+	}
+
+	var failedTables []string
+	for _, tp := range plans {
+		if len(tp.Changes) == 0 {
+			continue
+		}
+		dbmgr.Logger.Infof("Applying %d changes to table:%s ...", len(tp.Changes), tp.TableName)
+		if err := applyTablePlan(dbmgr, tp, reserveDecreases); err != nil {
+			dbmgr.Logger.Errorf("Failed to apply the plan of table:%s due to: %v", tp.TableName, err)
+			failedTables = append(failedTables, tp.TableName)
+		}
+	}
+
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to apply the plan to tables: %s", strings.Join(failedTables, ",")))
+	}
+	dbmgr.Logger.Info("Apply complete!")
+	return nil
+}
@@ -0,0 +1,167 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// Billing modes and table classes accepted in a spec file
+const (
+	Provisioned = "PROVISIONED"
+	OnDemand    = "PAY_PER_REQUEST"
+
+	StandardClass   = "STANDARD"
+	StandardIaClass = "STANDARD_INFREQUENT_ACCESS"
+)
+
+// GsiSpec describes the desired provisioned capacity of a global secondary index.
+type GsiSpec struct {
+	IndexName string `json:"indexName" yaml:"indexName"`
+	Rcu       *int64 `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu       *int64 `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+}
+
+// TimeToLiveSpec describes the desired Time to Live setting of a table.
+type TimeToLiveSpec struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	AttributeName string `json:"attributeName,omitempty" yaml:"attributeName,omitempty"`
+}
+
+// TableSpec describes the desired state of the tables it selects, either a single table by name
+// or every table carrying all of the selector tags.
+// Unset fields are left untouched, and tags are merged into the existing tags of a table.
+type TableSpec struct {
+	Table                string            `json:"table,omitempty" yaml:"table,omitempty"`
+	Selector             map[string]string `json:"selector,omitempty" yaml:"selector,omitempty"`
+	BillingMode          string            `json:"billingMode,omitempty" yaml:"billingMode,omitempty"`
+	Rcu                  *int64            `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu                  *int64            `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+	MaxReadRequestUnits  *int64            `json:"maxReadRequestUnits,omitempty" yaml:"maxReadRequestUnits,omitempty"`
+	MaxWriteRequestUnits *int64            `json:"maxWriteRequestUnits,omitempty" yaml:"maxWriteRequestUnits,omitempty"`
+	Gsis                 []GsiSpec         `json:"gsis,omitempty" yaml:"gsis,omitempty"`
+	TableClass           string            `json:"tableClass,omitempty" yaml:"tableClass,omitempty"`
+	DeletionProtection   *bool             `json:"deletionProtection,omitempty" yaml:"deletionProtection,omitempty"`
+	PointInTimeRecovery  *bool             `json:"pointInTimeRecovery,omitempty" yaml:"pointInTimeRecovery,omitempty"`
+	TimeToLive           *TimeToLiveSpec   `json:"timeToLive,omitempty" yaml:"timeToLive,omitempty"`
+	Tags                 map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Spec is the desired-state file, a list of table specs applied in order.
+type Spec struct {
+	Tables []TableSpec `json:"tables" yaml:"tables"`
+}
+
+// LoadSpec reads a desired-state file, parsed as JSON for a .json extension and as YAML otherwise.
+// It returns the validated Spec and an error.
+func LoadSpec(path string) (*Spec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &spec)
+	} else {
+		err = yaml.Unmarshal(content, &spec)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse spec file:%s - error:%v", path, err))
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid spec file:%s - error:%v", path, err))
+	}
+	return &spec, nil
+}
+
+// Validate checks that every table spec selects tables unambiguously and only holds supported values.
+// It returns an error describing the first invalid entry.
+func (s *Spec) Validate() error {
+	if len(s.Tables) == 0 {
+		return errors.New("no tables specified")
+	}
+
+	for i, ts := range s.Tables {
+		if (ts.Table == "") == (len(ts.Selector) == 0) {
+			return errors.New(fmt.Sprintf("tables[%d]: exactly one of table or selector must be set", i))
+		}
+
+		if ts.BillingMode != "" && ts.BillingMode != Provisioned && ts.BillingMode != OnDemand {
+			return errors.New(fmt.Sprintf("tables[%d]: unsupported billingMode:%s", i, ts.BillingMode))
+		}
+
+		if ts.BillingMode == OnDemand && (ts.Rcu != nil || ts.Wcu != nil) {
+			return errors.New(fmt.Sprintf("tables[%d]: rcu and wcu can't be used with billingMode:%s", i, OnDemand))
+		}
+
+		if ts.BillingMode == Provisioned && (ts.MaxReadRequestUnits != nil || ts.MaxWriteRequestUnits != nil) {
+			return errors.New(fmt.Sprintf("tables[%d]: on-demand limits can't be used with billingMode:%s", i, Provisioned))
+		}
+
+		for _, value := range []*int64{ts.Rcu, ts.Wcu} {
+			if value != nil && *value < 1 {
+				return errors.New(fmt.Sprintf("tables[%d]: rcu and wcu must be at least 1", i))
+			}
+		}
+
+		for _, value := range []*int64{ts.MaxReadRequestUnits, ts.MaxWriteRequestUnits} {
+			if value != nil && *value < 1 && *value != -1 {
+				return errors.New(fmt.Sprintf("tables[%d]: on-demand limits must be at least 1, or -1 for no limit", i))
+			}
+		}
+
+		for _, gsi := range ts.Gsis {
+			if gsi.IndexName == "" {
+				return errors.New(fmt.Sprintf("tables[%d]: gsis entries need an indexName", i))
+			}
+			if (gsi.Rcu == nil) != (gsi.Wcu == nil) || (gsi.Rcu != nil && (*gsi.Rcu < 1 || *gsi.Wcu < 1)) {
+				return errors.New(fmt.Sprintf("tables[%d]: gsi:%s needs both rcu and wcu of at least 1", i, gsi.IndexName))
+			}
+		}
+
+		if ts.TableClass != "" {
+			tableClass, err := client.ParseTableClass(ts.TableClass)
+			if err != nil {
+				return errors.New(fmt.Sprintf("tables[%d]: unsupported tableClass:%s", i, ts.TableClass))
+			}
+			s.Tables[i].TableClass = tableClass
+		}
+
+		if ts.TimeToLive != nil && ts.TimeToLive.Enabled && ts.TimeToLive.AttributeName == "" {
+			return errors.New(fmt.Sprintf("tables[%d]: timeToLive needs an attributeName when enabled", i))
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a table with the given name and tags is selected by the table spec.
+func (ts *TableSpec) Matches(tableName string, tags map[string]string) bool {
+	if ts.Table != "" {
+		return ts.Table == tableName
+	}
+	for key, value := range ts.Selector {
+		if tagValue, exists := tags[key]; !exists || tagValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Gsi returns the spec of the global secondary index with the given name, or nil if the table spec has none.
+func (ts *TableSpec) Gsi(indexName string) *GsiSpec {
+	for i := range ts.Gsis {
+		if ts.Gsis[i].IndexName == indexName {
+			return &ts.Gsis[i]
+		}
+	}
+	return nil
+}
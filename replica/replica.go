@@ -0,0 +1,183 @@
+package replica
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+// ReplicaWaitTimeout bounds how long replica add waits for a new replica to be created and ACTIVE.
+var ReplicaWaitTimeout = 6 * time.Hour
+
+var (
+	DescribeTableClient        = client.DescribeTable
+	AddReplicaClient           = client.AddReplica
+	UpdateReplicaClient        = client.UpdateReplica
+	RemoveReplicaClient        = client.RemoveReplica
+	WaitForReplicaActiveClient = client.WaitForReplicaActive
+	CheckPolicyClient          = client.CheckDestructiveOperation
+	ResolveTablesTask          = search.ResolveTables
+	ConfirmClient              = client.Confirm
+)
+
+// describeReplica renders the status and per-replica settings of a replica for the log.
+func describeReplica(replica *types.ReplicaDescription) string {
+	description := fmt.Sprintf("region:%s - status:%s", aws.ToString(replica.RegionName), replica.ReplicaStatus)
+	if replica.ReplicaStatusPercentProgress != nil {
+		description += fmt.Sprintf(" - progress:%s%%", aws.ToString(replica.ReplicaStatusPercentProgress))
+	}
+	tableClass := string(types.TableClassStandard)
+	if replica.ReplicaTableClassSummary != nil && replica.ReplicaTableClassSummary.TableClass != "" {
+		tableClass = string(replica.ReplicaTableClassSummary.TableClass)
+	}
+	description += " - class:" + tableClass
+	if replica.ProvisionedThroughputOverride != nil && replica.ProvisionedThroughputOverride.ReadCapacityUnits != nil {
+		description += fmt.Sprintf(" - readCapacity:%d", aws.ToInt64(replica.ProvisionedThroughputOverride.ReadCapacityUnits))
+	}
+	if replica.ReplicaStatusDescription != nil {
+		description += " - " + aws.ToString(replica.ReplicaStatusDescription)
+	}
+	return description
+}
+
+// ExecuteReplicaShow logs the replica regions of the given tables, or of the tables matched by the search conditions,
+// with the status and per-replica settings of each replica.
+// It takes a DynamoDBManager, table names, a fuzzy table name and a tag value as input.
+// It returns an error if the tables can't be selected or described.
+func ExecuteReplicaShow(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	for _, tableName := range targets {
+		table, err := DescribeTableClient(dbmgr, tableName)
+		if err != nil {
+			return err
+		}
+		if len(table.Replicas) == 0 {
+			dbmgr.Logger.Infof("Table:%s - not a global table", tableName)
+			continue
+		}
+		dbmgr.Logger.Infof("Table:%s - global table version:%s - replicas:%d", tableName, aws.ToString(table.GlobalTableVersion), len(table.Replicas))
+		for i := range table.Replicas {
+			dbmgr.Logger.Infof("Table:%s - replica %s", tableName, describeReplica(&table.Replicas[i]))
+		}
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	return nil
+}
+
+// ExecuteReplicaAdd adds a replica in another region to a table, optionally with its own table class and read
+// capacity. With wait it tracks the creation until the replica is ACTIVE.
+// It takes a DynamoDBManager, the table name, the region, the table class, the read capacity and a wait flag as input.
+// It returns an error if the table can't be replicated to the region or the replica can't be created.
+func ExecuteReplicaAdd(dbmgr *client.DynamoDBManager, tableName string, region string, tableClass string, readCapacity int64, wait bool) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+	if table.TableStatus != types.TableStatusActive {
+		return errors.New(fmt.Sprintf("Refused to add replica:%s to table:%s : its status is %s, not ACTIVE", region, tableName, table.TableStatus))
+	}
+	if region == dbmgr.AwsConfig.Region {
+		return errors.New(fmt.Sprintf("Refused to add replica:%s to table:%s : it is the region of the table", region, tableName))
+	}
+	if client.ReplicaOf(table, region) != nil {
+		return errors.New(fmt.Sprintf("table:%s already has a replica:%s", tableName, region))
+	}
+	if viewType := client.StreamViewTypeOf(table); viewType != "DISABLED" && viewType != string(types.StreamViewTypeNewAndOldImages) {
+		return errors.New(fmt.Sprintf("Refused to add replica:%s to table:%s : global tables require a NEW_AND_OLD_IMAGES stream, the table has %s", region, tableName, viewType))
+	}
+	if readCapacity > 0 && client.BillingModeOf(table) != string(types.BillingModeProvisioned) {
+		return errors.New(fmt.Sprintf("Refused to add replica:%s to table:%s : a read capacity only applies to provisioned tables", region, tableName))
+	}
+	if client.BillingModeOf(table) == string(types.BillingModeProvisioned) {
+		dbmgr.Logger.Warnf("Table:%s is provisioned - global tables need write auto scaling, or the same write capacity, on every replica", tableName)
+	}
+
+	if err := AddReplicaClient(dbmgr, tableName, region, tableClass, readCapacity); err != nil {
+		return err
+	}
+
+	if !wait {
+		dbmgr.Logger.Infof("Replica:%s is created in the background, follow it with: replica show --table %s", region, tableName)
+		return nil
+	}
+	if err := WaitForReplicaActiveClient(dbmgr, tableName, region, ReplicaWaitTimeout); err != nil {
+		return err
+	}
+	dbmgr.Logger.Infof("Replica:%s of table:%s is ACTIVE", region, tableName)
+	return nil
+}
+
+// ExecuteReplicaUpdate changes the table class or the read capacity override of a replica of a table.
+// With dry-run the change is only logged.
+// It takes a DynamoDBManager, the table name, the region, the table class, the read capacity and a dry-run flag as input.
+// It returns an error if the table has no replica in the region, nothing is changed or the replica can't be updated.
+func ExecuteReplicaUpdate(dbmgr *client.DynamoDBManager, tableName string, region string, tableClass string, readCapacity int64, dryRun bool) error {
+	if tableClass == "" && readCapacity <= 0 {
+		return errors.New("no replica settings provided, expected a table class or a read capacity")
+	}
+
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+	replica := client.ReplicaOf(table, region)
+	if replica == nil {
+		return errors.New(fmt.Sprintf("table:%s has no replica:%s", tableName, region))
+	}
+	if replica.ReplicaStatus != types.ReplicaStatusActive {
+		return errors.New(fmt.Sprintf("Refused to update replica:%s of table:%s : its status is %s, not ACTIVE", region, tableName, replica.ReplicaStatus))
+	}
+	if readCapacity > 0 && client.BillingModeOf(table) != string(types.BillingModeProvisioned) {
+		return errors.New(fmt.Sprintf("Refused to update replica:%s of table:%s : a read capacity only applies to provisioned tables", region, tableName))
+	}
+
+	if dryRun {
+		dbmgr.Logger.Infof("Table:%s - would update replica %s -> class:%s readCapacity:%d", tableName, describeReplica(replica), tableClass, readCapacity)
+		return nil
+	}
+	return UpdateReplicaClient(dbmgr, tableName, region, tableClass, readCapacity)
+}
+
+// ExecuteReplicaRemove removes the replica of a table in a region after typed confirmation of the region.
+// Replicas of tables the deletion protection policy doesn't allow it on are refused. With dry-run the replica is only logged.
+// It takes a DynamoDBManager, the table name, the region and a dry-run flag as input.
+// It returns an error if the table has no replica in the region, the removal is refused or the replica can't be removed.
+func ExecuteReplicaRemove(dbmgr *client.DynamoDBManager, tableName string, region string, dryRun bool) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+	replica := client.ReplicaOf(table, region)
+	if replica == nil {
+		return errors.New(fmt.Sprintf("table:%s has no replica:%s", tableName, region))
+	}
+
+	if err := CheckPolicyClient(dbmgr, tableName, fmt.Sprintf("remove replica:%s of", region)); err != nil {
+		dbmgr.Logger.Errorf("%v", err)
+		return err
+	}
+
+	if dryRun {
+		dbmgr.Logger.Infof("Table:%s - would remove replica %s", tableName, describeReplica(replica))
+		return nil
+	}
+
+	if !ConfirmClient(fmt.Sprintf("Type the region to confirm removing replica:%s of table:%s and deleting its items there: ", region, tableName), region) {
+		return errors.New(fmt.Sprintf("confirmation didn't match the region, replica:%s of table:%s not removed", region, tableName))
+	}
+	return RemoveReplicaClient(dbmgr, tableName, region)
+}
@@ -0,0 +1,366 @@
+package scaling
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	aastypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+// Capacity dimensions accepted on the command line
+const (
+	Read  = "read"
+	Write = "write"
+)
+
+// Target utilization range DynamoDB accepts for target tracking, in percent
+const (
+	MinTargetUtilization = 20
+	MaxTargetUtilization = 90
+)
+
+var (
+	DescribeTableClient            = client.DescribeTable
+	GetScalableTargetsClient       = client.GetScalableTargets
+	GetScalingPoliciesClient       = client.GetScalingPolicies
+	RegisterScalableTargetClient   = client.RegisterScalableTarget
+	PutTargetTrackingPolicyClient  = client.PutTargetTrackingPolicy
+	DeregisterScalableTargetClient = client.DeregisterScalableTarget
+	ResolveTablesTask              = search.ResolveTables
+	CheckPolicyClient              = client.CheckDestructiveOperation
+)
+
+// ScalingSettings describes the auto scaling of the read or write capacity of a table or index.
+// A dimension that isn't auto scaled has no MaxCapacity, and one without target tracking policy no TargetUtilization.
+type ScalingSettings struct {
+	TableName         string
+	IndexName         string
+	Dimension         string
+	Capacity          int64
+	MinCapacity       int32
+	MaxCapacity       int32
+	TargetUtilization float64
+	PolicyName        string
+}
+
+// ScalingChange describes the auto scaling to set up for the given dimensions of a table or one of its indexes.
+// Zero values keep the current setting of a dimension that is already auto scaled.
+type ScalingChange struct {
+	IndexName         string
+	Dimensions        []string
+	MinCapacity       int32
+	MaxCapacity       int32
+	TargetUtilization float64
+}
+
+// scalableDimension maps a table or index and a read or write dimension to its scalable dimension.
+func scalableDimension(indexName string, dimension string) aastypes.ScalableDimension {
+	switch {
+	case indexName == "" && dimension == Read:
+		return client.TableReadDimension
+	case indexName == "":
+		return client.TableWriteDimension
+	case dimension == Read:
+		return client.IndexReadDimension
+	default:
+		return client.IndexWriteDimension
+	}
+}
+
+// defaultPolicyName returns the policy name the AWS console uses for a table or index dimension.
+func defaultPolicyName(resourceId string, dimension string) string {
+	if dimension == Read {
+		return fmt.Sprintf("%s:%s", aastypes.MetricTypeDynamoDBReadCapacityUtilization, resourceId)
+	}
+	return fmt.Sprintf("%s:%s", aastypes.MetricTypeDynamoDBWriteCapacityUtilization, resourceId)
+}
+
+// provisionedCapacity returns the provisioned read or write capacity of a table or index.
+func provisionedCapacity(throughput *types.ProvisionedThroughputDescription, dimension string) int64 {
+	if throughput == nil {
+		return 0
+	}
+	if dimension == Read {
+		return aws.ToInt64(throughput.ReadCapacityUnits)
+	}
+	return aws.ToInt64(throughput.WriteCapacityUnits)
+}
+
+// GetScalingSettings collects the auto scaling of the read and write capacity of a table and its global
+// secondary indexes, including the dimensions that aren't auto scaled.
+// It returns the settings, the table description and an error.
+func GetScalingSettings(dbmgr *client.DynamoDBManager, tableName string) ([]ScalingSettings, *types.TableDescription, error) {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var settings []ScalingSettings
+	resourceIds := []string{client.ScalingResourceId(tableName, "")}
+	for _, dimension := range []string{Read, Write} {
+		settings = append(settings, ScalingSettings{TableName: tableName, Dimension: dimension, Capacity: provisionedCapacity(table.ProvisionedThroughput, dimension)})
+	}
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		indexName := aws.ToString(gsi.IndexName)
+		resourceIds = append(resourceIds, client.ScalingResourceId(tableName, indexName))
+		for _, dimension := range []string{Read, Write} {
+			settings = append(settings, ScalingSettings{TableName: tableName, IndexName: indexName, Dimension: dimension, Capacity: provisionedCapacity(gsi.ProvisionedThroughput, dimension)})
+		}
+	}
+
+	targets, err := GetScalableTargetsClient(dbmgr, resourceIds)
+	if err != nil {
+		return nil, nil, err
+	}
+	policiesByResource := make(map[string][]aastypes.ScalingPolicy)
+	for i := range settings {
+		setting := &settings[i]
+		resourceId := client.ScalingResourceId(tableName, setting.IndexName)
+		for _, target := range targets {
+			if aws.ToString(target.ResourceId) != resourceId || target.ScalableDimension != scalableDimension(setting.IndexName, setting.Dimension) {
+				continue
+			}
+			setting.MinCapacity = aws.ToInt32(target.MinCapacity)
+			setting.MaxCapacity = aws.ToInt32(target.MaxCapacity)
+
+			policies, cached := policiesByResource[resourceId]
+			if !cached {
+				if policies, err = GetScalingPoliciesClient(dbmgr, resourceId); err != nil {
+					return nil, nil, err
+				}
+				policiesByResource[resourceId] = policies
+			}
+			for _, policy := range policies {
+				if policy.ScalableDimension == target.ScalableDimension && policy.PolicyType == aastypes.PolicyTypeTargetTrackingScaling && policy.TargetTrackingScalingPolicyConfiguration != nil {
+					setting.PolicyName = aws.ToString(policy.PolicyName)
+					setting.TargetUtilization = aws.ToFloat64(policy.TargetTrackingScalingPolicyConfiguration.TargetValue)
+				}
+			}
+		}
+	}
+	return settings, table, nil
+}
+
+// describeTarget renders a table or index dimension for the log.
+func describeTarget(setting ScalingSettings) string {
+	if setting.IndexName == "" {
+		return fmt.Sprintf("table:%s %s", setting.TableName, setting.Dimension)
+	}
+	return fmt.Sprintf("table:%s index:%s %s", setting.TableName, setting.IndexName, setting.Dimension)
+}
+
+// ExecuteScalingShow logs the auto scaling of the read and write capacity of the given tables, or of the tables
+// matched by the search conditions, and of their global secondary indexes.
+// It takes a DynamoDBManager, table names, a fuzzy table name and a tag value as input.
+// It returns an error if the tables can't be selected or their auto scaling can't be retrieved.
+func ExecuteScalingShow(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	for _, tableName := range targets {
+		settings, table, err := GetScalingSettings(dbmgr, tableName)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to get the auto scaling of table:%s due to: %v", tableName, err)
+			return err
+		}
+		if client.BillingModeOf(table) != "PROVISIONED" {
+			dbmgr.Logger.Infof("Table:%s is on-demand, auto scaling doesn't apply", tableName)
+			continue
+		}
+
+		for _, setting := range settings {
+			switch {
+			case setting.MaxCapacity == 0:
+				dbmgr.Logger.Infof("%s - capacity:%d - not auto scaled", describeTarget(setting), setting.Capacity)
+			case setting.PolicyName == "":
+				dbmgr.Logger.Infof("%s - capacity:%d - min:%d max:%d - no target tracking policy", describeTarget(setting), setting.Capacity, setting.MinCapacity, setting.MaxCapacity)
+			default:
+				dbmgr.Logger.Infof("%s - capacity:%d - min:%d max:%d - target utilization:%.1f%% (policy:%s)",
+					describeTarget(setting), setting.Capacity, setting.MinCapacity, setting.MaxCapacity, setting.TargetUtilization, setting.PolicyName)
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	return nil
+}
+
+// findSettings returns the settings of a table or index dimension, or nil if the table has no such index.
+func findSettings(settings []ScalingSettings, indexName string, dimension string) *ScalingSettings {
+	for i := range settings {
+		if settings[i].IndexName == indexName && settings[i].Dimension == dimension {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// mergeScalingChange combines a change with the current auto scaling of a dimension and validates the result.
+// It returns the resulting settings and an error if they are incomplete or out of range.
+func mergeScalingChange(current ScalingSettings, change ScalingChange) (ScalingSettings, error) {
+	merged := current
+	if change.MinCapacity != 0 {
+		merged.MinCapacity = change.MinCapacity
+	}
+	if change.MaxCapacity != 0 {
+		merged.MaxCapacity = change.MaxCapacity
+	}
+	if change.TargetUtilization != 0 {
+		merged.TargetUtilization = change.TargetUtilization
+	}
+
+	if merged.MinCapacity == 0 || merged.MaxCapacity == 0 || merged.TargetUtilization == 0 {
+		return merged, errors.New(fmt.Sprintf("%s is not auto scaled yet - min, max and target utilization are required", describeTarget(current)))
+	}
+	if merged.MinCapacity > merged.MaxCapacity {
+		return merged, errors.New(fmt.Sprintf("%s - min:%d is greater than max:%d", describeTarget(current), merged.MinCapacity, merged.MaxCapacity))
+	}
+	if merged.TargetUtilization < MinTargetUtilization || merged.TargetUtilization > MaxTargetUtilization {
+		return merged, errors.New(fmt.Sprintf("%s - target utilization:%.1f must be between %d and %d", describeTarget(current), merged.TargetUtilization, MinTargetUtilization, MaxTargetUtilization))
+	}
+	return merged, nil
+}
+
+// ExecuteScalingSet enables auto scaling, or modifies its limits and target utilization, for the given dimensions
+// of the given tables, or of the tables matched by the search conditions, or of one of their indexes.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value, the ScalingChange and a dry-run flag as input.
+// With dry-run the changes are only logged.
+// It returns an error if no tables were selected or any table couldn't be changed.
+func ExecuteScalingSet(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, change ScalingChange, dryRun bool) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	var failedTables []string
+	for _, tableName := range targets {
+		if err := setTableScaling(dbmgr, tableName, change, dryRun); err != nil {
+			dbmgr.Logger.Errorf("Failed to set the auto scaling of table:%s due to: %v", tableName, err)
+			failedTables = append(failedTables, tableName)
+		}
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to set the auto scaling of tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
+
+// setTableScaling applies a ScalingChange to a single provisioned table.
+// It returns an error if the table is on-demand, lacks the index or can't be changed.
+func setTableScaling(dbmgr *client.DynamoDBManager, tableName string, change ScalingChange, dryRun bool) error {
+	settings, table, err := GetScalingSettings(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+	if client.BillingModeOf(table) != "PROVISIONED" {
+		return errors.New(fmt.Sprintf("table:%s is on-demand, only provisioned tables can be auto scaled", tableName))
+	}
+
+	for _, dimension := range change.Dimensions {
+		current := findSettings(settings, change.IndexName, dimension)
+		if current == nil {
+			return errors.New(fmt.Sprintf("table:%s has no global secondary index:%s", tableName, change.IndexName))
+		}
+
+		merged, err := mergeScalingChange(*current, change)
+		if err != nil {
+			return err
+		}
+		if merged == *current {
+			dbmgr.Logger.Infof("%s - auto scaling already up to date", describeTarget(merged))
+			continue
+		}
+
+		resourceId := client.ScalingResourceId(tableName, change.IndexName)
+		if merged.PolicyName == "" {
+			merged.PolicyName = defaultPolicyName(resourceId, dimension)
+		}
+		if dryRun {
+			dbmgr.Logger.Infof("%s - would set auto scaling min:%d max:%d target utilization:%.1f%% (policy:%s)",
+				describeTarget(merged), merged.MinCapacity, merged.MaxCapacity, merged.TargetUtilization, merged.PolicyName)
+			continue
+		}
+
+		dimensionName := scalableDimension(change.IndexName, dimension)
+		if err := RegisterScalableTargetClient(dbmgr, resourceId, dimensionName, merged.MinCapacity, merged.MaxCapacity); err != nil {
+			return err
+		}
+		if err := PutTargetTrackingPolicyClient(dbmgr, resourceId, dimensionName, merged.PolicyName, merged.TargetUtilization); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExecuteScalingRemove stops auto scaling the given dimensions of the given tables, or of the tables matched by
+// the search conditions, or of one of their indexes. The provisioned capacity stays at its last value.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value, an index name, the dimensions and
+// a dry-run flag as input. Tables the deletion protection policy doesn't allow it on are refused.
+// It returns an error if no tables were selected or any table couldn't be changed.
+func ExecuteScalingRemove(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, indexName string, dimensions []string, dryRun bool) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	var failedTables []string
+	for _, tableName := range targets {
+		if err := CheckPolicyClient(dbmgr, tableName, "remove the auto scaling of"); err != nil {
+			dbmgr.Logger.Errorf("%v", err)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		settings, _, err := GetScalingSettings(dbmgr, tableName)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		for _, dimension := range dimensions {
+			current := findSettings(settings, indexName, dimension)
+			if current == nil {
+				dbmgr.Logger.Errorf("Table:%s has no global secondary index:%s", tableName, indexName)
+				failedTables = append(failedTables, tableName)
+				break
+			}
+			if current.MaxCapacity == 0 {
+				dbmgr.Logger.Infof("%s - not auto scaled", describeTarget(*current))
+				continue
+			}
+			if dryRun {
+				dbmgr.Logger.Infof("%s - would remove auto scaling min:%d max:%d, capacity stays at %d", describeTarget(*current), current.MinCapacity, current.MaxCapacity, current.Capacity)
+				continue
+			}
+			if err := DeregisterScalableTargetClient(dbmgr, client.ScalingResourceId(tableName, indexName), scalableDimension(indexName, dimension)); err != nil {
+				failedTables = append(failedTables, tableName)
+				break
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to remove the auto scaling of tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+	"github.com/bazelgo/dynamodb-manager/update"
+)
+
+var (
+	ExecuteUpdateTask = update.ExecuteUpdate
+	ResolveTablesTask = search.ResolveTables
+)
+
+// TickInterval is how often the scheduler checks for due entries and retries.
+var TickInterval = time.Minute
+
+// EntryState is what the scheduler remembers about an entry between checks and restarts.
+// Pending holds the tables whose change was blocked by a cooldown and is retried at NextRetry.
+type EntryState struct {
+	LastScheduled time.Time  `json:"lastScheduled"`
+	LastRun       *time.Time `json:"lastRun,omitempty"`
+	Pending       []string   `json:"pending,omitempty"`
+	NextRetry     *time.Time `json:"nextRetry,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// State is the persisted state of the scheduler, by entry name.
+type State struct {
+	Entries map[string]*EntryState `json:"entries"`
+}
+
+// DefaultStateFile returns the scheduler state location used when none is configured: ~/.dynamodb-manager/schedule-state.json
+func DefaultStateFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".dynamodb-manager", "schedule-state.json")
+	}
+	return filepath.Join(home, ".dynamodb-manager", "schedule-state.json")
+}
+
+// LoadState reads the scheduler state file. A missing file holds an empty state.
+// It returns the State and an error.
+func LoadState(stateFile string) (*State, error) {
+	state := &State{Entries: make(map[string]*EntryState)}
+	content, err := os.ReadFile(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse state file:%s - error:%v", stateFile, err))
+	}
+	if state.Entries == nil {
+		state.Entries = make(map[string]*EntryState)
+	}
+	return state, nil
+}
+
+// SaveState writes the scheduler state file, replacing it atomically so a crash never leaves it half written.
+// It returns an error if the state can't be written.
+func SaveState(stateFile string, state *State) error {
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := stateFile + ".tmp"
+	if err := os.WriteFile(tmpFile, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, stateFile)
+}
+
+// applyEntry applies the change of an entry to every given table.
+// It returns the tables blocked by a cooldown and the tables that failed for another reason.
+func applyEntry(dbmgr *client.DynamoDBManager, entry *Entry, tableNames []string) ([]string, []string) {
+	var blockedTables, failedTables []string
+	for _, tableName := range tableNames {
+		dbmgr.Logger.Infof("Entry:%s - updating table:%s ...", entry.Name, tableName)
+		err := ExecuteUpdateTask(dbmgr, tableName, entry.UpdateOptions())
+		switch {
+		case errors.Is(err, update.ErrCooldown):
+			dbmgr.Logger.Warnf("Entry:%s - update of table:%s blocked: %v", entry.Name, tableName, err)
+			blockedTables = append(blockedTables, tableName)
+		case err != nil:
+			dbmgr.Logger.Errorf("Entry:%s - failed to update table:%s due to: %v", entry.Name, tableName, err)
+			failedTables = append(failedTables, tableName)
+		}
+	}
+	return blockedTables, failedTables
+}
+
+// runEntry applies an entry to the given tables and records the outcome, scheduling a retry of the tables
+// blocked by a cooldown as long as the retry window of the scheduled run allows it.
+func (s *Schedule) runEntry(dbmgr *client.DynamoDBManager, entry *Entry, entryState *EntryState, tableNames []string, now time.Time) {
+	blockedTables, failedTables := applyEntry(dbmgr, entry, tableNames)
+
+	entryState.Pending, entryState.NextRetry, entryState.LastError = nil, nil, ""
+	if len(failedTables) > 0 {
+		entryState.LastError = fmt.Sprintf("failed to update tables: %s", strings.Join(failedTables, ","))
+	}
+	if len(blockedTables) == 0 && len(failedTables) == 0 {
+		entryState.LastRun = &now
+	}
+
+	if len(blockedTables) == 0 {
+		return
+	}
+	nextRetry := now.Add(s.retryInterval)
+	if nextRetry.Sub(entryState.LastScheduled) > s.retryWindow {
+		dbmgr.Logger.Warnf("Entry:%s - giving up on tables blocked by a cooldown: %s, the retry window of the run scheduled at %v is over",
+			entry.Name, strings.Join(blockedTables, ","), entryState.LastScheduled)
+		entryState.LastError = strings.TrimPrefix(fmt.Sprintf("%s; skipped tables blocked by a cooldown: %s", entryState.LastError, strings.Join(blockedTables, ",")), "; ")
+		return
+	}
+	dbmgr.Logger.Infof("Entry:%s - retrying tables blocked by a cooldown: %s at %v", entry.Name, strings.Join(blockedTables, ","), nextRetry)
+	entryState.Pending = blockedTables
+	entryState.NextRetry = &nextRetry
+}
+
+// tick runs every entry that became due since its last scheduled run and retries pending tables.
+// Runs scheduled longer than the retry window ago, e.g. while the scheduler was down, are skipped.
+// It returns whether the state changed.
+func (s *Schedule) tick(dbmgr *client.DynamoDBManager, state *State, now time.Time) bool {
+	changed := false
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		entryState := state.Entries[entry.Name]
+		if entryState == nil {
+			// Start with the runs due within the last tick, not with everything the cron expression ever fired.
+			entryState = &EntryState{LastScheduled: now.Add(-TickInterval)}
+			state.Entries[entry.Name] = entryState
+			changed = true
+		}
+
+		var occurrence time.Time
+		missed := -1
+		for next := s.Next(entry, entryState.LastScheduled); !next.After(now); next = s.Next(entry, next) {
+			occurrence = next
+			missed++
+		}
+
+		if !occurrence.IsZero() {
+			changed = true
+			entryState.LastScheduled = occurrence
+			if missed > 0 {
+				dbmgr.Logger.Warnf("Entry:%s - missed %d runs, only the latest one scheduled at %v is considered", entry.Name, missed, occurrence)
+			}
+			if now.Sub(occurrence) > s.retryWindow {
+				dbmgr.Logger.Warnf("Entry:%s - skipping the run scheduled at %v, it is older than the retry window of %v", entry.Name, occurrence, s.retryWindow)
+				entryState.Pending, entryState.NextRetry = nil, nil
+				continue
+			}
+			if len(entryState.Pending) > 0 {
+				dbmgr.Logger.Warnf("Entry:%s - the run scheduled at %v replaces the pending retry of tables: %s", entry.Name, occurrence, strings.Join(entryState.Pending, ","))
+			}
+
+			dbmgr.Logger.Infof("Entry:%s - running the change scheduled at %v", entry.Name, occurrence)
+			tableNames, err := ResolveTablesTask(dbmgr, entry.Tables, entry.Search, entry.Tag)
+			if err != nil {
+				dbmgr.Logger.Errorf("Entry:%s - failed to select the tables due to: %v", entry.Name, err)
+				entryState.Pending, entryState.NextRetry, entryState.LastError = nil, nil, err.Error()
+				continue
+			}
+			s.runEntry(dbmgr, entry, entryState, tableNames, now)
+			continue
+		}
+
+		if len(entryState.Pending) > 0 && entryState.NextRetry != nil && !now.Before(*entryState.NextRetry) {
+			changed = true
+			dbmgr.Logger.Infof("Entry:%s - retrying tables: %s", entry.Name, strings.Join(entryState.Pending, ","))
+			s.runEntry(dbmgr, entry, entryState, entryState.Pending, now)
+		}
+	}
+	return changed
+}
+
+// ExecuteScheduleRun runs the scheduler: it applies the entries of a schedule file when their cron expressions
+// fire and retries changes blocked by a cooldown, persisting its progress in a state file so that a restart
+// neither repeats nor loses runs. It stops on SIGINT or SIGTERM.
+// It takes a DynamoDBManager, the schedule file path, the state file path and a once flag as input.
+// With once the due entries are handled a single time, e.g. when the scheduler itself is started by cron.
+// It returns an error if the schedule or state can't be loaded or the state can't be saved.
+func ExecuteScheduleRun(dbmgr *client.DynamoDBManager, scheduleFile string, stateFile string, once bool) error {
+	schedule, err := LoadSchedule(scheduleFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load schedule due to: %v", err)
+		return err
+	}
+
+	state, err := LoadState(stateFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load scheduler state due to: %v", err)
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !once {
+		dbmgr.Logger.Infof("Scheduler started with %d entries from:%s - state:%s", len(schedule.Entries), scheduleFile, stateFile)
+	}
+	for {
+		now := time.Now()
+		if schedule.tick(dbmgr, state, now) {
+			if err := SaveState(stateFile, state); err != nil {
+				dbmgr.Logger.Errorf("Failed to save scheduler state:%s due to: %v", stateFile, err)
+				return err
+			}
+		}
+		if once {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			dbmgr.Logger.Infof("Scheduler stopped")
+			return nil
+		case <-time.After(time.Until(now.Truncate(TickInterval).Add(TickInterval))):
+		}
+	}
+}
+
+// ExecuteScheduleShow logs every entry of a schedule file with its next run and the outcome of its last run.
+// It takes a DynamoDBManager, the schedule file path and the state file path as input.
+// It returns an error if the schedule or state can't be loaded.
+func ExecuteScheduleShow(dbmgr *client.DynamoDBManager, scheduleFile string, stateFile string) error {
+	schedule, err := LoadSchedule(scheduleFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load schedule due to: %v", err)
+		return err
+	}
+
+	state, err := LoadState(stateFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load scheduler state due to: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	for i := range schedule.Entries {
+		entry := &schedule.Entries[i]
+		dbmgr.Logger.Infof("Entry:%s - cron:%s - next run:%v", entry.Name, entry.Cron, schedule.Next(entry, now))
+
+		entryState := state.Entries[entry.Name]
+		if entryState == nil {
+			dbmgr.Logger.Infof("Entry:%s - never run", entry.Name)
+			continue
+		}
+		if entryState.LastRun != nil {
+			dbmgr.Logger.Infof("Entry:%s - last completed run:%v", entry.Name, *entryState.LastRun)
+		}
+		if len(entryState.Pending) > 0 {
+			dbmgr.Logger.Warnf("Entry:%s - tables blocked by a cooldown: %s - next retry:%v", entry.Name, strings.Join(entryState.Pending, ","), *entryState.NextRetry)
+		}
+		if entryState.LastError != "" {
+			dbmgr.Logger.Warnf("Entry:%s - last error: %s", entry.Name, entryState.LastError)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/update"
+)
+
+// Billing modes accepted in a schedule file
+const (
+	Provisioned = "PROVISIONED"
+	OnDemand    = "PAY_PER_REQUEST"
+)
+
+// Defaults for changes blocked by a DynamoDB cooldown
+const (
+	DefaultRetryInterval = 15 * time.Minute
+	DefaultRetryWindow   = 6 * time.Hour
+)
+
+// Entry is a capacity change applied to the selected tables whenever its cron expression fires.
+// Tables are selected by name, or by fuzzy name and tag value like search does.
+// Rcu and Wcu accept absolute and relative values (see update.ParseCapacityChange).
+type Entry struct {
+	Name             string   `json:"name" yaml:"name"`
+	Cron             string   `json:"cron" yaml:"cron"`
+	Tables           []string `json:"tables,omitempty" yaml:"tables,omitempty"`
+	Search           string   `json:"search,omitempty" yaml:"search,omitempty"`
+	Tag              string   `json:"tag,omitempty" yaml:"tag,omitempty"`
+	BillingMode      string   `json:"billingMode,omitempty" yaml:"billingMode,omitempty"`
+	Rcu              string   `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu              string   `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+	MinCapacity      int64    `json:"minCapacity,omitempty" yaml:"minCapacity,omitempty"`
+	MaxCapacity      int64    `json:"maxCapacity,omitempty" yaml:"maxCapacity,omitempty"`
+	ReserveDecreases int      `json:"reserveDecreases,omitempty" yaml:"reserveDecreases,omitempty"`
+
+	schedule cron.Schedule
+}
+
+// Schedule is the schedule file: the entries and how changes blocked by a cooldown are retried.
+// Cron expressions are evaluated in TimeZone, the local time zone if unset.
+// A blocked change is retried every RetryInterval until RetryWindow has passed since its scheduled time.
+type Schedule struct {
+	TimeZone      string  `json:"timeZone,omitempty" yaml:"timeZone,omitempty"`
+	RetryInterval string  `json:"retryInterval,omitempty" yaml:"retryInterval,omitempty"`
+	RetryWindow   string  `json:"retryWindow,omitempty" yaml:"retryWindow,omitempty"`
+	Entries       []Entry `json:"entries" yaml:"entries"`
+
+	location      *time.Location
+	retryInterval time.Duration
+	retryWindow   time.Duration
+}
+
+// UpdateOptions converts the change of an entry into the options of update.ExecuteUpdate.
+func (e *Entry) UpdateOptions() update.UpdateOptions {
+	return update.UpdateOptions{
+		Rcu:                 e.Rcu,
+		Wcu:                 e.Wcu,
+		SwitchToOnDemand:    e.BillingMode == OnDemand,
+		SwitchToProvisioned: e.BillingMode == Provisioned,
+		ReserveDecreases:    e.ReserveDecreases,
+		MinCapacity:         e.MinCapacity,
+		MaxCapacity:         e.MaxCapacity,
+	}
+}
+
+// Next returns the first time the entry fires after the given time.
+func (s *Schedule) Next(entry *Entry, after time.Time) time.Time {
+	return entry.schedule.Next(after.In(s.location))
+}
+
+// parseDuration parses an optional duration setting of the schedule file.
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration <= 0 {
+		return 0, errors.New(fmt.Sprintf("duration:%s must be positive", value))
+	}
+	return duration, nil
+}
+
+// validate checks a single schedule entry and parses its cron expression.
+// It returns an error describing the first problem found.
+func (e *Entry) validate() error {
+	if e.Name == "" {
+		return errors.New("entry has no name")
+	}
+
+	schedule, err := cron.ParseStandard(e.Cron)
+	if err != nil {
+		return errors.New(fmt.Sprintf("entry:%s has an invalid cron expression:%s - error:%v", e.Name, e.Cron, err))
+	}
+	e.schedule = schedule
+
+	if len(e.Tables) == 0 && e.Search == "" && e.Tag == "" {
+		return errors.New(fmt.Sprintf("entry:%s selects no tables - set tables, search or tag", e.Name))
+	}
+	if len(e.Tables) > 0 && (e.Search != "" || e.Tag != "") {
+		return errors.New(fmt.Sprintf("entry:%s sets tables together with search or tag", e.Name))
+	}
+
+	switch e.BillingMode {
+	case "", Provisioned:
+		if e.BillingMode == "" && e.Rcu == "" && e.Wcu == "" {
+			return errors.New(fmt.Sprintf("entry:%s changes nothing - set billingMode, rcu or wcu", e.Name))
+		}
+	case OnDemand:
+		if e.Rcu != "" || e.Wcu != "" {
+			return errors.New(fmt.Sprintf("entry:%s sets rcu or wcu for %s", e.Name, OnDemand))
+		}
+	default:
+		return errors.New(fmt.Sprintf("entry:%s has unsupported billingMode:%s", e.Name, e.BillingMode))
+	}
+
+	for _, value := range []string{e.Rcu, e.Wcu} {
+		if value == "" {
+			continue
+		}
+		if _, err := update.ParseCapacityChange(value); err != nil {
+			return errors.New(fmt.Sprintf("entry:%s has invalid capacity:%s - error:%v", e.Name, value, err))
+		}
+	}
+
+	if e.ReserveDecreases < 0 || e.MinCapacity < 0 || e.MaxCapacity < 0 || (e.MaxCapacity > 0 && e.MinCapacity > e.MaxCapacity) {
+		return errors.New(fmt.Sprintf("entry:%s has invalid limits - reserveDecreases:%d minCapacity:%d maxCapacity:%d", e.Name, e.ReserveDecreases, e.MinCapacity, e.MaxCapacity))
+	}
+	return nil
+}
+
+// Validate checks the schedule file and prepares its time zone, retry settings and cron expressions.
+// It returns an error describing the first problem found.
+func (s *Schedule) Validate() error {
+	if len(s.Entries) == 0 {
+		return errors.New("no entries defined")
+	}
+
+	var err error
+	s.location = time.Local
+	if s.TimeZone != "" {
+		if s.location, err = time.LoadLocation(s.TimeZone); err != nil {
+			return errors.New(fmt.Sprintf("invalid timeZone:%s - error:%v", s.TimeZone, err))
+		}
+	}
+	if s.retryInterval, err = parseDuration(s.RetryInterval, DefaultRetryInterval); err != nil {
+		return errors.New(fmt.Sprintf("invalid retryInterval - error:%v", err))
+	}
+	if s.retryWindow, err = parseDuration(s.RetryWindow, DefaultRetryWindow); err != nil {
+		return errors.New(fmt.Sprintf("invalid retryWindow - error:%v", err))
+	}
+
+	names := make(map[string]bool, len(s.Entries))
+	for i := range s.Entries {
+		entry := &s.Entries[i]
+		if err := entry.validate(); err != nil {
+			return errors.New(fmt.Sprintf("entries[%d]: %v", i, err))
+		}
+		if names[entry.Name] {
+			return errors.New(fmt.Sprintf("entries[%d]: duplicate name:%s", i, entry.Name))
+		}
+		names[entry.Name] = true
+	}
+	return nil
+}
+
+// LoadSchedule reads a schedule file, parsed as JSON for a .json extension and as YAML otherwise.
+// It returns the validated Schedule and an error.
+func LoadSchedule(path string) (*Schedule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule Schedule
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &schedule)
+	} else {
+		err = yaml.Unmarshal(content, &schedule)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse schedule file:%s - error:%v", path, err))
+	}
+
+	if err := schedule.Validate(); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid schedule file:%s - %v", path, err))
+	}
+	return &schedule, nil
+}
@@ -0,0 +1,363 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// DynamoDB limits checked before creating a table
+const (
+	MaxGlobalSecondaryIndexes = 20
+	MaxLocalSecondaryIndexes  = 5
+)
+
+// CreateWaitTimeout bounds how long create waits for a new table to become ACTIVE.
+var CreateWaitTimeout = 30 * time.Minute
+
+var (
+	CreateTableClient             = client.CreateTable
+	WaitForTableActiveClient      = client.WaitForTableActive
+	UpdateTimeToLiveClient        = client.UpdateTimeToLive
+	UpdateContinuousBackupsClient = client.UpdateContinuousBackups
+)
+
+// validTableName matches the table and index names DynamoDB accepts.
+var validTableName = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,255}$`)
+
+// LoadDefinition reads a table definition file, as emitted by describe, parsed as JSON for a .json extension and
+// as YAML otherwise. The runtime sections are dropped.
+// It returns the TableDefinition and an error.
+func LoadDefinition(path string) (*TableDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var definition TableDefinition
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &definition)
+	} else {
+		err = yaml.Unmarshal(content, &definition)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse table definition file:%s - error:%v", path, err))
+	}
+
+	definition.Runtime = nil
+	for _, indexes := range [][]IndexDefinition{definition.GlobalSecondaryIndexes, definition.LocalSecondaryIndexes} {
+		for i := range indexes {
+			indexes[i].Runtime = nil
+		}
+	}
+	return &definition, nil
+}
+
+// validateKeySchema checks the key schema of a table or index against the attribute definitions
+// and marks the key attributes as used.
+func (d *TableDefinition) validateKeySchema(owner string, keySchema KeySchema, used map[string]bool) error {
+	if keySchema.PartitionKey == "" {
+		return errors.New(fmt.Sprintf("%s has no partitionKey", owner))
+	}
+	for _, name := range []string{keySchema.PartitionKey, keySchema.SortKey} {
+		if name == "" {
+			continue
+		}
+		if d.AttributeType(name) == "" {
+			return errors.New(fmt.Sprintf("%s uses key attribute:%s that isn't in attributes", owner, name))
+		}
+		used[name] = true
+	}
+	return nil
+}
+
+// validateIndex checks a global or local secondary index.
+func (d *TableDefinition) validateIndex(index IndexDefinition, global bool, names map[string]bool, used map[string]bool) error {
+	owner := fmt.Sprintf("index:%s", index.IndexName)
+	if !validTableName.MatchString(index.IndexName) {
+		return errors.New(fmt.Sprintf("invalid indexName:%s - expected 3 to 255 characters a-z, A-Z, 0-9, _, - or .", index.IndexName))
+	}
+	if names[index.IndexName] {
+		return errors.New(fmt.Sprintf("duplicate %s", owner))
+	}
+	names[index.IndexName] = true
+
+	if err := d.validateKeySchema(owner, index.KeySchema, used); err != nil {
+		return err
+	}
+	if !global && (index.KeySchema.PartitionKey != d.KeySchema.PartitionKey || index.KeySchema.SortKey == "") {
+		return errors.New(fmt.Sprintf("%s is a local index and needs the partitionKey of the table and a sortKey", owner))
+	}
+
+	switch types.ProjectionType(index.Projection.Type) {
+	case types.ProjectionTypeInclude:
+		if len(index.Projection.NonKeyAttributes) == 0 {
+			return errors.New(fmt.Sprintf("%s has an INCLUDE projection without nonKeyAttributes", owner))
+		}
+	case types.ProjectionTypeAll, types.ProjectionTypeKeysOnly:
+		if len(index.Projection.NonKeyAttributes) > 0 {
+			return errors.New(fmt.Sprintf("%s can only have nonKeyAttributes with an INCLUDE projection", owner))
+		}
+	default:
+		return errors.New(fmt.Sprintf("%s has unsupported projection type:%s - expected ALL, KEYS_ONLY or INCLUDE", owner, index.Projection.Type))
+	}
+
+	provisioned := d.BillingMode == string(types.BillingModeProvisioned)
+	if !global && (index.Rcu != 0 || index.Wcu != 0 || index.MaxReadRequestUnits != 0 || index.MaxWriteRequestUnits != 0) {
+		return errors.New(fmt.Sprintf("%s is a local index and shares the throughput of the table", owner))
+	}
+	if global && provisioned && (index.Rcu < 1 || index.Wcu < 1) {
+		return errors.New(fmt.Sprintf("%s needs rcu and wcu of at least 1 with billingMode:%s", owner, d.BillingMode))
+	}
+	if global && !provisioned && (index.Rcu != 0 || index.Wcu != 0) {
+		return errors.New(fmt.Sprintf("%s can't have rcu or wcu with billingMode:%s", owner, d.BillingMode))
+	}
+	if global && provisioned && (index.MaxReadRequestUnits != 0 || index.MaxWriteRequestUnits != 0) {
+		return errors.New(fmt.Sprintf("%s can't have on-demand limits with billingMode:%s", owner, d.BillingMode))
+	}
+	return nil
+}
+
+// Validate checks a table definition before it is created, filling in the default billing mode and table class.
+// It returns an error describing the first problem found.
+func (d *TableDefinition) Validate() error {
+	if !validTableName.MatchString(d.TableName) {
+		return errors.New(fmt.Sprintf("invalid tableName:%s - expected 3 to 255 characters a-z, A-Z, 0-9, _, - or .", d.TableName))
+	}
+
+	seen := make(map[string]bool)
+	for _, attribute := range d.Attributes {
+		if attribute.Name == "" || seen[attribute.Name] {
+			return errors.New(fmt.Sprintf("attribute names must be set and unique, got:%q", attribute.Name))
+		}
+		seen[attribute.Name] = true
+		if attribute.Type != "S" && attribute.Type != "N" && attribute.Type != "B" {
+			return errors.New(fmt.Sprintf("attribute:%s has unsupported type:%s - expected S, N or B", attribute.Name, attribute.Type))
+		}
+	}
+
+	if d.BillingMode == "" {
+		d.BillingMode = string(types.BillingModePayPerRequest)
+	}
+	switch d.BillingMode {
+	case string(types.BillingModeProvisioned):
+		if d.Rcu < 1 || d.Wcu < 1 {
+			return errors.New(fmt.Sprintf("rcu and wcu must be at least 1 with billingMode:%s", d.BillingMode))
+		}
+		if d.MaxReadRequestUnits != 0 || d.MaxWriteRequestUnits != 0 {
+			return errors.New(fmt.Sprintf("on-demand limits can't be used with billingMode:%s", d.BillingMode))
+		}
+	case string(types.BillingModePayPerRequest):
+		if d.Rcu != 0 || d.Wcu != 0 {
+			return errors.New(fmt.Sprintf("rcu and wcu can't be used with billingMode:%s", d.BillingMode))
+		}
+	default:
+		return errors.New(fmt.Sprintf("unsupported billingMode:%s - expected PROVISIONED or PAY_PER_REQUEST", d.BillingMode))
+	}
+
+	used := make(map[string]bool)
+	if err := d.validateKeySchema("table", d.KeySchema, used); err != nil {
+		return err
+	}
+	if len(d.GlobalSecondaryIndexes) > MaxGlobalSecondaryIndexes || len(d.LocalSecondaryIndexes) > MaxLocalSecondaryIndexes {
+		return errors.New(fmt.Sprintf("at most %d global and %d local secondary indexes are allowed", MaxGlobalSecondaryIndexes, MaxLocalSecondaryIndexes))
+	}
+	names := make(map[string]bool)
+	for _, index := range d.GlobalSecondaryIndexes {
+		if err := d.validateIndex(index, true, names, used); err != nil {
+			return err
+		}
+	}
+	for _, index := range d.LocalSecondaryIndexes {
+		if err := d.validateIndex(index, false, names, used); err != nil {
+			return err
+		}
+	}
+	for _, attribute := range d.Attributes {
+		if !used[attribute.Name] {
+			return errors.New(fmt.Sprintf("attribute:%s isn't used by any key schema, DynamoDB only accepts key attributes", attribute.Name))
+		}
+	}
+
+	if d.Stream != nil && d.Stream.Enabled {
+		viewType, err := client.ParseStreamViewType(d.Stream.ViewType)
+		if err != nil {
+			return err
+		}
+		d.Stream.ViewType = viewType
+	}
+	if d.TimeToLive != nil && d.TimeToLive.Enabled && d.TimeToLive.AttributeName == "" {
+		return errors.New("timeToLive needs an attributeName when enabled")
+	}
+	if d.Sse != nil && d.Sse.Type != "" && d.Sse.Type != string(types.SSETypeKms) {
+		return errors.New(fmt.Sprintf("unsupported sse type:%s - expected KMS, or no sse for the AWS owned key", d.Sse.Type))
+	}
+	if d.Sse != nil && d.Sse.Type == "" && d.Sse.KmsKeyId != "" {
+		return errors.New("sse kmsKeyId needs type KMS")
+	}
+
+	d.TableClass = strings.TrimSpace(d.TableClass)
+	if d.TableClass == "" {
+		d.TableClass = string(types.TableClassStandard)
+	}
+	tableClass, err := client.ParseTableClass(d.TableClass)
+	if err != nil {
+		return err
+	}
+	d.TableClass = tableClass
+	return nil
+}
+
+// keySchemaElements converts a key schema into the elements of a CreateTable request.
+func keySchemaElements(keySchema KeySchema) []types.KeySchemaElement {
+	elements := []types.KeySchemaElement{{AttributeName: aws.String(keySchema.PartitionKey), KeyType: types.KeyTypeHash}}
+	if keySchema.SortKey != "" {
+		elements = append(elements, types.KeySchemaElement{AttributeName: aws.String(keySchema.SortKey), KeyType: types.KeyTypeRange})
+	}
+	return elements
+}
+
+// onDemandThroughput converts on-demand limits, nil if there are none.
+func onDemandThroughput(maxRead int64, maxWrite int64) *types.OnDemandThroughput {
+	if maxRead == 0 && maxWrite == 0 {
+		return nil
+	}
+	throughput := &types.OnDemandThroughput{}
+	if maxRead != 0 {
+		throughput.MaxReadRequestUnits = aws.Int64(maxRead)
+	}
+	if maxWrite != 0 {
+		throughput.MaxWriteRequestUnits = aws.Int64(maxWrite)
+	}
+	return throughput
+}
+
+// CreateTableInput builds the CreateTable request of a validated table definition.
+// Tags with the reserved aws: prefix, Time to Live, point-in-time recovery and replicas are not part of the request.
+func (d *TableDefinition) CreateTableInput() *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName:                 aws.String(d.TableName),
+		KeySchema:                 keySchemaElements(d.KeySchema),
+		BillingMode:               types.BillingMode(d.BillingMode),
+		TableClass:                types.TableClass(d.TableClass),
+		DeletionProtectionEnabled: aws.Bool(d.DeletionProtection),
+		OnDemandThroughput:        onDemandThroughput(d.MaxReadRequestUnits, d.MaxWriteRequestUnits),
+	}
+	for _, attribute := range d.Attributes {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, types.AttributeDefinition{
+			AttributeName: aws.String(attribute.Name),
+			AttributeType: types.ScalarAttributeType(attribute.Type),
+		})
+	}
+	provisioned := d.BillingMode == string(types.BillingModeProvisioned)
+	if provisioned {
+		input.ProvisionedThroughput = &types.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(d.Rcu), WriteCapacityUnits: aws.Int64(d.Wcu)}
+	}
+
+	projection := func(p Projection) *types.Projection {
+		return &types.Projection{ProjectionType: types.ProjectionType(p.Type), NonKeyAttributes: p.NonKeyAttributes}
+	}
+	for _, index := range d.GlobalSecondaryIndexes {
+		gsi := types.GlobalSecondaryIndex{
+			IndexName:          aws.String(index.IndexName),
+			KeySchema:          keySchemaElements(index.KeySchema),
+			Projection:         projection(index.Projection),
+			OnDemandThroughput: onDemandThroughput(index.MaxReadRequestUnits, index.MaxWriteRequestUnits),
+		}
+		if provisioned {
+			gsi.ProvisionedThroughput = &types.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(index.Rcu), WriteCapacityUnits: aws.Int64(index.Wcu)}
+		}
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, gsi)
+	}
+	for _, index := range d.LocalSecondaryIndexes {
+		input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes, types.LocalSecondaryIndex{
+			IndexName:  aws.String(index.IndexName),
+			KeySchema:  keySchemaElements(index.KeySchema),
+			Projection: projection(index.Projection),
+		})
+	}
+
+	if d.Stream != nil && d.Stream.Enabled {
+		input.StreamSpecification = &types.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: types.StreamViewType(d.Stream.ViewType)}
+	}
+	if d.Sse != nil && d.Sse.Type != "" {
+		input.SSESpecification = &types.SSESpecification{Enabled: aws.Bool(true), SSEType: types.SSEType(d.Sse.Type)}
+		if d.Sse.KmsKeyId != "" {
+			input.SSESpecification.KMSMasterKeyId = aws.String(d.Sse.KmsKeyId)
+		}
+	}
+	for key, value := range d.Tags {
+		if !strings.HasPrefix(key, "aws:") {
+			input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+	}
+	return input
+}
+
+// ExecuteCreate creates a table from a table definition file, the format describe emits, so that tables can be
+// cloned across environments. Time to Live and point-in-time recovery can only be set on an ACTIVE table, so create
+// waits for the table whenever the definition enables them.
+// It takes a DynamoDBManager, the definition file, an optional table name replacing the one of the file and a wait flag as input.
+// It returns an error if the definition is not valid or the table can't be created or configured.
+func ExecuteCreate(dbmgr *client.DynamoDBManager, specFile string, tableName string, wait bool) error {
+	definition, err := LoadDefinition(specFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load table definition due to: %v", err)
+		return err
+	}
+	if tableName != "" {
+		definition.TableName = tableName
+	}
+	if err := definition.Validate(); err != nil {
+		return errors.New(fmt.Sprintf("invalid table definition file:%s - error:%v", specFile, err))
+	}
+
+	for key := range definition.Tags {
+		if strings.HasPrefix(key, "aws:") {
+			dbmgr.Logger.Warnf("Tag:%s is reserved by AWS and isn't copied to table:%s", key, definition.TableName)
+		}
+	}
+	if len(definition.Replicas) > 0 {
+		dbmgr.Logger.Warnf("Table:%s is created without the %d replicas of the definition", definition.TableName, len(definition.Replicas))
+	}
+
+	dbmgr.Logger.Infof("Creating table:%s - %s, %s, %d global and %d local secondary indexes", definition.TableName, definition.BillingMode,
+		definition.TableClass, len(definition.GlobalSecondaryIndexes), len(definition.LocalSecondaryIndexes))
+	if err := CreateTableClient(dbmgr, definition.CreateTableInput()); err != nil {
+		return err
+	}
+
+	ttlEnabled := definition.TimeToLive != nil && definition.TimeToLive.Enabled
+	if !wait && !ttlEnabled && !definition.PointInTimeRecovery {
+		dbmgr.Logger.Infof("Table:%s is being created, it is usable once ACTIVE", definition.TableName)
+		return nil
+	}
+	if err := WaitForTableActiveClient(dbmgr, definition.TableName, CreateWaitTimeout); err != nil {
+		return err
+	}
+	dbmgr.Logger.Infof("Table:%s is ACTIVE", definition.TableName)
+
+	if ttlEnabled {
+		if err := UpdateTimeToLiveClient(dbmgr, definition.TableName, definition.TimeToLive.AttributeName, true); err != nil {
+			return err
+		}
+	}
+	if definition.PointInTimeRecovery {
+		if err := UpdateContinuousBackupsClient(dbmgr, definition.TableName, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
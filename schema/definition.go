@@ -0,0 +1,329 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// KeySchema names the partition key and the optional sort key of a table or index.
+type KeySchema struct {
+	PartitionKey string `json:"partitionKey" yaml:"partitionKey"`
+	SortKey      string `json:"sortKey,omitempty" yaml:"sortKey,omitempty"`
+}
+
+// Attribute is an attribute definition: a key attribute name and its scalar type S, N or B.
+type Attribute struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// Projection describes the attributes copied into an index: KEYS_ONLY, INCLUDE with NonKeyAttributes, or ALL.
+type Projection struct {
+	Type             string   `json:"type" yaml:"type"`
+	NonKeyAttributes []string `json:"nonKeyAttributes,omitempty" yaml:"nonKeyAttributes,omitempty"`
+}
+
+// IndexRuntime holds the live status of an index, reported by describe but never needed to create it.
+type IndexRuntime struct {
+	Status      string `json:"status,omitempty" yaml:"status,omitempty"`
+	Backfilling bool   `json:"backfilling,omitempty" yaml:"backfilling,omitempty"`
+	ItemCount   int64  `json:"itemCount" yaml:"itemCount"`
+	SizeBytes   int64  `json:"sizeBytes" yaml:"sizeBytes"`
+}
+
+// IndexDefinition describes a global or local secondary index.
+// Capacity values are only set for global indexes of provisioned tables and on-demand limits only for
+// global indexes of on-demand tables.
+type IndexDefinition struct {
+	IndexName            string        `json:"indexName" yaml:"indexName"`
+	KeySchema            KeySchema     `json:"keySchema" yaml:"keySchema"`
+	Projection           Projection    `json:"projection" yaml:"projection"`
+	Rcu                  int64         `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu                  int64         `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+	MaxReadRequestUnits  int64         `json:"maxReadRequestUnits,omitempty" yaml:"maxReadRequestUnits,omitempty"`
+	MaxWriteRequestUnits int64         `json:"maxWriteRequestUnits,omitempty" yaml:"maxWriteRequestUnits,omitempty"`
+	Runtime              *IndexRuntime `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+}
+
+// StreamDefinition describes the DynamoDB stream of a table.
+type StreamDefinition struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	ViewType string `json:"viewType,omitempty" yaml:"viewType,omitempty"`
+}
+
+// TimeToLiveDefinition describes the Time to Live setting of a table.
+type TimeToLiveDefinition struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	AttributeName string `json:"attributeName,omitempty" yaml:"attributeName,omitempty"`
+}
+
+// SseDefinition describes the server-side encryption of a table.
+// An empty type stands for the AWS owned key, the default encryption of every table.
+type SseDefinition struct {
+	Type     string `json:"type,omitempty" yaml:"type,omitempty"`
+	KmsKeyId string `json:"kmsKeyId,omitempty" yaml:"kmsKeyId,omitempty"`
+}
+
+// ReplicaDefinition describes a replica of a global table.
+type ReplicaDefinition struct {
+	RegionName           string `json:"regionName" yaml:"regionName"`
+	ReadCapacityOverride int64  `json:"readCapacityOverride,omitempty" yaml:"readCapacityOverride,omitempty"`
+	TableClass           string `json:"tableClass,omitempty" yaml:"tableClass,omitempty"`
+	Status               string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// TableRuntime holds the live status of a table, reported by describe but never needed to create it.
+type TableRuntime struct {
+	TableArn             string     `json:"tableArn" yaml:"tableArn"`
+	Status               string     `json:"status" yaml:"status"`
+	CreationDateTime     *time.Time `json:"creationDateTime,omitempty" yaml:"creationDateTime,omitempty"`
+	ItemCount            int64      `json:"itemCount" yaml:"itemCount"`
+	SizeBytes            int64      `json:"sizeBytes" yaml:"sizeBytes"`
+	StreamArn            string     `json:"streamArn,omitempty" yaml:"streamArn,omitempty"`
+	StreamLabel          string     `json:"streamLabel,omitempty" yaml:"streamLabel,omitempty"`
+	TimeToLiveStatus     string     `json:"timeToLiveStatus,omitempty" yaml:"timeToLiveStatus,omitempty"`
+	SseStatus            string     `json:"sseStatus,omitempty" yaml:"sseStatus,omitempty"`
+	GlobalTableVersion   string     `json:"globalTableVersion,omitempty" yaml:"globalTableVersion,omitempty"`
+	DecreasesToday       int64      `json:"decreasesToday" yaml:"decreasesToday"`
+	LastDecreaseDateTime *time.Time `json:"lastDecreaseDateTime,omitempty" yaml:"lastDecreaseDateTime,omitempty"`
+}
+
+// TableDefinition is the complete configuration of a table.
+// It is what describe reports and, without the runtime sections, what a table is created from.
+type TableDefinition struct {
+	TableName              string                `json:"tableName" yaml:"tableName"`
+	KeySchema              KeySchema             `json:"keySchema" yaml:"keySchema"`
+	Attributes             []Attribute           `json:"attributes" yaml:"attributes"`
+	BillingMode            string                `json:"billingMode" yaml:"billingMode"`
+	Rcu                    int64                 `json:"rcu,omitempty" yaml:"rcu,omitempty"`
+	Wcu                    int64                 `json:"wcu,omitempty" yaml:"wcu,omitempty"`
+	MaxReadRequestUnits    int64                 `json:"maxReadRequestUnits,omitempty" yaml:"maxReadRequestUnits,omitempty"`
+	MaxWriteRequestUnits   int64                 `json:"maxWriteRequestUnits,omitempty" yaml:"maxWriteRequestUnits,omitempty"`
+	GlobalSecondaryIndexes []IndexDefinition     `json:"globalSecondaryIndexes,omitempty" yaml:"globalSecondaryIndexes,omitempty"`
+	LocalSecondaryIndexes  []IndexDefinition     `json:"localSecondaryIndexes,omitempty" yaml:"localSecondaryIndexes,omitempty"`
+	Stream                 *StreamDefinition     `json:"stream,omitempty" yaml:"stream,omitempty"`
+	TimeToLive             *TimeToLiveDefinition `json:"timeToLive,omitempty" yaml:"timeToLive,omitempty"`
+	PointInTimeRecovery    bool                  `json:"pointInTimeRecovery" yaml:"pointInTimeRecovery"`
+	Sse                    *SseDefinition        `json:"sse,omitempty" yaml:"sse,omitempty"`
+	TableClass             string                `json:"tableClass" yaml:"tableClass"`
+	DeletionProtection     bool                  `json:"deletionProtection" yaml:"deletionProtection"`
+	Replicas               []ReplicaDefinition   `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	Tags                   map[string]string     `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Runtime                *TableRuntime         `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+}
+
+// Index returns the global or local secondary index with the given name, or nil if the table has no such index.
+func (d *TableDefinition) Index(indexName string) *IndexDefinition {
+	for _, indexes := range [][]IndexDefinition{d.GlobalSecondaryIndexes, d.LocalSecondaryIndexes} {
+		for i := range indexes {
+			if indexes[i].IndexName == indexName {
+				return &indexes[i]
+			}
+		}
+	}
+	return nil
+}
+
+// AttributeType returns the type of an attribute definition, or "" if the attribute isn't defined.
+func (d *TableDefinition) AttributeType(name string) string {
+	for _, attribute := range d.Attributes {
+		if attribute.Name == name {
+			return attribute.Type
+		}
+	}
+	return ""
+}
+
+// keySchemaOf converts the key schema elements of a table or index.
+func keySchemaOf(elements []types.KeySchemaElement) KeySchema {
+	var keySchema KeySchema
+	for _, element := range elements {
+		switch element.KeyType {
+		case types.KeyTypeHash:
+			keySchema.PartitionKey = aws.ToString(element.AttributeName)
+		case types.KeyTypeRange:
+			keySchema.SortKey = aws.ToString(element.AttributeName)
+		}
+	}
+	return keySchema
+}
+
+// projectionOf converts the projection of an index.
+func projectionOf(projection *types.Projection) Projection {
+	if projection == nil {
+		return Projection{}
+	}
+	return Projection{Type: string(projection.ProjectionType), NonKeyAttributes: projection.NonKeyAttributes}
+}
+
+// DefinitionFromDescription builds the definition of a table from its description, continuous backups,
+// Time to Live description and tags. The backups and Time to Live descriptions may be nil if unknown.
+// It returns the TableDefinition including its runtime sections.
+func DefinitionFromDescription(table *types.TableDescription, pitr *types.PointInTimeRecoveryDescription, ttl *types.TimeToLiveDescription, tags map[string]string) *TableDefinition {
+	definition := &TableDefinition{
+		TableName:          aws.ToString(table.TableName),
+		KeySchema:          keySchemaOf(table.KeySchema),
+		BillingMode:        client.BillingModeOf(table),
+		TableClass:         client.TableClassOf(table),
+		DeletionProtection: aws.ToBool(table.DeletionProtectionEnabled),
+		Tags:               tags,
+		Runtime: &TableRuntime{
+			TableArn:           aws.ToString(table.TableArn),
+			Status:             string(table.TableStatus),
+			CreationDateTime:   table.CreationDateTime,
+			ItemCount:          aws.ToInt64(table.ItemCount),
+			SizeBytes:          aws.ToInt64(table.TableSizeBytes),
+			StreamArn:          aws.ToString(table.LatestStreamArn),
+			StreamLabel:        aws.ToString(table.LatestStreamLabel),
+			GlobalTableVersion: aws.ToString(table.GlobalTableVersion),
+		},
+	}
+	provisioned := definition.BillingMode == string(types.BillingModeProvisioned)
+
+	for _, attribute := range table.AttributeDefinitions {
+		definition.Attributes = append(definition.Attributes, Attribute{Name: aws.ToString(attribute.AttributeName), Type: string(attribute.AttributeType)})
+	}
+	sort.Slice(definition.Attributes, func(i, j int) bool { return definition.Attributes[i].Name < definition.Attributes[j].Name })
+
+	if provisioned && table.ProvisionedThroughput != nil {
+		definition.Rcu = aws.ToInt64(table.ProvisionedThroughput.ReadCapacityUnits)
+		definition.Wcu = aws.ToInt64(table.ProvisionedThroughput.WriteCapacityUnits)
+		definition.Runtime.DecreasesToday = aws.ToInt64(table.ProvisionedThroughput.NumberOfDecreasesToday)
+		definition.Runtime.LastDecreaseDateTime = table.ProvisionedThroughput.LastDecreaseDateTime
+	}
+	if !provisioned && table.OnDemandThroughput != nil {
+		definition.MaxReadRequestUnits = aws.ToInt64(table.OnDemandThroughput.MaxReadRequestUnits)
+		definition.MaxWriteRequestUnits = aws.ToInt64(table.OnDemandThroughput.MaxWriteRequestUnits)
+	}
+
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		index := IndexDefinition{
+			IndexName:  aws.ToString(gsi.IndexName),
+			KeySchema:  keySchemaOf(gsi.KeySchema),
+			Projection: projectionOf(gsi.Projection),
+			Runtime: &IndexRuntime{
+				Status:      string(gsi.IndexStatus),
+				Backfilling: aws.ToBool(gsi.Backfilling),
+				ItemCount:   aws.ToInt64(gsi.ItemCount),
+				SizeBytes:   aws.ToInt64(gsi.IndexSizeBytes),
+			},
+		}
+		if provisioned && gsi.ProvisionedThroughput != nil {
+			index.Rcu = aws.ToInt64(gsi.ProvisionedThroughput.ReadCapacityUnits)
+			index.Wcu = aws.ToInt64(gsi.ProvisionedThroughput.WriteCapacityUnits)
+		}
+		if !provisioned && gsi.OnDemandThroughput != nil {
+			index.MaxReadRequestUnits = aws.ToInt64(gsi.OnDemandThroughput.MaxReadRequestUnits)
+			index.MaxWriteRequestUnits = aws.ToInt64(gsi.OnDemandThroughput.MaxWriteRequestUnits)
+		}
+		definition.GlobalSecondaryIndexes = append(definition.GlobalSecondaryIndexes, index)
+	}
+	sort.Slice(definition.GlobalSecondaryIndexes, func(i, j int) bool {
+		return definition.GlobalSecondaryIndexes[i].IndexName < definition.GlobalSecondaryIndexes[j].IndexName
+	})
+
+	for _, lsi := range table.LocalSecondaryIndexes {
+		definition.LocalSecondaryIndexes = append(definition.LocalSecondaryIndexes, IndexDefinition{
+			IndexName:  aws.ToString(lsi.IndexName),
+			KeySchema:  keySchemaOf(lsi.KeySchema),
+			Projection: projectionOf(lsi.Projection),
+			Runtime: &IndexRuntime{
+				ItemCount: aws.ToInt64(lsi.ItemCount),
+				SizeBytes: aws.ToInt64(lsi.IndexSizeBytes),
+			},
+		})
+	}
+	sort.Slice(definition.LocalSecondaryIndexes, func(i, j int) bool {
+		return definition.LocalSecondaryIndexes[i].IndexName < definition.LocalSecondaryIndexes[j].IndexName
+	})
+
+	if table.StreamSpecification != nil && aws.ToBool(table.StreamSpecification.StreamEnabled) {
+		definition.Stream = &StreamDefinition{Enabled: true, ViewType: string(table.StreamSpecification.StreamViewType)}
+	}
+
+	if ttl != nil {
+		definition.Runtime.TimeToLiveStatus = string(ttl.TimeToLiveStatus)
+		if ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabled || ttl.TimeToLiveStatus == types.TimeToLiveStatusEnabling {
+			definition.TimeToLive = &TimeToLiveDefinition{Enabled: true, AttributeName: aws.ToString(ttl.AttributeName)}
+		}
+	}
+
+	if pitr != nil {
+		definition.PointInTimeRecovery = pitr.PointInTimeRecoveryStatus == types.PointInTimeRecoveryStatusEnabled
+	}
+
+	if table.SSEDescription != nil && table.SSEDescription.SSEType != "" {
+		definition.Sse = &SseDefinition{Type: string(table.SSEDescription.SSEType), KmsKeyId: aws.ToString(table.SSEDescription.KMSMasterKeyArn)}
+		definition.Runtime.SseStatus = string(table.SSEDescription.Status)
+	}
+
+	for _, replica := range table.Replicas {
+		replicaDefinition := ReplicaDefinition{
+			RegionName: aws.ToString(replica.RegionName),
+			Status:     string(replica.ReplicaStatus),
+		}
+		if replica.ProvisionedThroughputOverride != nil {
+			replicaDefinition.ReadCapacityOverride = aws.ToInt64(replica.ProvisionedThroughputOverride.ReadCapacityUnits)
+		}
+		if replica.ReplicaTableClassSummary != nil {
+			replicaDefinition.TableClass = string(replica.ReplicaTableClassSummary.TableClass)
+		}
+		definition.Replicas = append(definition.Replicas, replicaDefinition)
+	}
+	sort.Slice(definition.Replicas, func(i, j int) bool { return definition.Replicas[i].RegionName < definition.Replicas[j].RegionName })
+
+	return definition
+}
+
+// GetTableDefinition collects the complete configuration of a DynamoDB table: its description, continuous backups,
+// Time to Live and tags.
+// It returns the TableDefinition and an error.
+func GetTableDefinition(dbmgr *client.DynamoDBManager, tableName string) (*TableDefinition, error) {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	pitr, err := GetContinuousBackupsClient(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := GetTimeToLiveClient(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := GetTableTagsClient(dbmgr, aws.ToString(table.TableArn))
+	if err != nil {
+		return nil, err
+	}
+
+	return DefinitionFromDescription(table, pitr, ttl, client.TagsToMap(tags)), nil
+}
+
+// MarshalDefinition renders a value as JSON or YAML.
+// It returns the rendered bytes and an error if the format is neither.
+func MarshalDefinition(value interface{}, format string) ([]byte, error) {
+	switch format {
+	case JsonFormat:
+		content, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(content, '\n'), nil
+	case YamlFormat:
+		return yaml.Marshal(value)
+	default:
+		return nil, errors.New(fmt.Sprintf("unsupported output format:%s", format))
+	}
+}
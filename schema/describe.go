@@ -0,0 +1,255 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// Output formats of describe
+const (
+	TextFormat = "text"
+	JsonFormat = "json"
+	YamlFormat = "yaml"
+)
+
+var (
+	DescribeTableClient        = client.DescribeTable
+	GetContinuousBackupsClient = client.GetContinuousBackups
+	GetTimeToLiveClient        = client.GetTimeToLive
+	GetTableTagsClient         = client.GetTableTags
+)
+
+// Output is where reports are written.
+var Output io.Writer = os.Stdout
+
+// formatBytes renders a size in bytes with a binary unit.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// enabled renders a flag as enabled or disabled.
+func enabled(flag bool) string {
+	if flag {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// describeKey renders a key attribute together with its type.
+func describeKey(definition *TableDefinition, name string) string {
+	if attributeType := definition.AttributeType(name); attributeType != "" {
+		return fmt.Sprintf("%s (%s)", name, attributeType)
+	}
+	return name
+}
+
+// describeKeySchema renders the partition and sort key of a table or index.
+func describeKeySchema(definition *TableDefinition, keySchema KeySchema) string {
+	if keySchema.SortKey == "" {
+		return fmt.Sprintf("partition key %s", describeKey(definition, keySchema.PartitionKey))
+	}
+	return fmt.Sprintf("partition key %s, sort key %s", describeKey(definition, keySchema.PartitionKey), describeKey(definition, keySchema.SortKey))
+}
+
+// describeProjection renders the projection of an index.
+func describeProjection(projection Projection) string {
+	if len(projection.NonKeyAttributes) == 0 {
+		return projection.Type
+	}
+	return fmt.Sprintf("%s (%s)", projection.Type, strings.Join(projection.NonKeyAttributes, ", "))
+}
+
+// describeThroughput renders the capacity or on-demand limits of a table or index.
+func describeThroughput(billingMode string, rcu, wcu, maxRead, maxWrite int64) string {
+	if billingMode == "PROVISIONED" {
+		return fmt.Sprintf("RCU %d, WCU %d", rcu, wcu)
+	}
+	if maxRead == 0 && maxWrite == 0 {
+		return "on-demand, no request limits"
+	}
+	limit := func(value int64) string {
+		if value <= 0 {
+			return "unlimited"
+		}
+		return fmt.Sprintf("%d", value)
+	}
+	return fmt.Sprintf("on-demand, max read %s, max write %s", limit(maxRead), limit(maxWrite))
+}
+
+// writeIndexes renders the global or local secondary indexes of a table.
+func writeIndexes(w io.Writer, definition *TableDefinition, title string, indexes []IndexDefinition, global bool) {
+	if len(indexes) == 0 {
+		fmt.Fprintf(w, "%s: none\n", title)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, index := range indexes {
+		status := ""
+		if index.Runtime != nil && index.Runtime.Status != "" {
+			status = fmt.Sprintf(" [%s]", index.Runtime.Status)
+			if index.Runtime.Backfilling {
+				status = fmt.Sprintf(" [%s, backfilling]", index.Runtime.Status)
+			}
+		}
+		fmt.Fprintf(w, "  %s%s\n", index.IndexName, status)
+		fmt.Fprintf(w, "    Keys:         %s\n", describeKeySchema(definition, index.KeySchema))
+		fmt.Fprintf(w, "    Projection:   %s\n", describeProjection(index.Projection))
+		if global {
+			fmt.Fprintf(w, "    Throughput:   %s\n", describeThroughput(definition.BillingMode, index.Rcu, index.Wcu, index.MaxReadRequestUnits, index.MaxWriteRequestUnits))
+		}
+		if index.Runtime != nil {
+			fmt.Fprintf(w, "    Items:        %d (%s)\n", index.Runtime.ItemCount, formatBytes(index.Runtime.SizeBytes))
+		}
+	}
+}
+
+// WriteTextReport renders a table definition as a human-readable report.
+func WriteTextReport(w io.Writer, definition *TableDefinition) {
+	runtime := definition.Runtime
+	if runtime == nil {
+		runtime = &TableRuntime{}
+	}
+
+	fmt.Fprintf(w, "Table:                   %s\n", definition.TableName)
+	if runtime.TableArn != "" {
+		fmt.Fprintf(w, "ARN:                     %s\n", runtime.TableArn)
+		fmt.Fprintf(w, "Status:                  %s\n", runtime.Status)
+	}
+	if runtime.CreationDateTime != nil {
+		fmt.Fprintf(w, "Created:                 %s\n", runtime.CreationDateTime.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+	if definition.Runtime != nil {
+		fmt.Fprintf(w, "Items:                   %d (%s)\n", runtime.ItemCount, formatBytes(runtime.SizeBytes))
+	}
+	fmt.Fprintf(w, "Key schema:              %s\n", describeKeySchema(definition, definition.KeySchema))
+
+	attributes := make([]string, 0, len(definition.Attributes))
+	for _, attribute := range definition.Attributes {
+		attributes = append(attributes, fmt.Sprintf("%s (%s)", attribute.Name, attribute.Type))
+	}
+	fmt.Fprintf(w, "Attributes:              %s\n", strings.Join(attributes, ", "))
+
+	fmt.Fprintf(w, "Billing mode:            %s\n", definition.BillingMode)
+	fmt.Fprintf(w, "Throughput:              %s\n", describeThroughput(definition.BillingMode, definition.Rcu, definition.Wcu, definition.MaxReadRequestUnits, definition.MaxWriteRequestUnits))
+	if definition.BillingMode == "PROVISIONED" && definition.Runtime != nil {
+		remaining, _ := client.RemainingCapacityDecreases(runtime.DecreasesToday, runtime.LastDecreaseDateTime, time.Now())
+		fmt.Fprintf(w, "Decreases:               %d today, %d remaining\n", runtime.DecreasesToday, remaining)
+	}
+	fmt.Fprintf(w, "Table class:             %s\n", definition.TableClass)
+	fmt.Fprintf(w, "Deletion protection:     %s\n", enabled(definition.DeletionProtection))
+	fmt.Fprintf(w, "Point-in-time recovery:  %s\n", enabled(definition.PointInTimeRecovery))
+
+	switch {
+	case definition.TimeToLive != nil && definition.TimeToLive.Enabled:
+		fmt.Fprintf(w, "Time to Live:            enabled on %s\n", definition.TimeToLive.AttributeName)
+	case runtime.TimeToLiveStatus != "":
+		fmt.Fprintf(w, "Time to Live:            %s\n", strings.ToLower(runtime.TimeToLiveStatus))
+	default:
+		fmt.Fprintf(w, "Time to Live:            disabled\n")
+	}
+
+	if definition.Stream != nil && definition.Stream.Enabled {
+		fmt.Fprintf(w, "Stream:                  enabled (%s)\n", definition.Stream.ViewType)
+		if runtime.StreamArn != "" {
+			fmt.Fprintf(w, "Stream ARN:              %s\n", runtime.StreamArn)
+			fmt.Fprintf(w, "Stream label:            %s\n", runtime.StreamLabel)
+		}
+	} else {
+		fmt.Fprintf(w, "Stream:                  disabled\n")
+	}
+
+	if definition.Sse == nil {
+		fmt.Fprintf(w, "Encryption:              AWS owned key\n")
+	} else {
+		details := []string{definition.Sse.Type}
+		for _, detail := range []string{definition.Sse.KmsKeyId, runtime.SseStatus} {
+			if detail != "" {
+				details = append(details, detail)
+			}
+		}
+		fmt.Fprintf(w, "Encryption:              %s\n", strings.Join(details, " "))
+	}
+
+	writeIndexes(w, definition, "Global secondary indexes", definition.GlobalSecondaryIndexes, true)
+	writeIndexes(w, definition, "Local secondary indexes", definition.LocalSecondaryIndexes, false)
+
+	if len(definition.Replicas) == 0 {
+		fmt.Fprintf(w, "Replicas: none\n")
+	} else {
+		if runtime.GlobalTableVersion != "" {
+			fmt.Fprintf(w, "Replicas (global table version %s):\n", runtime.GlobalTableVersion)
+		} else {
+			fmt.Fprintf(w, "Replicas:\n")
+		}
+		for _, replica := range definition.Replicas {
+			var details []string
+			for _, detail := range []string{replica.Status, replica.TableClass} {
+				if detail != "" {
+					details = append(details, detail)
+				}
+			}
+			if replica.ReadCapacityOverride != 0 {
+				details = append(details, fmt.Sprintf("RCU %d", replica.ReadCapacityOverride))
+			}
+			fmt.Fprintf(w, "  %s [%s]\n", replica.RegionName, strings.Join(details, ", "))
+		}
+	}
+
+	if len(definition.Tags) == 0 {
+		fmt.Fprintf(w, "Tags: none\n")
+		return
+	}
+	fmt.Fprintf(w, "Tags:\n")
+	keys := make([]string, 0, len(definition.Tags))
+	for key := range definition.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "  %s = %s\n", key, definition.Tags[key])
+	}
+}
+
+// ExecuteDescribe writes the complete configuration of a table to Output.
+// It takes a DynamoDBManager, the table name and the output format (text, json or yaml) as input.
+// The json and yaml output is a table definition file that includes the runtime sections.
+// It returns an error if the table can't be described.
+func ExecuteDescribe(dbmgr *client.DynamoDBManager, tableName string, format string) error {
+	if format != TextFormat && format != JsonFormat && format != YamlFormat {
+		return errors.New(fmt.Sprintf("unsupported output format:%s", format))
+	}
+
+	definition, err := GetTableDefinition(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s due to: %v", tableName, err)
+		return err
+	}
+
+	if format == TextFormat {
+		WriteTextReport(Output, definition)
+		return nil
+	}
+
+	content, err := MarshalDefinition(definition, format)
+	if err != nil {
+		return err
+	}
+	_, err = Output.Write(content)
+	return err
+}
@@ -0,0 +1,255 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// ErrSchemaDiffers is returned by ExecuteDiff when the compared schemas differ.
+var ErrSchemaDiffers = errors.New("schemas differ")
+
+var NewDynamoDBManagerClient = client.CreateNewDynamoDBManagerInRegion
+
+const missingValue = "-"
+
+// Difference is a single setting that differs between two table definitions.
+type Difference struct {
+	Field string
+	Left  string
+	Right string
+}
+
+// DiffTarget is what a table is compared with: a table name, optionally in another profile or region,
+// or a table definition file.
+type DiffTarget struct {
+	TableName string
+	Profile   string
+	Region    string
+	SpecFile  string
+}
+
+// formatKeySchema renders a key schema with the types of its attributes.
+func formatKeySchema(definition *TableDefinition, keySchema KeySchema) string {
+	if keySchema.SortKey == "" {
+		return describeKey(definition, keySchema.PartitionKey)
+	}
+	return fmt.Sprintf("%s, %s", describeKey(definition, keySchema.PartitionKey), describeKey(definition, keySchema.SortKey))
+}
+
+// formatStream renders the stream setting of a table.
+func formatStream(definition *TableDefinition) string {
+	if definition.Stream == nil || !definition.Stream.Enabled {
+		return "disabled"
+	}
+	return definition.Stream.ViewType
+}
+
+// formatTimeToLive renders the Time to Live setting of a table.
+func formatTimeToLive(definition *TableDefinition) string {
+	if definition.TimeToLive == nil || !definition.TimeToLive.Enabled {
+		return "disabled"
+	}
+	return "enabled on " + definition.TimeToLive.AttributeName
+}
+
+// formatSse renders the encryption type of a table. Key ids are account and region specific and not compared.
+func formatSse(definition *TableDefinition) string {
+	if definition.Sse == nil || definition.Sse.Type == "" {
+		return "AWS owned key"
+	}
+	return definition.Sse.Type
+}
+
+// formatReplicas renders the replica regions of a table.
+func formatReplicas(definition *TableDefinition) string {
+	if len(definition.Replicas) == 0 {
+		return missingValue
+	}
+	regions := make([]string, 0, len(definition.Replicas))
+	for _, replica := range definition.Replicas {
+		regions = append(regions, replica.RegionName)
+	}
+	sort.Strings(regions)
+	return strings.Join(regions, ", ")
+}
+
+// indexNames returns the sorted names of the indexes of both definitions.
+func indexNames(left []IndexDefinition, right []IndexDefinition) []string {
+	set := make(map[string]bool)
+	for _, indexes := range [][]IndexDefinition{left, right} {
+		for _, index := range indexes {
+			set[index.IndexName] = true
+		}
+	}
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DiffDefinitions compares two table definitions, ignoring their names and runtime sections.
+// It returns the differences in keys, attributes, capacity, indexes, projections and features, and in tags if requested.
+// Tags reserved by AWS are never compared.
+func DiffDefinitions(left *TableDefinition, right *TableDefinition, includeTags bool) []Difference {
+	var diffs []Difference
+	add := func(field string, leftValue string, rightValue string) {
+		if leftValue != rightValue {
+			diffs = append(diffs, Difference{field, leftValue, rightValue})
+		}
+	}
+
+	add("keySchema", formatKeySchema(left, left.KeySchema), formatKeySchema(right, right.KeySchema))
+	add("billingMode", left.BillingMode, right.BillingMode)
+	add("throughput", describeThroughput(left.BillingMode, left.Rcu, left.Wcu, left.MaxReadRequestUnits, left.MaxWriteRequestUnits),
+		describeThroughput(right.BillingMode, right.Rcu, right.Wcu, right.MaxReadRequestUnits, right.MaxWriteRequestUnits))
+	add("tableClass", left.TableClass, right.TableClass)
+	add("deletionProtection", enabled(left.DeletionProtection), enabled(right.DeletionProtection))
+	add("pointInTimeRecovery", enabled(left.PointInTimeRecovery), enabled(right.PointInTimeRecovery))
+	add("timeToLive", formatTimeToLive(left), formatTimeToLive(right))
+	add("stream", formatStream(left), formatStream(right))
+	add("sse", formatSse(left), formatSse(right))
+	add("replicas", formatReplicas(left), formatReplicas(right))
+
+	for _, kind := range []string{"gsi", "lsi"} {
+		leftIndexes, rightIndexes := left.GlobalSecondaryIndexes, right.GlobalSecondaryIndexes
+		if kind == "lsi" {
+			leftIndexes, rightIndexes = left.LocalSecondaryIndexes, right.LocalSecondaryIndexes
+		}
+		for _, name := range indexNames(leftIndexes, rightIndexes) {
+			leftIndex, rightIndex := left.Index(name), right.Index(name)
+			if leftIndex == nil || rightIndex == nil {
+				add(kind+":"+name, presence(leftIndex != nil), presence(rightIndex != nil))
+				continue
+			}
+			field := kind + ":" + name + "."
+			add(field+"keySchema", formatKeySchema(left, leftIndex.KeySchema), formatKeySchema(right, rightIndex.KeySchema))
+			add(field+"projection", describeProjection(leftIndex.Projection), describeProjection(rightIndex.Projection))
+			if kind == "gsi" {
+				add(field+"throughput", describeThroughput(left.BillingMode, leftIndex.Rcu, leftIndex.Wcu, leftIndex.MaxReadRequestUnits, leftIndex.MaxWriteRequestUnits),
+					describeThroughput(right.BillingMode, rightIndex.Rcu, rightIndex.Wcu, rightIndex.MaxReadRequestUnits, rightIndex.MaxWriteRequestUnits))
+			}
+		}
+	}
+
+	if includeTags {
+		keys := make(map[string]bool)
+		for _, tags := range []map[string]string{left.Tags, right.Tags} {
+			for key := range tags {
+				if strings.HasPrefix(key, "aws:") {
+					continue
+				}
+				keys[key] = true
+			}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+		for _, key := range sortedKeys {
+			add("tag:"+key, tagOrMissing(left.Tags, key), tagOrMissing(right.Tags, key))
+		}
+	}
+	return diffs
+}
+
+// presence renders whether an index exists.
+func presence(exists bool) string {
+	if exists {
+		return "present"
+	}
+	return missingValue
+}
+
+// tagOrMissing renders the value of a tag, or "-" if the tag isn't set.
+func tagOrMissing(tags map[string]string, key string) string {
+	if value, exists := tags[key]; exists {
+		return value
+	}
+	return missingValue
+}
+
+// describeSource renders where a table lives.
+func describeSource(dbmgr *client.DynamoDBManager, tableName string) string {
+	profile := dbmgr.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	return fmt.Sprintf("table:%s (profile %s, region %s)", tableName, profile, dbmgr.AwsConfig.Region)
+}
+
+// ExecuteDiff compares the schema of a table with another table, possibly in another profile or region,
+// or with a table definition file, and writes the differences to Output.
+// It takes a DynamoDBManager, the table name, the diff target and whether tags are compared as input.
+// It returns ErrSchemaDiffers if any difference was found, or another error if the comparison couldn't be done.
+func ExecuteDiff(dbmgr *client.DynamoDBManager, tableName string, target DiffTarget, includeTags bool) error {
+	left, err := GetTableDefinition(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s due to: %v", tableName, err)
+		return err
+	}
+
+	var right *TableDefinition
+	var rightSource string
+	if target.SpecFile != "" {
+		right, err = LoadDefinition(target.SpecFile)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to load table definition due to: %v", err)
+			return err
+		}
+		if err := right.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("invalid table definition file:%s - error:%v", target.SpecFile, err))
+		}
+		rightSource = "file:" + target.SpecFile
+	} else {
+		otherName := target.TableName
+		if otherName == "" {
+			otherName = tableName
+		}
+		other := dbmgr
+		if target.Profile != "" || target.Region != "" {
+			profile := target.Profile
+			if profile == "" {
+				profile = dbmgr.Profile
+			}
+			other, err = NewDynamoDBManagerClient(profile, target.Region)
+			if err != nil {
+				dbmgr.Logger.Errorf("Failed to create DynamoDB client for profile:%s region:%s due to: %v", profile, target.Region, err)
+				return err
+			}
+			other.Logger = dbmgr.Logger
+		}
+		right, err = GetTableDefinition(other, otherName)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to describe table:%s due to: %v", otherName, err)
+			return err
+		}
+		rightSource = describeSource(other, otherName)
+	}
+	leftSource := describeSource(dbmgr, tableName)
+
+	diffs := DiffDefinitions(left, right, includeTags)
+	if len(diffs) == 0 {
+		dbmgr.Logger.Infof("Schema of %s matches %s", leftSource, rightSource)
+		return nil
+	}
+
+	fmt.Fprintf(Output, "Differences between %s and %s:\n", leftSource, rightSource)
+	writer := tabwriter.NewWriter(Output, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "FIELD\tLEFT\tRIGHT\n")
+	for _, diff := range diffs {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", diff.Field, diff.Left, diff.Right)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	dbmgr.Logger.Warnf("Found %d schema differences", len(diffs))
+	return ErrSchemaDiffers
+}
@@ -0,0 +1,77 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// Portable returns a copy of a table definition without the runtime sections, the replica status and the tags
+// reserved by AWS, so that it can be used to create the table in another account or region.
+func (d *TableDefinition) Portable() *TableDefinition {
+	portable := *d
+	portable.Runtime = nil
+
+	portable.GlobalSecondaryIndexes = nil
+	for _, index := range d.GlobalSecondaryIndexes {
+		index.Runtime = nil
+		portable.GlobalSecondaryIndexes = append(portable.GlobalSecondaryIndexes, index)
+	}
+	portable.LocalSecondaryIndexes = nil
+	for _, index := range d.LocalSecondaryIndexes {
+		index.Runtime = nil
+		portable.LocalSecondaryIndexes = append(portable.LocalSecondaryIndexes, index)
+	}
+
+	portable.Replicas = nil
+	for _, replica := range d.Replicas {
+		replica.Status = ""
+		portable.Replicas = append(portable.Replicas, replica)
+	}
+
+	portable.Tags = nil
+	for key, value := range d.Tags {
+		if strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		if portable.Tags == nil {
+			portable.Tags = make(map[string]string)
+		}
+		portable.Tags[key] = value
+	}
+	return &portable
+}
+
+// ExecuteExport writes the portable definition of a table, the spec format create accepts, to Output or to a file.
+// It takes a DynamoDBManager, the table name, the output format (json or yaml) and an optional file path as input.
+// It returns an error if the table can't be described or the definition can't be written.
+func ExecuteExport(dbmgr *client.DynamoDBManager, tableName string, format string, file string) error {
+	if format != JsonFormat && format != YamlFormat {
+		return errors.New(fmt.Sprintf("unsupported output format:%s", format))
+	}
+
+	definition, err := GetTableDefinition(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to export table:%s due to: %v", tableName, err)
+		return err
+	}
+
+	content, err := MarshalDefinition(definition.Portable(), format)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		_, err = Output.Write(content)
+		return err
+	}
+
+	if err := os.WriteFile(file, content, 0644); err != nil {
+		dbmgr.Logger.Errorf("Failed to write table definition file:%s due to: %v", file, err)
+		return err
+	}
+	dbmgr.Logger.Infof("Exported the definition of table:%s to:%s", tableName, file)
+	return nil
+}
@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// IndexWaitTimeout bounds how long index add waits for a new index to be backfilled and ACTIVE.
+var IndexWaitTimeout = 6 * time.Hour
+
+var (
+	CreateIndexClient        = client.CreateGlobalSecondaryIndex
+	DeleteIndexClient        = client.DeleteGlobalSecondaryIndex
+	WaitForIndexActiveClient = client.WaitForIndexActive
+	CheckPolicyClient        = client.CheckDestructiveOperation
+	ConfirmClient            = client.Confirm
+)
+
+
+// IndexSpec describes a global secondary index to add, in the format of the globalSecondaryIndexes entries of a
+// table definition, together with the definitions of its key attributes the table doesn't have yet.
+type IndexSpec struct {
+	IndexDefinition `yaml:",inline"`
+	Attributes      []Attribute `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// LoadIndexSpec reads an index spec file, parsed as JSON for a .json extension and as YAML otherwise.
+// It returns the IndexSpec and an error.
+func LoadIndexSpec(path string) (*IndexSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec IndexSpec
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &spec)
+	} else {
+		err = yaml.Unmarshal(content, &spec)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse index spec file:%s - error:%v", path, err))
+	}
+	spec.Runtime = nil
+	return &spec, nil
+}
+
+// addIndex adds the index of a spec and its key attributes to the definition of a table and validates the result.
+// It returns an error if the index already exists, redefines an attribute type or isn't valid for the table.
+func (d *TableDefinition) addIndex(spec *IndexSpec) error {
+	if d.Index(spec.IndexName) != nil {
+		return errors.New(fmt.Sprintf("table:%s already has an index:%s", d.TableName, spec.IndexName))
+	}
+	for _, attribute := range spec.Attributes {
+		switch d.AttributeType(attribute.Name) {
+		case "":
+			d.Attributes = append(d.Attributes, attribute)
+		case attribute.Type:
+		default:
+			return errors.New(fmt.Sprintf("attribute:%s is already defined with type:%s on table:%s", attribute.Name, d.AttributeType(attribute.Name), d.TableName))
+		}
+	}
+	d.GlobalSecondaryIndexes = append(d.GlobalSecondaryIndexes, spec.IndexDefinition)
+	return d.Validate()
+}
+
+// ExecuteIndexList writes the global and local secondary indexes of a table with their status, backfilling,
+// key schema, projection, throughput and size to Output.
+// It takes a DynamoDBManager and the table name as input.
+// It returns an error if the table can't be described.
+func ExecuteIndexList(dbmgr *client.DynamoDBManager, tableName string) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s due to: %v", tableName, err)
+		return err
+	}
+
+	definition := DefinitionFromDescription(table, nil, nil, nil)
+	fmt.Fprintf(Output, "Table: %s (%s)\n", tableName, definition.BillingMode)
+	writeIndexes(Output, definition, "Global secondary indexes", definition.GlobalSecondaryIndexes, true)
+	writeIndexes(Output, definition, "Local secondary indexes", definition.LocalSecondaryIndexes, false)
+	return nil
+}
+
+// ExecuteIndexAdd adds a global secondary index from an index spec file to a table, validating it against
+// the table first. With wait it tracks the backfill until the index is ACTIVE.
+// It takes a DynamoDBManager, the table name, the index spec file and a wait flag as input.
+// It returns an error if the spec isn't valid for the table or the index can't be created.
+func ExecuteIndexAdd(dbmgr *client.DynamoDBManager, tableName string, specFile string, wait bool) error {
+	spec, err := LoadIndexSpec(specFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load index spec due to: %v", err)
+		return err
+	}
+
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s due to: %v", tableName, err)
+		return err
+	}
+	if table.TableStatus != types.TableStatusActive {
+		return errors.New(fmt.Sprintf("Refused to add index:%s to table:%s : its status is %s, not ACTIVE", spec.IndexName, tableName, table.TableStatus))
+	}
+
+	definition := DefinitionFromDescription(table, nil, nil, nil).Portable()
+	if err := definition.addIndex(spec); err != nil {
+		return errors.New(fmt.Sprintf("invalid index spec file:%s - error:%v", specFile, err))
+	}
+
+	input := definition.CreateTableInput()
+	var index types.GlobalSecondaryIndex
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		if aws.ToString(gsi.IndexName) == spec.IndexName {
+			index = gsi
+		}
+	}
+	dbmgr.Logger.Infof("Adding index:%s to table:%s - keys: %s - projection: %s - throughput: %s", spec.IndexName, tableName,
+		describeKeySchema(definition, spec.KeySchema), describeProjection(spec.Projection),
+		describeThroughput(definition.BillingMode, spec.Rcu, spec.Wcu, spec.MaxReadRequestUnits, spec.MaxWriteRequestUnits))
+	if err := CreateIndexClient(dbmgr, tableName, input.AttributeDefinitions, index); err != nil {
+		return err
+	}
+
+	if !wait {
+		dbmgr.Logger.Infof("Index:%s is backfilled in the background, follow it with: index list %s", spec.IndexName, tableName)
+		return nil
+	}
+	if err := WaitForIndexActiveClient(dbmgr, tableName, spec.IndexName, IndexWaitTimeout); err != nil {
+		return err
+	}
+	dbmgr.Logger.Infof("Index:%s of table:%s is ACTIVE", spec.IndexName, tableName)
+	return nil
+}
+
+// ExecuteIndexDelete deletes a global secondary index of a table after typed confirmation of the index name.
+// Indexes of tables the deletion protection policy doesn't allow it on are refused. With dry-run the index is only logged.
+// It takes a DynamoDBManager, the table name, the index name and a dry-run flag as input.
+// It returns an error if the index doesn't exist, is refused or can't be deleted.
+func ExecuteIndexDelete(dbmgr *client.DynamoDBManager, tableName string, indexName string, dryRun bool) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s due to: %v", tableName, err)
+		return err
+	}
+
+	definition := DefinitionFromDescription(table, nil, nil, nil)
+	index := definition.Index(indexName)
+	if index == nil {
+		return errors.New(fmt.Sprintf("table:%s has no index:%s", tableName, indexName))
+	}
+	for _, lsi := range definition.LocalSecondaryIndexes {
+		if lsi.IndexName == indexName {
+			return errors.New(fmt.Sprintf("index:%s of table:%s is a local secondary index, which can't be deleted", indexName, tableName))
+		}
+	}
+
+	if err := CheckPolicyClient(dbmgr, tableName, fmt.Sprintf("delete index:%s of", indexName)); err != nil {
+		dbmgr.Logger.Errorf("%v", err)
+		return err
+	}
+
+	if dryRun {
+		dbmgr.Logger.Infof("Table:%s - would delete index:%s - keys: %s - items:%d - size:%d", tableName, indexName,
+			describeKeySchema(definition, index.KeySchema), index.Runtime.ItemCount, index.Runtime.SizeBytes)
+		return nil
+	}
+
+	if !ConfirmClient(fmt.Sprintf("Type the index name to confirm deleting index:%s of table:%s: ", indexName, tableName), indexName) {
+		return errors.New(fmt.Sprintf("confirmation didn't match the index name, index:%s of table:%s not deleted", indexName, tableName))
+	}
+	return DeleteIndexClient(dbmgr, tableName, indexName)
+}
@@ -0,0 +1,546 @@
+package search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// Output formats of list
+const (
+	TextFormat = "text"
+	CsvFormat  = "csv"
+	JsonFormat = "json"
+)
+
+// TagColumnPrefix prefixes the columns showing the value of a tag, e.g. tag:owner.
+const TagColumnPrefix = "tag:"
+
+var DescribeTableClient = client.DescribeTable
+
+// Output is where the table inventory is written.
+var Output io.Writer = os.Stdout
+
+// DefaultColumns are listed when no columns are chosen.
+var DefaultColumns = []string{"name", "billing", "rcu", "wcu", "items", "size", "status"}
+
+// TableInfo holds what is known about a table while listing it.
+// Tags and the settings of the Lookups are only looked up when a column needs them.
+type TableInfo struct {
+	Name                string
+	Region              string
+	Description         *types.TableDescription
+	Tags                map[string]string
+	PointInTimeRecovery *types.PointInTimeRecoveryDescription
+	TimeToLive          *types.TimeToLiveDescription
+	ConsumedCapacity    *client.ConsumedCapacity
+}
+
+// Column is a column of the table inventory.
+// Numeric columns are compared as numbers when filtering and sorting.
+type Column struct {
+	Header  string
+	Numeric bool
+	Value   func(info *TableInfo) string
+}
+
+// provisionedValue returns a provisioned capacity value, or "" for on-demand tables.
+func provisionedValue(info *TableInfo, read bool) string {
+	throughput := info.Description.ProvisionedThroughput
+	if client.BillingModeOf(info.Description) != "PROVISIONED" || throughput == nil {
+		return ""
+	}
+	if read {
+		return fmt.Sprintf("%d", aws.ToInt64(throughput.ReadCapacityUnits))
+	}
+	return fmt.Sprintf("%d", aws.ToInt64(throughput.WriteCapacityUnits))
+}
+
+// Columns are the columns list can show, filter and sort by, besides the tag columns.
+var Columns = map[string]Column{
+	"name":   {"NAME", false, func(info *TableInfo) string { return info.Name }},
+	"region": {"REGION", false, func(info *TableInfo) string { return info.Region }},
+	"arn":    {"ARN", false, func(info *TableInfo) string { return aws.ToString(info.Description.TableArn) }},
+	"status": {"STATUS", false, func(info *TableInfo) string { return string(info.Description.TableStatus) }},
+	"billing": {"BILLING", false, func(info *TableInfo) string {
+		return client.BillingModeOf(info.Description)
+	}},
+	"rcu":   {"RCU", true, func(info *TableInfo) string { return provisionedValue(info, true) }},
+	"wcu":   {"WCU", true, func(info *TableInfo) string { return provisionedValue(info, false) }},
+	"items": {"ITEMS", true, func(info *TableInfo) string { return fmt.Sprintf("%d", aws.ToInt64(info.Description.ItemCount)) }},
+	"size":  {"SIZE", true, func(info *TableInfo) string { return fmt.Sprintf("%d", aws.ToInt64(info.Description.TableSizeBytes)) }},
+	"class": {"CLASS", false, func(info *TableInfo) string { return client.TableClassOf(info.Description) }},
+	"gsis":  {"GSIS", true, func(info *TableInfo) string { return fmt.Sprintf("%d", len(info.Description.GlobalSecondaryIndexes)) }},
+	"deletion-protection": {"DELETION_PROTECTION", false, func(info *TableInfo) string {
+		return fmt.Sprintf("%t", aws.ToBool(info.Description.DeletionProtectionEnabled))
+	}},
+	"pitr": {"PITR", false, func(info *TableInfo) string {
+		if info.PointInTimeRecovery == nil {
+			return ""
+		}
+		return string(info.PointInTimeRecovery.PointInTimeRecoveryStatus)
+	}},
+	"stream": {"STREAM", false, func(info *TableInfo) string { return client.StreamViewTypeOf(info.Description) }},
+	"stream-arn": {"STREAM_ARN", false, func(info *TableInfo) string {
+		return aws.ToString(info.Description.LatestStreamArn)
+	}},
+	"stream-label": {"STREAM_LABEL", false, func(info *TableInfo) string {
+		return aws.ToString(info.Description.LatestStreamLabel)
+	}},
+	"global": {"GLOBAL", false, func(info *TableInfo) string {
+		return fmt.Sprintf("%t", len(info.Description.Replicas) > 0)
+	}},
+	"replicas": {"REPLICAS", false, func(info *TableInfo) string {
+		return strings.Join(client.ReplicaRegionsOf(info.Description), ",")
+	}},
+	"ttl": {"TTL", false, func(info *TableInfo) string {
+		if info.TimeToLive == nil {
+			return ""
+		}
+		return string(info.TimeToLive.TimeToLiveStatus)
+	}},
+	"ttl-attribute": {"TTL_ATTRIBUTE", false, func(info *TableInfo) string {
+		if info.TimeToLive == nil {
+			return ""
+		}
+		return aws.ToString(info.TimeToLive.AttributeName)
+	}},
+	"created": {"CREATED", false, func(info *TableInfo) string {
+		if info.Description.CreationDateTime == nil {
+			return ""
+		}
+		return info.Description.CreationDateTime.UTC().Format(time.RFC3339)
+	}},
+	"age-days": {"AGE_DAYS", true, func(info *TableInfo) string {
+		if info.Description.CreationDateTime == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", int64(time.Since(*info.Description.CreationDateTime)/(24*time.Hour)))
+	}},
+	"reads": {"READS", true, func(info *TableInfo) string {
+		if info.ConsumedCapacity == nil {
+			return ""
+		}
+		return strconv.FormatFloat(info.ConsumedCapacity.ReadCapacityUnits, 'f', -1, 64)
+	}},
+	"writes": {"WRITES", true, func(info *TableInfo) string {
+		if info.ConsumedCapacity == nil {
+			return ""
+		}
+		return strconv.FormatFloat(info.ConsumedCapacity.WriteCapacityUnits, 'f', -1, 64)
+	}},
+}
+
+// UsageLookback is the window the reads and writes columns sum the consumed capacity over.
+var UsageLookback = 30 * 24 * time.Hour
+
+// UsageSource provides the consumed capacity of the reads and writes columns, CloudWatch unless set.
+var UsageSource client.MetricsSource
+
+// lookupConsumedCapacity sums the capacity a table and its global secondary indexes consumed within UsageLookback.
+func lookupConsumedCapacity(dbmgr *client.DynamoDBManager, info *TableInfo) error {
+	if info.ConsumedCapacity != nil {
+		return nil
+	}
+	if UsageSource == nil {
+		UsageSource = NewMetricsSourceClient(dbmgr)
+	}
+	end := time.Now().Truncate(client.ActivityPeriod)
+	consumed, err := client.GetConsumedCapacity(UsageSource, info.Description, end.Add(-UsageLookback), end, client.ActivityPeriod)
+	if err != nil {
+		return err
+	}
+	info.ConsumedCapacity = &consumed
+	return nil
+}
+
+// lookupTimeToLive fetches the Time to Live settings of a table.
+func lookupTimeToLive(dbmgr *client.DynamoDBManager, info *TableInfo) error {
+	ttl, err := GetTimeToLiveClient(dbmgr, info.Name)
+	info.TimeToLive = ttl
+	return err
+}
+
+// Lookups fetch the settings DescribeTable doesn't return, for the columns showing them.
+var Lookups = map[string]func(dbmgr *client.DynamoDBManager, info *TableInfo) error{
+	"pitr": func(dbmgr *client.DynamoDBManager, info *TableInfo) error {
+		pitr, err := GetContinuousBackupsClient(dbmgr, info.Name)
+		info.PointInTimeRecovery = pitr
+		return err
+	},
+	"ttl":           lookupTimeToLive,
+	"ttl-attribute": lookupTimeToLive,
+	"reads":         lookupConsumedCapacity,
+	"writes":        lookupConsumedCapacity,
+}
+
+// GetColumn looks up a column by name, case-insensitively, including tag:KEY columns.
+// It returns the Column and an error if there is no such column.
+func GetColumn(name string) (Column, error) {
+	if strings.HasPrefix(name, TagColumnPrefix) && len(name) > len(TagColumnPrefix) {
+		key := strings.TrimPrefix(name, TagColumnPrefix)
+		return Column{Header: strings.ToUpper(name), Value: func(info *TableInfo) string { return info.Tags[key] }}, nil
+	}
+
+	column, exists := Columns[strings.ToLower(name)]
+	if !exists {
+		names := make([]string, 0, len(Columns))
+		for columnName := range Columns {
+			names = append(names, columnName)
+		}
+		sort.Strings(names)
+		return Column{}, errors.New(fmt.Sprintf("unknown column:%s - expected one of %s or %sKEY", name, strings.Join(names, ","), TagColumnPrefix))
+	}
+	return column, nil
+}
+
+// Filter operators
+const (
+	Equal          = "="
+	NotEqual       = "!="
+	Greater        = ">"
+	GreaterOrEqual = ">="
+	Less           = "<"
+	LessOrEqual    = "<="
+)
+
+// Filter keeps the tables whose column value compares to Value with Operator.
+// Equality accepts * and ? wildcards and ignores the case.
+type Filter struct {
+	Column   string
+	Operator string
+	Value    string
+
+	column Column
+}
+
+// ParseFilter parses a filter expression such as billing=PROVISIONED, items>0 or tag:owner!=
+// It returns the Filter and an error if the expression or column is not valid.
+func ParseFilter(expression string) (Filter, error) {
+	index := strings.IndexAny(expression, "=!<>")
+	if index <= 0 {
+		return Filter{}, errors.New(fmt.Sprintf("invalid filter:%s - expected COLUMN OPERATOR VALUE", expression))
+	}
+
+	operator := expression[index : index+1]
+	if rest := expression[index:]; strings.HasPrefix(rest, NotEqual) || strings.HasPrefix(rest, GreaterOrEqual) || strings.HasPrefix(rest, LessOrEqual) {
+		operator = rest[:2]
+	}
+	if operator == "!" {
+		return Filter{}, errors.New(fmt.Sprintf("invalid filter:%s - unknown operator", expression))
+	}
+
+	filter := Filter{Column: expression[:index], Operator: operator, Value: expression[index+len(operator):]}
+	column, err := GetColumn(filter.Column)
+	if err != nil {
+		return Filter{}, err
+	}
+	filter.column = column
+
+	// Table classes can be filtered by the names the command line accepts, e.g. class=standard-ia
+	if strings.EqualFold(filter.Column, "class") {
+		if tableClass, err := client.ParseTableClass(filter.Value); err == nil {
+			filter.Value = tableClass
+		}
+	}
+	return filter, nil
+}
+
+// compareValues compares two column values, as numbers for numeric columns when both are numbers.
+// It returns a negative number, zero or a positive number like strings.Compare.
+func compareValues(column Column, a string, b string) int {
+	if column.Numeric {
+		numberA, errA := strconv.ParseFloat(a, 64)
+		numberB, errB := strconv.ParseFloat(b, 64)
+		switch {
+		case errA == nil && errB == nil:
+			if numberA < numberB {
+				return -1
+			} else if numberA > numberB {
+				return 1
+			}
+			return 0
+		case errA == nil:
+			return 1
+		case errB == nil:
+			return -1
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// Matches reports whether a table passes the filter.
+func (f *Filter) Matches(info *TableInfo) bool {
+	value := f.column.Value(info)
+	switch f.Operator {
+	case Equal, NotEqual:
+		equal := compareValues(f.column, value, f.Value) == 0
+		if strings.ContainsAny(f.Value, "*?") {
+			equal, _ = path.Match(strings.ToLower(f.Value), strings.ToLower(value))
+		}
+		return equal == (f.Operator == Equal)
+	case Greater:
+		return value != "" && compareValues(f.column, value, f.Value) > 0
+	case GreaterOrEqual:
+		return value != "" && compareValues(f.column, value, f.Value) >= 0
+	case Less:
+		return value != "" && compareValues(f.column, value, f.Value) < 0
+	case LessOrEqual:
+		return value != "" && compareValues(f.column, value, f.Value) <= 0
+	}
+	return false
+}
+
+// ListOptions selects the columns, filters, order and format of the table inventory.
+type ListOptions struct {
+	Columns []string
+	Filters []string
+	SortBy  string
+	Reverse bool
+	Format  string
+}
+
+// usedColumns returns the names of the columns, filters and the sort column used, filters with their expressions.
+func (o *ListOptions) usedColumns() []string {
+	names := append([]string{o.SortBy}, o.Columns...)
+	return append(names, o.Filters...)
+}
+
+// usesTags reports whether any of the columns or filter expressions needs the tags of the tables.
+func usesTags(names []string) bool {
+	for _, name := range names {
+		if strings.HasPrefix(name, TagColumnPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupColumns runs the Lookups of the columns or filter expressions that need them.
+// It returns an error if a lookup fails.
+func lookupColumns(dbmgr *client.DynamoDBManager, info *TableInfo, names []string) error {
+	done := make(map[string]bool)
+	for _, name := range names {
+		if index := strings.IndexAny(name, "=!<>"); index >= 0 {
+			name = name[:index]
+		}
+		name = strings.ToLower(name)
+		lookup, exists := Lookups[name]
+		if !exists || done[name] {
+			continue
+		}
+		if err := lookup(dbmgr, info); err != nil {
+			return err
+		}
+		done[name] = true
+	}
+	return nil
+}
+
+// GetTableInfo describes a table and looks up its tags and other settings if the given columns or filter expressions need them.
+// It returns the TableInfo and an error.
+func GetTableInfo(dbmgr *client.DynamoDBManager, tableName string, columns []string) (*TableInfo, error) {
+	description, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TableInfo{Name: tableName, Region: dbmgr.AwsConfig.Region, Description: description}
+	if err := lookupColumns(dbmgr, info, columns); err != nil {
+		return nil, err
+	}
+	if usesTags(columns) {
+		tags, err := GetTableTagsClient(dbmgr, aws.ToString(description.TableArn))
+		if err != nil {
+			return nil, err
+		}
+		info.Tags = client.TagsToMap(tags)
+	}
+	return info, nil
+}
+
+// FilterTables keeps the tables found by ExecuteSearch that pass every filter and logs them with the filtered columns.
+// It takes a DynamoDBManager, the matching tables of a search and the filter expressions as input.
+// Tables that can't be described are reported and left out.
+// It returns the tables passing the filters and an error if a filter is not valid.
+func FilterTables(dbmgr *client.DynamoDBManager, matchingTables []map[string]string, expressions []string) ([]map[string]string, error) {
+	filters := make([]Filter, 0, len(expressions))
+	for _, expression := range expressions {
+		filter, err := ParseFilter(expression)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	dbmgr.Logger.Info("Filtered results:")
+	var filteredTables []map[string]string
+	for _, table := range matchingTables {
+		info, err := GetTableInfo(dbmgr, table["Name"], expressions)
+		if err != nil {
+			dbmgr.Logger.Warnf("Describe table:%s, failed due to:%v", table["Name"], err)
+			continue
+		}
+
+		matches := true
+		values := make([]string, 0, len(filters))
+		for i := range filters {
+			matches = matches && filters[i].Matches(info)
+			values = append(values, fmt.Sprintf("%s: %s", filters[i].column.Header, filters[i].column.Value(info)))
+		}
+		if matches {
+			filteredTables = append(filteredTables, table)
+			dbmgr.Logger.Infof("Table Name: %s, %s\n", table["Name"], strings.Join(values, ", "))
+		}
+	}
+
+	dbmgr.Logger.Infof("%d of %d tables match the filters: %s", len(filteredTables), len(matchingTables), strings.Join(expressions, " "))
+	return filteredTables, nil
+}
+
+// writeInventory renders the rows of the inventory in the given format.
+func writeInventory(w io.Writer, format string, names []string, headers []string, rows [][]string) error {
+	switch format {
+	case CsvFormat:
+		writer := csv.NewWriter(w)
+		writer.Write(names)
+		writer.WriteAll(rows)
+		return writer.Error()
+	case JsonFormat:
+		records := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			record := make(map[string]string, len(names))
+			for i, name := range names {
+				record[name] = row[i]
+			}
+			records = append(records, record)
+		}
+		content, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(content, '\n'))
+		return err
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, strings.Join(headers, "\t"))
+		for _, row := range rows {
+			for i := range row {
+				if row[i] == "" {
+					row[i] = "-"
+				}
+			}
+			fmt.Fprintln(writer, strings.Join(row, "\t"))
+		}
+		return writer.Flush()
+	}
+}
+
+// ExecuteList writes an inventory of every table, or of the tables matched by the search conditions, to Output.
+// It takes a DynamoDBManager, a fuzzy table name, a tag value and the ListOptions as input.
+// Tables that can't be described are left out and listed in the returned error instead of being logged,
+// as the log would mix into the csv or json written to Output.
+// It returns an error if the options are not valid, the tables can't be listed or any table couldn't be described.
+func ExecuteList(dbmgr *client.DynamoDBManager, tableFuzzyName string, tagValue string, opts ListOptions) error {
+	if len(opts.Columns) == 0 {
+		opts.Columns = DefaultColumns
+	}
+	if opts.SortBy == "" {
+		opts.SortBy = "name"
+	}
+	if opts.Format != TextFormat && opts.Format != CsvFormat && opts.Format != JsonFormat {
+		return errors.New(fmt.Sprintf("unsupported output format:%s", opts.Format))
+	}
+
+	columns := make([]Column, 0, len(opts.Columns))
+	headers := make([]string, 0, len(opts.Columns))
+	for _, name := range opts.Columns {
+		column, err := GetColumn(name)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, column)
+		headers = append(headers, column.Header)
+	}
+	sortColumn, err := GetColumn(opts.SortBy)
+	if err != nil {
+		return err
+	}
+	filters := make([]Filter, 0, len(opts.Filters))
+	for _, expression := range opts.Filters {
+		filter, err := ParseFilter(expression)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, filter)
+	}
+
+	var tableNames []string
+	if tableFuzzyName == "" && tagValue == "" {
+		tableNames, err = GetTableListClient(dbmgr)
+	} else {
+		tableNames = matchTableNames(dbmgr, tableFuzzyName, tagValue)
+	}
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to list the tables due to: %v", err)
+		return err
+	}
+
+	var infos []*TableInfo
+	var failedTables []string
+	for _, tableName := range tableNames {
+		info, err := GetTableInfo(dbmgr, tableName, opts.usedColumns())
+		if err != nil {
+			failedTables = append(failedTables, fmt.Sprintf("%s (%v)", tableName, err))
+			continue
+		}
+
+		matches := true
+		for i := range filters {
+			matches = matches && filters[i].Matches(info)
+		}
+		if matches {
+			infos = append(infos, info)
+		}
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		order := compareValues(sortColumn, sortColumn.Value(infos[i]), sortColumn.Value(infos[j]))
+		if opts.Reverse {
+			return order > 0
+		}
+		return order < 0
+	})
+
+	rows := make([][]string, 0, len(infos))
+	for _, info := range infos {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			row = append(row, column.Value(info))
+		}
+		rows = append(rows, row)
+	}
+	if err := writeInventory(Output, opts.Format, opts.Columns, headers, rows); err != nil {
+		return err
+	}
+	dbmgr.Logger.Debugf("Listed %d of %d tables", len(infos), len(tableNames))
+
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to describe tables: %s", strings.Join(failedTables, ", ")))
+	}
+	return nil
+}
@@ -1,7 +1,13 @@
 package search
 
 import (
+	"errors"
+	"fmt"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/bazelgo/dynamodb-manager/client"
 	"github.com/texttheater/golang-levenshtein/levenshtein"
@@ -13,6 +19,10 @@ var (
 	GetTableListClient = client.GetTableList
 	GetTableArnClient  = client.GetTableArn
 	GetTableTagsClient = client.GetTableTags
+
+	GetContinuousBackupsClient = client.GetContinuousBackups
+	GetTimeToLiveClient        = client.GetTimeToLive
+	NewMetricsSourceClient     = client.NewCloudWatchMetrics
 )
 
 // NormalizeRatio normalizes the fuzzy ratio to be between 0 and 100.
@@ -53,7 +63,7 @@ func searchTablesByFuzzyName(dbmgr *client.DynamoDBManager, fuzzyName string) []
 	}
 
 	// Perform fuzzy search and filter matching tables
-	dbmgr.Logger.Info("searchTablesByFuzzyName before")
+	dbmgr.Logger.Debug("searchTablesByFuzzyName before")
 	matchingTables := make([]map[string]string, 0)
 	for _, tableName := range tableList {
 		if !strings.Contains(tableName, fuzzyName) {
@@ -64,13 +74,8 @@ func searchTablesByFuzzyName(dbmgr *client.DynamoDBManager, fuzzyName string) []
 				continue
 			}
 		}
-		tableArn, err := GetTableArnClient(dbmgr, tableName)
-		if err != nil {
-			dbmgr.Logger.Warnf("Error getting table ARN: %v", err)
-			continue
-		}
-		dbmgr.Logger.Infof("searchTablesByFuzzyName: fuzzyname:%s - tablename:%s - tableArn: %s\n", strings.ToLower(fuzzyName), strings.ToLower(tableName), tableArn)
-		matchingTables = append(matchingTables, map[string]string{"Name": tableName, "ARN": tableArn})
+		dbmgr.Logger.Debugf("searchTablesByFuzzyName: fuzzyname:%s - tablename:%s\n", strings.ToLower(fuzzyName), strings.ToLower(tableName))
+		matchingTables = append(matchingTables, map[string]string{"Name": tableName})
 
 	}
 	return matchingTables
@@ -96,7 +101,7 @@ func searchTablesByTagValue(dbmgr *client.DynamoDBManager, tagValue string, tabl
 
 	// Iterate over the tableList and check tags
 	for _, tableName := range tableListTag {
-		dbmgr.Logger.Infof("Check the tags of table name: %s\n", tableName)
+		dbmgr.Logger.Debugf("Check the tags of table name: %s\n", tableName)
 		tableArn, err := GetTableArnClient(dbmgr, tableName)
 		if err != nil {
 			dbmgr.Logger.Warnf("Error getting table ARN: %v", err)
@@ -121,6 +126,58 @@ func searchTablesByTagValue(dbmgr *client.DynamoDBManager, tagValue string, tabl
 	return matchingTables
 }
 
+// remainingDecreases renders the number of provisioned capacity decreases a table has left for today.
+// On-demand tables have no decrease budget and are reported with "n/a".
+func remainingDecreases(description *types.TableDescription) string {
+	if client.BillingModeOf(description) == string(types.BillingModePayPerRequest) || description.ProvisionedThroughput == nil {
+		return "n/a"
+	}
+	throughput := description.ProvisionedThroughput
+	remaining, _ := client.RemainingCapacityDecreases(aws.ToInt64(throughput.NumberOfDecreasesToday), throughput.LastDecreaseDateTime, time.Now())
+	return fmt.Sprintf("%d", remaining)
+}
+
+// addPitrStatus adds the point-in-time recovery status to every matching table.
+// Tables whose status can't be retrieved are reported with "unknown".
+func addPitrStatus(dbmgr *client.DynamoDBManager, matchingTables []map[string]string) {
+	for _, table := range matchingTables {
+		pitr, err := GetContinuousBackupsClient(dbmgr, table["Name"])
+		if err != nil {
+			dbmgr.Logger.Warnf("Get point-in-time recovery status for table:%s, failed due to:%v", table["Name"], err)
+			table["PITR"] = "unknown"
+			continue
+		}
+		table["PITR"] = string(pitr.PointInTimeRecoveryStatus)
+	}
+}
+
+// addTableDetails describes every matching table once and adds its ARN, the capacity decreases left for today,
+// the stream view type with the ARN and label of an enabled stream, and whether the table is a global table
+// with the regions of its replicas. Tables that can't be described are reported with "unknown".
+func addTableDetails(dbmgr *client.DynamoDBManager, matchingTables []map[string]string) {
+	for _, table := range matchingTables {
+		description, err := DescribeTableClient(dbmgr, table["Name"])
+		if err != nil {
+			dbmgr.Logger.Warnf("Describe table:%s, failed due to:%v", table["Name"], err)
+			table["RemainingDecreases"] = "unknown"
+			table["Stream"] = "unknown"
+			table["Global"] = "unknown"
+			continue
+		}
+		table["ARN"] = aws.ToString(description.TableArn)
+		table["RemainingDecreases"] = remainingDecreases(description)
+		table["Global"] = "no"
+		if regions := client.ReplicaRegionsOf(description); len(regions) > 0 {
+			table["Global"] = fmt.Sprintf("yes (%s)", strings.Join(regions, ","))
+		}
+		table["Stream"] = client.StreamViewTypeOf(description)
+		if table["Stream"] != "DISABLED" {
+			table["StreamArn"] = aws.ToString(description.LatestStreamArn)
+			table["StreamLabel"] = aws.ToString(description.LatestStreamLabel)
+		}
+	}
+}
+
 // ExecuteSearch performs a search operation based on the provided conditions such as fuzzy table name and tag value.
 // It takes a DynamoDBManager, a fuzzy table name, and a tag value as input and returns a slice of matching tables.
 func ExecuteSearch(dbmgr *client.DynamoDBManager, tableFuzzyName string, tagValue string) []map[string]string {
@@ -152,10 +209,57 @@ func ExecuteSearch(dbmgr *client.DynamoDBManager, tableFuzzyName string, tagValu
 		dbmgr.Logger.Warnf("Empty search results - please check the search conditions, tableFuzzyName:%s - tagValue:%s", tableFuzzyName, tagValue)
 	}
 
+	addTableDetails(dbmgr, matchingTables)
+	addPitrStatus(dbmgr, matchingTables)
+
 	dbmgr.Logger.Info("Search results:")
 	for _, table := range matchingTables {
-		dbmgr.Logger.Infof("Table Name: %s, ARN: %s\n", table["Name"], table["ARN"])
+		stream := table["Stream"]
+		if table["StreamArn"] != "" {
+			stream = fmt.Sprintf("%s (ARN: %s, Label: %s)", stream, table["StreamArn"], table["StreamLabel"])
+		}
+		dbmgr.Logger.Infof("Table Name: %s, ARN: %s, Remaining Decreases: %s, PITR: %s, Stream: %s, Global: %s\n", table["Name"], table["ARN"], table["RemainingDecreases"], table["PITR"], stream, table["Global"])
 	}
 
 	return matchingTables
 }
+
+// matchTableNames selects the tables matching a fuzzy table name and a tag value like ExecuteSearch, but without
+// logging the results or retrieving the details of every match, which only the search command shows.
+// It returns the names of the matching tables.
+func matchTableNames(dbmgr *client.DynamoDBManager, tableFuzzyName string, tagValue string) []string {
+	var candidates []string
+	if tableFuzzyName != "" {
+		for _, table := range searchTablesByFuzzyName(dbmgr, tableFuzzyName) {
+			candidates = append(candidates, table["Name"])
+		}
+		if tagValue == "" || len(candidates) == 0 {
+			return candidates
+		}
+	}
+
+	var tableNames []string
+	for _, table := range searchTablesByTagValue(dbmgr, tagValue, candidates) {
+		tableNames = append(tableNames, table["Name"])
+	}
+	return tableNames
+}
+
+// ResolveTables returns the tables an operation should act on: the given table names if any,
+// otherwise the tables matching the fuzzy table name and tag value.
+// It returns the table names and an error if neither names nor search conditions are given.
+func ResolveTables(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) ([]string, error) {
+	if len(tableNames) > 0 {
+		return tableNames, nil
+	}
+
+	if tableFuzzyName == "" && tagValue == "" {
+		return nil, errors.New("no table names or search conditions provided")
+	}
+
+	matchingNames := matchTableNames(dbmgr, tableFuzzyName, tagValue)
+	if len(matchingNames) == 0 {
+		dbmgr.Logger.Warnf("Empty search results - please check the search conditions, tableFuzzyName:%s - tagValue:%s", tableFuzzyName, tagValue)
+	}
+	return matchingNames, nil
+}
@@ -0,0 +1,274 @@
+package tags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// ErrNonCompliant is returned by ExecuteAudit when tables violate the tag policy.
+var ErrNonCompliant = errors.New("tag policy violations found")
+
+// Case rules for tag values
+const (
+	AnyCase   = ""
+	LowerCase = "lower"
+	UpperCase = "upper"
+)
+
+var GetTableListClient = client.GetTableList
+
+// TagRule describes the requirements for a single tag key.
+// Default is written by the audit fix for a missing required tag.
+type TagRule struct {
+	Key           string   `json:"key" yaml:"key"`
+	Required      bool     `json:"required" yaml:"required"`
+	AllowedValues []string `json:"allowedValues,omitempty" yaml:"allowedValues,omitempty"`
+	Pattern       string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Case          string   `json:"case,omitempty" yaml:"case,omitempty"`
+	Default       string   `json:"default,omitempty" yaml:"default,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// TagPolicy is the set of tag rules every table must comply with.
+type TagPolicy struct {
+	Rules []TagRule `json:"rules" yaml:"rules"`
+}
+
+// Violation is a single way a table breaks a tag rule.
+type Violation struct {
+	TableName string
+	Key       string
+	Message   string
+}
+
+// LoadPolicy reads a tag policy file, parsed as JSON for a .json extension and as YAML otherwise.
+// It returns the validated TagPolicy and an error.
+func LoadPolicy(path string) (*TagPolicy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy TagPolicy
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &policy)
+	} else {
+		err = yaml.Unmarshal(content, &policy)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse policy file:%s - error:%v", path, err))
+	}
+
+	if len(policy.Rules) == 0 {
+		return nil, errors.New(fmt.Sprintf("invalid policy file:%s - no rules", path))
+	}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if rule.Key == "" {
+			return nil, errors.New(fmt.Sprintf("invalid policy file:%s - rules[%d] has no key", path, i))
+		}
+		if rule.Case != AnyCase && rule.Case != LowerCase && rule.Case != UpperCase {
+			return nil, errors.New(fmt.Sprintf("invalid policy file:%s - rules[%d] has unsupported case:%s", path, i, rule.Case))
+		}
+		if rule.Pattern != "" {
+			rule.pattern, err = regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid policy file:%s - rules[%d] has invalid pattern:%v", path, i, err))
+			}
+		}
+		if rule.Default != "" && len(rule.checkValue(rule.Default)) > 0 {
+			return nil, errors.New(fmt.Sprintf("invalid policy file:%s - rules[%d] default:%s violates the rule", path, i, rule.Default))
+		}
+	}
+	return &policy, nil
+}
+
+// checkValue checks a tag value against the allowed values, pattern and case rule.
+// It returns a message for every violated requirement.
+func (r *TagRule) checkValue(value string) []string {
+	var messages []string
+	if len(r.AllowedValues) > 0 {
+		allowed := false
+		for _, allowedValue := range r.AllowedValues {
+			allowed = allowed || value == allowedValue
+		}
+		if !allowed {
+			messages = append(messages, fmt.Sprintf("value:%s is not one of %v", value, r.AllowedValues))
+		}
+	}
+	if r.pattern != nil && !r.pattern.MatchString(value) {
+		messages = append(messages, fmt.Sprintf("value:%s does not match %s", value, r.Pattern))
+	}
+	if fixed := r.fixCase(value); fixed != value {
+		messages = append(messages, fmt.Sprintf("value:%s is not %s case", value, r.Case))
+	}
+	return messages
+}
+
+// fixCase converts a value to the case the rule requires.
+func (r *TagRule) fixCase(value string) string {
+	switch r.Case {
+	case LowerCase:
+		return strings.ToLower(value)
+	case UpperCase:
+		return strings.ToUpper(value)
+	default:
+		return value
+	}
+}
+
+// findKeyIgnoringCase returns the key of a tag matching the rule key in another case, or "" if there is none.
+func findKeyIgnoringCase(tags map[string]string, key string) string {
+	for tagKey := range tags {
+		if tagKey != key && strings.EqualFold(tagKey, key) {
+			return tagKey
+		}
+	}
+	return ""
+}
+
+// AuditTags checks the tags of a table against a policy.
+// It returns the violations and the tag changes (writes and deletions) that fix those the policy can repair:
+// missing tags with a default, keys in the wrong case, and values in the wrong case.
+func AuditTags(policy *TagPolicy, tableName string, tags map[string]string) ([]Violation, []types.Tag, []string) {
+	var violations []Violation
+	var toTag []types.Tag
+	var toUntag []string
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		value, exists := tags[rule.Key]
+		if !exists {
+			if otherKey := findKeyIgnoringCase(tags, rule.Key); otherKey != "" {
+				violations = append(violations, Violation{tableName, rule.Key, fmt.Sprintf("key is spelled %s", otherKey)})
+				value, exists = tags[otherKey], true
+				toUntag = append(toUntag, otherKey)
+				toTag = append(toTag, types.Tag{Key: aws.String(rule.Key), Value: aws.String(rule.fixCase(value))})
+			}
+		}
+
+		if !exists {
+			if rule.Required {
+				violations = append(violations, Violation{tableName, rule.Key, "required tag is missing"})
+				if rule.Default != "" {
+					toTag = append(toTag, types.Tag{Key: aws.String(rule.Key), Value: aws.String(rule.Default)})
+				}
+			}
+			continue
+		}
+
+		messages := rule.checkValue(value)
+		for _, message := range messages {
+			violations = append(violations, Violation{tableName, rule.Key, message})
+		}
+		if len(messages) > 0 && tags[rule.Key] == value && len(rule.checkValue(rule.fixCase(value))) == 0 {
+			toTag = append(toTag, types.Tag{Key: aws.String(rule.Key), Value: aws.String(rule.fixCase(value))})
+		}
+	}
+	return violations, toTag, toUntag
+}
+
+// ExecuteAudit checks the tags of the given tables, of the tables matched by the search conditions, or of every table
+// when neither is given, against a tag policy file and logs every violation.
+// It takes a DynamoDBManager, the policy file path, table names, a fuzzy table name, a tag value, a fix flag and
+// a dry-run flag as input.
+// With fix the repairable violations are corrected; together with dry-run the fixes are only logged.
+// It returns ErrNonCompliant if violations remain, or another error if the audit couldn't be done.
+func ExecuteAudit(dbmgr *client.DynamoDBManager, policyFile string, tableNames []string, tableFuzzyName string, tagValue string, fix bool, dryRun bool) error {
+	policy, err := LoadPolicy(policyFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to load tag policy due to: %v", err)
+		return err
+	}
+
+	if len(tableNames) == 0 && tableFuzzyName == "" && tagValue == "" {
+		tableNames, err = GetTableListClient(dbmgr)
+	} else {
+		tableNames, err = ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	}
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to list the tables to audit due to: %v", err)
+		return err
+	}
+	sort.Strings(tableNames)
+
+	nonCompliant, fixed := 0, 0
+	for _, tableName := range tableNames {
+		tableArn, err := GetTableArnClient(dbmgr, tableName)
+		if err != nil {
+			return err
+		}
+		currentTags, err := GetTableTagsClient(dbmgr, tableArn)
+		if err != nil {
+			return err
+		}
+
+		violations, toTag, toUntag := AuditTags(policy, tableName, client.TagsToMap(currentTags))
+		if len(violations) == 0 {
+			dbmgr.Logger.Debugf("Table:%s complies with the tag policy", tableName)
+			continue
+		}
+		for _, violation := range violations {
+			dbmgr.Logger.Warnf("Table:%s - tag:%s - %s", violation.TableName, violation.Key, violation.Message)
+		}
+
+		if !fix || (len(toTag) == 0 && len(toUntag) == 0) {
+			nonCompliant++
+			continue
+		}
+
+		current := client.TagsToMap(currentTags)
+		if dryRun {
+			dbmgr.Logger.Infof("Table:%s - would fix tags: %s", tableName, describeTagChanges(current, toTag, toUntag))
+			nonCompliant++
+			continue
+		}
+
+		dbmgr.Logger.Infof("Table:%s - fixing tags: %s", tableName, describeTagChanges(current, toTag, toUntag))
+		if err := applyTagChanges(dbmgr, tableArn, toTag, toUntag); err != nil {
+			return err
+		}
+
+		// Count the table as fixed only if nothing is left to repair by hand.
+		remaining, _, _ := AuditTags(policy, tableName, applyToMap(current, toTag, toUntag))
+		if len(remaining) > 0 {
+			nonCompliant++
+		} else {
+			fixed++
+		}
+	}
+
+	dbmgr.Logger.Infof("Audited %d tables: %d non-compliant, %d fixed", len(tableNames), nonCompliant, fixed)
+	if nonCompliant > 0 {
+		return ErrNonCompliant
+	}
+	return nil
+}
+
+// applyToMap returns a copy of the tags with the given writes and deletions applied.
+func applyToMap(tags map[string]string, toTag []types.Tag, toUntag []string) map[string]string {
+	result := make(map[string]string, len(tags))
+	for key, value := range tags {
+		result[key] = value
+	}
+	for _, key := range toUntag {
+		delete(result, key)
+	}
+	for _, tag := range toTag {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
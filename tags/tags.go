@@ -0,0 +1,208 @@
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+// Tag operations
+const (
+	Add       = "add"
+	Remove    = "remove"
+	Set       = "set"
+	RenameKey = "rename-key"
+)
+
+// TagBatchSize is the maximum number of tags sent in a single TagResource or UntagResource call.
+const TagBatchSize = 25
+
+var (
+	GetTableArnClient  = client.GetTableArn
+	GetTableTagsClient = client.GetTableTags
+	TagTableClient     = client.TagTable
+	UntagTableClient   = client.UntagTable
+	ResolveTablesTask  = search.ResolveTables
+	CheckPolicyClient  = client.CheckDestructiveOperation
+)
+
+// TagOperation describes a change to the tags of a table.
+// Add only creates missing keys, Set creates or overwrites keys, Remove deletes Keys
+// and RenameKey moves the value of OldKey to NewKey.
+type TagOperation struct {
+	Action string
+	Tags   map[string]string
+	Keys   []string
+	OldKey string
+	NewKey string
+}
+
+// ParseTagArgs parses KEY=VALUE arguments into a tag map.
+// It returns the tags and an error if an argument has no key.
+func ParseTagArgs(args []string) (map[string]string, error) {
+	tags := make(map[string]string, len(args))
+	for _, arg := range args {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			return nil, errors.New(fmt.Sprintf("invalid tag:%s - expected KEY=VALUE", arg))
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+// planTagChanges calculates the tags to write and the tag keys to delete to apply an operation to the current tags.
+func planTagChanges(op TagOperation, current map[string]string) ([]types.Tag, []string) {
+	var toTag []types.Tag
+	var toUntag []string
+
+	switch op.Action {
+	case Add, Set:
+		keys := make([]string, 0, len(op.Tags))
+		for key := range op.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, exists := current[key]
+			if (op.Action == Add && exists) || (exists && value == op.Tags[key]) {
+				continue
+			}
+			toTag = append(toTag, types.Tag{Key: aws.String(key), Value: aws.String(op.Tags[key])})
+		}
+	case Remove:
+		for _, key := range op.Keys {
+			if _, exists := current[key]; exists {
+				toUntag = append(toUntag, key)
+			}
+		}
+	case RenameKey:
+		if value, exists := current[op.OldKey]; exists {
+			toTag = append(toTag, types.Tag{Key: aws.String(op.NewKey), Value: aws.String(value)})
+			toUntag = append(toUntag, op.OldKey)
+		}
+	}
+	return toTag, toUntag
+}
+
+// applyTagChanges writes and deletes tags in batches of TagBatchSize, writing before deleting
+// so that a renamed key is never lost.
+// It returns an error on the first failed call.
+func applyTagChanges(dbmgr *client.DynamoDBManager, tableArn string, toTag []types.Tag, toUntag []string) error {
+	for start := 0; start < len(toTag); start += TagBatchSize {
+		end := start + TagBatchSize
+		if end > len(toTag) {
+			end = len(toTag)
+		}
+		if err := TagTableClient(dbmgr, tableArn, toTag[start:end]); err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(toUntag); start += TagBatchSize {
+		end := start + TagBatchSize
+		if end > len(toUntag) {
+			end = len(toUntag)
+		}
+		if err := UntagTableClient(dbmgr, tableArn, toUntag[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeTagChanges renders the planned tag changes of a table for the log.
+func describeTagChanges(current map[string]string, toTag []types.Tag, toUntag []string) string {
+	var parts []string
+	for _, tag := range toTag {
+		key := aws.ToString(tag.Key)
+		if value, exists := current[key]; exists {
+			parts = append(parts, fmt.Sprintf("%s: %s -> %s", key, value, aws.ToString(tag.Value)))
+		} else {
+			parts = append(parts, fmt.Sprintf("+%s=%s", key, aws.ToString(tag.Value)))
+		}
+	}
+	for _, key := range toUntag {
+		parts = append(parts, fmt.Sprintf("-%s", key))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ExecuteTag applies a tag operation to the given tables, or to the tables matched by the search conditions
+// when no table names are given, and logs a summary of changed, unchanged and failed tables.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value, the TagOperation and a dry-run flag as input.
+// With dry-run the changes are only logged. Removing tags is refused on tables the deletion protection policy doesn't allow it on.
+// It returns an error if no tables were selected or any table couldn't be tagged.
+func ExecuteTag(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, op TagOperation, dryRun bool) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables to tag due to: %v", err)
+		return err
+	}
+
+	var changedTables, unchangedTables, failedTables []string
+	for _, tableName := range targets {
+		tableArn, err := GetTableArnClient(dbmgr, tableName)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		currentTags, err := GetTableTagsClient(dbmgr, tableArn)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		current := client.TagsToMap(currentTags)
+
+		toTag, toUntag := planTagChanges(op, current)
+		if len(toTag) == 0 && len(toUntag) == 0 {
+			dbmgr.Logger.Infof("Table:%s - tags already up to date", tableName)
+			unchangedTables = append(unchangedTables, tableName)
+			continue
+		}
+
+		if len(toUntag) > 0 {
+			if err := CheckPolicyClient(dbmgr, tableName, "remove tags of"); err != nil {
+				dbmgr.Logger.Errorf("%v", err)
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+		}
+
+		if dryRun {
+			dbmgr.Logger.Infof("Table:%s - would change tags: %s", tableName, describeTagChanges(current, toTag, toUntag))
+			changedTables = append(changedTables, tableName)
+			continue
+		}
+
+		dbmgr.Logger.Infof("Table:%s - changing tags: %s", tableName, describeTagChanges(current, toTag, toUntag))
+		if err := applyTagChanges(dbmgr, tableArn, toTag, toUntag); err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		changedTables = append(changedTables, tableName)
+	}
+
+	verb := "Changed"
+	if dryRun {
+		verb = "Would change"
+	}
+	dbmgr.Logger.Infof("%s tags of %d tables: %s", verb, len(changedTables), strings.Join(changedTables, ","))
+	dbmgr.Logger.Infof("Unchanged tables: %d, failed tables: %d", len(unchangedTables), len(failedTables))
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to tag tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
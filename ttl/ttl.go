@@ -0,0 +1,153 @@
+package ttl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+	"github.com/bazelgo/dynamodb-manager/search"
+)
+
+var (
+	GetTimeToLiveClient    = client.GetTimeToLive
+	UpdateTimeToLiveClient = client.UpdateTimeToLive
+	CheckPolicyClient      = client.CheckDestructiveOperation
+	ResolveTablesTask      = search.ResolveTables
+)
+
+// describeTimeToLive renders the Time to Live settings of a table for the log.
+func describeTimeToLive(ttl *types.TimeToLiveDescription) string {
+	if ttl.AttributeName == nil {
+		return fmt.Sprintf("TTL:%s", ttl.TimeToLiveStatus)
+	}
+	return fmt.Sprintf("TTL:%s - attribute:%s", ttl.TimeToLiveStatus, aws.ToString(ttl.AttributeName))
+}
+
+// ExecuteTtlShow logs the Time to Live status and attribute of the given tables, or of the tables matched by the
+// search conditions.
+// It takes a DynamoDBManager, table names, a fuzzy table name and a tag value as input.
+// It returns an error if the tables can't be selected or their settings can't be retrieved.
+func ExecuteTtlShow(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string) error {
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	for _, tableName := range targets {
+		ttl, err := GetTimeToLiveClient(dbmgr, tableName)
+		if err != nil {
+			return err
+		}
+		dbmgr.Logger.Infof("Table:%s - %s", tableName, describeTimeToLive(ttl))
+	}
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	return nil
+}
+
+// planTimeToLive decides how the Time to Live of a table must change to reach the requested state.
+// Disabling uses the current attribute, enabling on another attribute requires disabling first and
+// DynamoDB refuses any change while one is in progress.
+// It returns the attribute to update, whether an update is needed and an error if the change is not possible.
+func planTimeToLive(ttl *types.TimeToLiveDescription, attributeName string, enabled bool) (string, bool, error) {
+	current := aws.ToString(ttl.AttributeName)
+	switch ttl.TimeToLiveStatus {
+	case types.TimeToLiveStatusEnabling, types.TimeToLiveStatusDisabling:
+		return "", false, errors.New(fmt.Sprintf("a TTL change is in progress, status:%s", ttl.TimeToLiveStatus))
+	case types.TimeToLiveStatusEnabled:
+		if !enabled {
+			return current, true, nil
+		}
+		if current != attributeName {
+			return "", false, errors.New(fmt.Sprintf("TTL is enabled on attribute:%s, disable it before enabling it on attribute:%s", current, attributeName))
+		}
+		return current, false, nil
+	default:
+		return attributeName, enabled, nil
+	}
+}
+
+// ExecuteTtlUpdate enables Time to Live on an attribute, or disables it, on the given tables or on the tables
+// matched by the search conditions, and logs a summary of changed, unchanged and failed tables.
+// It takes a DynamoDBManager, table names, a fuzzy table name, a tag value, the attribute name, the requested
+// state and a dry-run flag as input.
+// With dry-run the changes are only logged. Enabling makes DynamoDB delete the expired items, so it is refused
+// on tables the deletion protection policy doesn't allow it on.
+// It returns an error if no tables were selected or any table couldn't be changed.
+func ExecuteTtlUpdate(dbmgr *client.DynamoDBManager, tableNames []string, tableFuzzyName string, tagValue string, attributeName string, enabled bool, dryRun bool) error {
+	if enabled && attributeName == "" {
+		return errors.New("no TTL attribute provided")
+	}
+
+	targets, err := ResolveTablesTask(dbmgr, tableNames, tableFuzzyName, tagValue)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to select the tables due to: %v", err)
+		return err
+	}
+
+	var changedTables, unchangedTables, failedTables []string
+	for _, tableName := range targets {
+		ttl, err := GetTimeToLiveClient(dbmgr, tableName)
+		if err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+
+		attribute, needed, err := planTimeToLive(ttl, attributeName, enabled)
+		if err != nil {
+			dbmgr.Logger.Errorf("Refused to change TTL of table:%s : %v", tableName, err)
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		if !needed {
+			dbmgr.Logger.Infof("Table:%s - already %s", tableName, describeTimeToLive(ttl))
+			unchangedTables = append(unchangedTables, tableName)
+			continue
+		}
+
+		if enabled {
+			if err := CheckPolicyClient(dbmgr, tableName, "enable TTL, deleting expired items, on"); err != nil {
+				dbmgr.Logger.Errorf("%v", err)
+				failedTables = append(failedTables, tableName)
+				continue
+			}
+		}
+
+		if dryRun {
+			dbmgr.Logger.Infof("Table:%s - would change %s -> enabled:%t attribute:%s", tableName, describeTimeToLive(ttl), enabled, attribute)
+			changedTables = append(changedTables, tableName)
+			continue
+		}
+
+		if enabled {
+			dbmgr.Logger.Warnf("Enabling TTL on table:%s - items whose attribute:%s is in the past will be deleted", tableName, attribute)
+		}
+		if err := UpdateTimeToLiveClient(dbmgr, tableName, attribute, enabled); err != nil {
+			failedTables = append(failedTables, tableName)
+			continue
+		}
+		changedTables = append(changedTables, tableName)
+	}
+
+	verb := "Changed"
+	if dryRun {
+		verb = "Would change"
+	}
+	dbmgr.Logger.Infof("%s TTL of %d tables: %s", verb, len(changedTables), strings.Join(changedTables, ","))
+	dbmgr.Logger.Infof("Unchanged tables: %d, failed tables: %d", len(unchangedTables), len(failedTables))
+
+	if len(targets) == 0 {
+		return errors.New("no tables matched the search conditions")
+	}
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to change TTL of tables: %s", strings.Join(failedTables, ",")))
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// BackupLabel is part of the names of the backups taken before an update.
+const BackupLabel = "before-update"
+
+// BackupWaitTimeout bounds how long an update waits for its backup to become available.
+var BackupWaitTimeout = 30 * time.Minute
+
+var (
+	CreateBackupClient  = client.CreateBackup
+	WaitForBackupClient = client.WaitForBackupAvailable
+)
+
+// backupBeforeUpdate creates an on-demand backup of a table and waits until it is available,
+// so that the table can be restored to its state before the update.
+// It returns an error if the backup can't be created or doesn't become available.
+func backupBeforeUpdate(dbmgr *client.DynamoDBManager, tableName string) error {
+	backup, err := CreateBackupClient(dbmgr, tableName, client.BackupName(tableName, BackupLabel, time.Now()))
+	if err != nil {
+		return errors.New(fmt.Sprintf("Refused to update table:%s : the backup before the update failed due to: %v", tableName, err))
+	}
+
+	backupArn := aws.ToString(backup.BackupArn)
+	dbmgr.Logger.Infof("Waiting for backup:%s of table:%s to become available ...", backupArn, tableName)
+	if err := WaitForBackupClient(dbmgr, backupArn, BackupWaitTimeout); err != nil {
+		return errors.New(fmt.Sprintf("Refused to update table:%s : the backup before the update is not available due to: %v", tableName, err))
+	}
+	return nil
+}
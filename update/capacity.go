@@ -0,0 +1,125 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Kinds of capacity changes accepted by --rcu and --wcu
+const (
+	AbsoluteChange = "absolute" // 100
+	DeltaChange    = "delta"    // +50, -200
+	PercentChange  = "percent"  // +50%, -20%
+	FactorChange   = "factor"   // x2, x0.5
+)
+
+// MinCapacityUnits is the lowest read or write capacity DynamoDB accepts for a provisioned table.
+const MinCapacityUnits = 1
+
+// CapacityChange describes a requested read or write capacity, either absolute or relative to the current value.
+type CapacityChange struct {
+	Kind  string
+	Value float64
+}
+
+// ParseCapacityChange parses a capacity parameter such as "100", "+50", "-200", "+50%" or "x2".
+// It returns the parsed CapacityChange and an error if the parameter is malformed.
+func ParseCapacityChange(param string) (CapacityChange, error) {
+	param = strings.TrimSpace(param)
+	switch {
+	case param == "":
+		return CapacityChange{}, errors.New("empty capacity value")
+	case strings.HasPrefix(param, "x") || strings.HasPrefix(param, "X"):
+		factor, err := strconv.ParseFloat(param[1:], 64)
+		if err != nil || factor <= 0 {
+			return CapacityChange{}, errors.New(fmt.Sprintf("invalid capacity factor:%s", param))
+		}
+		return CapacityChange{Kind: FactorChange, Value: factor}, nil
+	case strings.HasPrefix(param, "+") || strings.HasPrefix(param, "-"):
+		if strings.HasSuffix(param, "%") {
+			percent, err := strconv.ParseFloat(strings.TrimSuffix(param, "%"), 64)
+			if err != nil {
+				return CapacityChange{}, errors.New(fmt.Sprintf("invalid capacity percentage:%s", param))
+			}
+			return CapacityChange{Kind: PercentChange, Value: percent}, nil
+		}
+		delta, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return CapacityChange{}, errors.New(fmt.Sprintf("invalid capacity delta:%s", param))
+		}
+		return CapacityChange{Kind: DeltaChange, Value: float64(delta)}, nil
+	default:
+		absolute, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return CapacityChange{}, errors.New(fmt.Sprintf("invalid capacity value:%s", param))
+		}
+		return CapacityChange{Kind: AbsoluteChange, Value: float64(absolute)}, nil
+	}
+}
+
+// IsRelative reports whether the change depends on the current capacity of the table.
+func (c CapacityChange) IsRelative() bool {
+	return c.Kind != AbsoluteChange
+}
+
+// Apply calculates the new capacity from the current one, rounding relative results to the nearest unit.
+func (c CapacityChange) Apply(current int64) int64 {
+	switch c.Kind {
+	case DeltaChange:
+		return current + int64(c.Value)
+	case PercentChange:
+		return int64(math.Round(float64(current) * (100 + c.Value) / 100))
+	case FactorChange:
+		return int64(math.Round(float64(current) * c.Value))
+	default:
+		return int64(c.Value)
+	}
+}
+
+// ClampCapacity limits a capacity value to [minCapacity, maxCapacity].
+// A minCapacity below MinCapacityUnits is raised to it and a maxCapacity of 0 means no upper limit.
+func ClampCapacity(value int64, minCapacity int64, maxCapacity int64) int64 {
+	if minCapacity < MinCapacityUnits {
+		minCapacity = MinCapacityUnits
+	}
+	if value < minCapacity {
+		return minCapacity
+	}
+	if maxCapacity > 0 && value > maxCapacity {
+		return maxCapacity
+	}
+	return value
+}
+
+// resolveCapacity turns a capacity parameter into the absolute value to provision.
+// An empty parameter keeps the current value of a provisioned table and falls back to defaultValue otherwise.
+// It returns the absolute capacity as a string and an error if a relative change can't be applied.
+func resolveCapacity(param string, current string, defaultValue int64, minCapacity int64, maxCapacity int64) (string, error) {
+	if param == "" {
+		if current != "" {
+			return current, nil
+		}
+		return fmt.Sprintf("%d", defaultValue), nil
+	}
+
+	change, err := ParseCapacityChange(param)
+	if err != nil {
+		return "", err
+	}
+
+	var currentVal int64
+	if change.IsRelative() {
+		if current == "" {
+			return "", errors.New(fmt.Sprintf("relative capacity change:%s requires a provisioned table", param))
+		}
+		currentVal, err = strconv.ParseInt(current, 10, 64)
+		if err != nil {
+			return "", errors.New(fmt.Sprintf("invalid current capacity:%s", current))
+		}
+	}
+
+	return fmt.Sprintf("%d", ClampCapacity(change.Apply(currentVal), minCapacity, maxCapacity)), nil
+}
@@ -0,0 +1,79 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// DynamoDB allows TableClassChangesPerPeriod table class changes of a table within a trailing TableClassChangePeriod.
+const (
+	TableClassChangesPerPeriod = 2
+	TableClassChangePeriod     = 30 * 24 * time.Hour
+)
+
+// UpdateWaitTimeout bounds how long an update waits for a table to become ACTIVE between two UpdateTable calls.
+var UpdateWaitTimeout = 30 * time.Minute
+
+// CheckTableClassChange verifies that a table can be switched to a table class: it must be ACTIVE and must not have
+// used up its class changes within the trailing TableClassChangePeriod. The changes are counted from the journal
+// and changes made without journal may go unnoticed, DescribeTable only reports the last one.
+// It returns whether the table has another class and an error if the change must be refused.
+func CheckTableClassChange(dbmgr *client.DynamoDBManager, tableName string, tableClass string) (bool, error) {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return false, err
+	}
+
+	if client.TableClassOf(table) == tableClass {
+		return false, nil
+	}
+	if table.TableStatus != types.TableStatusActive {
+		return true, errors.New(fmt.Sprintf("Refused to change table class of table:%s : its status is %s, not ACTIVE", tableName, table.TableStatus))
+	}
+
+	since := time.Now().Add(-TableClassChangePeriod)
+	entries, err := ReadJournalClient(dbmgr.JournalFile)
+	if err != nil {
+		dbmgr.Logger.Warnf("Failed to read journal:%s, only the last table class change of table:%s is known: %v", dbmgr.JournalFile, tableName, err)
+	}
+	var journalChanges []time.Time
+	for _, entry := range entries {
+		if entry.TableName == tableName && entry.Before.TableClass != entry.After.TableClass && entry.Timestamp.After(since) {
+			journalChanges = append(journalChanges, entry.Timestamp)
+		}
+	}
+
+	if len(journalChanges) >= TableClassChangesPerPeriod {
+		nextChange := journalChanges[len(journalChanges)-TableClassChangesPerPeriod].Add(TableClassChangePeriod)
+		return true, &cooldownError{fmt.Sprintf("Refused to change table class of table:%s : already changed %d times within %v, next change is allowed after %v",
+			tableName, len(journalChanges), TableClassChangePeriod, nextChange.UTC())}
+	}
+
+	lastChange := table.TableClassSummary != nil && table.TableClassSummary.LastUpdateDateTime != nil && table.TableClassSummary.LastUpdateDateTime.After(since)
+	if len(journalChanges) == TableClassChangesPerPeriod-1 || (len(journalChanges) == 0 && lastChange) {
+		dbmgr.Logger.Warnf("This is the last table class change of table:%s allowed within %v", tableName, TableClassChangePeriod)
+	}
+	return true, nil
+}
+
+// updateTableClass switches a table to a table class after checking the class-change restrictions.
+// It returns an error if the change is refused or fails.
+func updateTableClass(dbmgr *client.DynamoDBManager, tableName string, tableClass string) error {
+	needed, err := CheckTableClassChange(dbmgr, tableName, tableClass)
+	if err != nil {
+		dbmgr.Logger.Errorf("%v", err)
+		return err
+	}
+	if !needed {
+		dbmgr.Logger.Warnf("No need to change the table class, table:%s already is %s!", tableName, tableClass)
+		return nil
+	}
+
+	dbmgr.Logger.Infof("Table:%s - tableClass -> %s", tableName, tableClass)
+	return UpdateTableClassClient(dbmgr, tableName, tableClass)
+}
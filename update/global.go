@@ -0,0 +1,54 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// checkGlobalTable checks an update of a global table before any change is made. DynamoDB propagates the billing
+// mode and the write capacity to every replica, so the update is refused while a replica isn't ACTIVE, and global
+// tables need a NEW_AND_OLD_IMAGES stream to replicate. Regional tables are not checked.
+// It returns an error if the table can't be described or the update would break the replication.
+func checkGlobalTable(dbmgr *client.DynamoDBManager, tableName string, opts UpdateOptions) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+	regions := client.ReplicaRegionsOf(table)
+	if len(regions) == 0 {
+		return nil
+	}
+
+	for _, replica := range table.Replicas {
+		if replica.ReplicaStatus != types.ReplicaStatusActive {
+			return errors.New(fmt.Sprintf("Refused to update global table:%s : replica:%s status is %s, not ACTIVE", tableName, aws.ToString(replica.RegionName), replica.ReplicaStatus))
+		}
+	}
+	if opts.StreamViewType != "" && opts.StreamViewType != string(types.StreamViewTypeNewAndOldImages) {
+		return errors.New(fmt.Sprintf("Refused to change the stream of global table:%s to %s : replication requires NEW_AND_OLD_IMAGES", tableName, opts.StreamViewType))
+	}
+
+	if opts.changesCapacity() {
+		dbmgr.Logger.Infof("Table:%s is a global table - the billing mode and capacity changes are propagated to the replicas in: %s", tableName, strings.Join(regions, ","))
+		for _, replica := range table.Replicas {
+			if replica.ProvisionedThroughputOverride == nil || replica.ProvisionedThroughputOverride.ReadCapacityUnits == nil {
+				continue
+			}
+			if opts.SwitchToOnDemand {
+				dbmgr.Logger.Warnf("Replica:%s of table:%s loses its read capacity override of %d when switching to on-demand", aws.ToString(replica.RegionName), tableName, aws.ToInt64(replica.ProvisionedThroughputOverride.ReadCapacityUnits))
+			} else {
+				dbmgr.Logger.Warnf("Replica:%s of table:%s keeps its read capacity override of %d, change it with: replica update", aws.ToString(replica.RegionName), tableName, aws.ToInt64(replica.ProvisionedThroughputOverride.ReadCapacityUnits))
+			}
+		}
+	}
+	if opts.TableClass != "" || opts.DeletionProtection != nil {
+		dbmgr.Logger.Warnf("Table:%s is a global table - the table class and deletion protection only change in region:%s, use replica update for the other replicas", tableName, dbmgr.AwsConfig.Region)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package update
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// updateDeletionProtection enables or disables the deletion protection of a table, unless it already is in that state.
+// Disabling it has to pass the protection policy first.
+// It returns an error if the table can't be described, the policy refuses disabling or the update fails.
+func updateDeletionProtection(dbmgr *client.DynamoDBManager, tableName string, enabled bool) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+
+	if aws.ToBool(table.DeletionProtectionEnabled) == enabled {
+		dbmgr.Logger.Warnf("No need to change the deletion protection, table:%s already has it set to %t!", tableName, enabled)
+		return nil
+	}
+
+	if !enabled {
+		if err := CheckUnprotectClient(dbmgr, tableName); err != nil {
+			return err
+		}
+		dbmgr.Logger.Warnf("Disabling the deletion protection of table:%s, it can be deleted afterwards", tableName)
+	}
+	dbmgr.Logger.Infof("Table:%s - deletionProtection -> %t", tableName, enabled)
+	return UpdateDeletionProtectClient(dbmgr, tableName, enabled)
+}
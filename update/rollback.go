@@ -0,0 +1,269 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// RollbackWaitTimeout bounds how long a rollback waits for a table to become ACTIVE between two updates.
+var RollbackWaitTimeout = 30 * time.Minute
+
+var (
+	ReadJournalClient           = client.ReadJournal
+	GetTableStateClient         = client.GetTableState
+	WaitForTableActiveClient    = client.WaitForTableActive
+	UpdateGsiCapacityClient     = client.UpdateGsiProvisionedCapacity
+	UpdateOnDemandClient        = client.UpdateOnDemandThroughput
+	UpdateTableClassClient      = client.UpdateTableClass
+	UpdateDeletionProtectClient = client.UpdateDeletionProtection
+)
+
+// describeCapacity renders the billing mode and capacities of a table state for the history.
+func describeCapacity(state client.TableState) string {
+	var parts []string
+	if state.BillingMode == "PROVISIONED" {
+		parts = append(parts, fmt.Sprintf("PROVISIONED rcu:%d wcu:%d", state.Rcu, state.Wcu))
+		for _, gsi := range state.Gsis {
+			parts = append(parts, fmt.Sprintf("gsi:%s %d/%d", gsi.IndexName, gsi.Rcu, gsi.Wcu))
+		}
+	} else {
+		parts = append(parts, state.BillingMode)
+		if state.MaxReadRequestUnits != 0 || state.MaxWriteRequestUnits != 0 {
+			parts = append(parts, fmt.Sprintf("max read:%d write:%d", state.MaxReadRequestUnits, state.MaxWriteRequestUnits))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("class:%s", state.TableClass), fmt.Sprintf("protected:%t", state.DeletionProtection))
+	if state.StreamViewType != "" {
+		parts = append(parts, fmt.Sprintf("stream:%s", state.StreamViewType))
+	}
+	for _, replica := range state.Replicas {
+		replicaPart := "replica:" + replica.Region
+		if replica.TableClass != "" {
+			replicaPart += " class:" + replica.TableClass
+		}
+		if replica.ReadCapacity != 0 {
+			replicaPart += fmt.Sprintf(" readCapacity:%d", replica.ReadCapacity)
+		}
+		parts = append(parts, replicaPart)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ExecuteHistory logs the changes recorded in the journal, newest first.
+// It takes a DynamoDBManager, an optional table name to filter by and the maximum number of entries (0 for all) as input.
+// It returns an error if the journal can't be read.
+func ExecuteHistory(dbmgr *client.DynamoDBManager, tableName string, limit int) error {
+	entries, err := ReadJournalClient(dbmgr.JournalFile)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to read journal:%s due to: %v", dbmgr.JournalFile, err)
+		return err
+	}
+
+	shown := 0
+	for i := len(entries) - 1; i >= 0 && (limit == 0 || shown < limit); i-- {
+		entry := entries[i]
+		if tableName != "" && entry.TableName != tableName {
+			continue
+		}
+		shown++
+		dbmgr.Logger.Infof("Change:%s at %v by %s (profile:%s) on table:%s - before: [%s] - after: [%s]",
+			entry.ID, entry.Timestamp, entry.Principal, entry.Profile, entry.TableName, describeCapacity(entry.Before), describeCapacity(entry.After))
+	}
+
+	if shown == 0 {
+		dbmgr.Logger.Warnf("No changes recorded in journal:%s", dbmgr.JournalFile)
+	}
+	return nil
+}
+
+// replicasChanged reports whether a change added, removed or updated a replica.
+func replicasChanged(before client.TableState, after client.TableState) bool {
+	if len(before.Replicas) != len(after.Replicas) {
+		return true
+	}
+	for _, replica := range before.Replicas {
+		if changed := after.Replica(replica.Region); changed == nil || *changed != replica {
+			return true
+		}
+	}
+	return false
+}
+
+// indexCapacityChanged reports whether a change modified the capacity of an index present before and after it.
+func indexCapacityChanged(before client.TableState, after client.TableState) bool {
+	for _, gsi := range before.Gsis {
+		if changed := after.Gsi(gsi.IndexName); changed != nil && (changed.Rcu != gsi.Rcu || changed.Wcu != gsi.Wcu) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRestorable verifies that rollback can restore a recorded change: it must have modified the billing mode,
+// capacities, on-demand limits, table class or deletion protection, the settings rollback knows how to restore,
+// and nothing else it records, like the stream, the set of indexes or the replicas.
+// It returns an error describing why the change can't be rolled back.
+func checkRestorable(entry *client.JournalEntry) error {
+	before, after := entry.Before, entry.After
+	if replicasChanged(before, after) {
+		return errors.New(fmt.Sprintf("change:%s on table:%s changed the replicas, which rollback can't restore - use the replica commands instead",
+			entry.ID, entry.TableName))
+	}
+	for _, gsi := range after.Gsis {
+		if before.Gsi(gsi.IndexName) == nil {
+			return errors.New(fmt.Sprintf("change:%s on table:%s added index:%s, which rollback can't restore - remove it with index delete",
+				entry.ID, entry.TableName, gsi.IndexName))
+		}
+	}
+	for _, gsi := range before.Gsis {
+		if after.Gsi(gsi.IndexName) == nil {
+			return errors.New(fmt.Sprintf("change:%s on table:%s deleted index:%s, which rollback can't restore - recreate it with index add",
+				entry.ID, entry.TableName, gsi.IndexName))
+		}
+	}
+	if before.StreamViewType != after.StreamViewType {
+		return errors.New(fmt.Sprintf("change:%s on table:%s changed the stream from %s to %s, which rollback can't restore",
+			entry.ID, entry.TableName, before.StreamViewType, after.StreamViewType))
+	}
+	if before.BillingMode != after.BillingMode || before.Rcu != after.Rcu || before.Wcu != after.Wcu ||
+		before.MaxReadRequestUnits != after.MaxReadRequestUnits || before.MaxWriteRequestUnits != after.MaxWriteRequestUnits ||
+		before.TableClass != after.TableClass || before.DeletionProtection != after.DeletionProtection ||
+		indexCapacityChanged(before, after) {
+		return nil
+	}
+	return errors.New(fmt.Sprintf("change:%s on table:%s can't be rolled back: it changed none of billing mode, capacity, on-demand limits, table class or deletion protection", entry.ID, entry.TableName))
+}
+
+// findJournalEntry looks up a journal entry by its change id.
+// It returns the entry and an error if there is no such change.
+func findJournalEntry(dbmgr *client.DynamoDBManager, changeID string) (*client.JournalEntry, error) {
+	entries, err := ReadJournalClient(dbmgr.JournalFile)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == changeID {
+			return &entries[i], nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("change:%s not found in journal:%s", changeID, dbmgr.JournalFile))
+}
+
+// ExecuteRollback restores the billing mode, capacities, table class and deletion protection a table had
+// before a recorded change, touching only the settings that change modified. Other changes are refused.
+// The restore goes through ExecuteUpdate, UpdateIndexCapacity and the table class checks, so decrease budgets and
+// cooldowns are respected for the table and its indexes, and a class change that would be refused is refused before
+// any capacity is touched.
+// It takes a DynamoDBManager, the change id and the number of daily capacity decreases to keep in reserve as input.
+// It returns an error if the change is unknown, can't be rolled back or the table can't be restored.
+func ExecuteRollback(dbmgr *client.DynamoDBManager, changeID string, reserveDecreases int) error {
+	entry, err := findJournalEntry(dbmgr, changeID)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to find the change to roll back due to: %v", err)
+		return err
+	}
+	if err := checkRestorable(entry); err != nil {
+		dbmgr.Logger.Errorf("%v", err)
+		return err
+	}
+	before, after := entry.Before, entry.After
+	tableName := entry.TableName
+
+	current, err := GetTableStateClient(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to get the state of table:%s due to: %v", tableName, err)
+		return err
+	}
+	if describeCapacity(*current) != describeCapacity(after) {
+		dbmgr.Logger.Warnf("Table:%s changed since change:%s - current: [%s] - restoring: [%s]", tableName, changeID, describeCapacity(*current), describeCapacity(before))
+	}
+
+	if before.TableClass != after.TableClass {
+		if _, err := CheckTableClassChange(dbmgr, tableName, before.TableClass); err != nil {
+			dbmgr.Logger.Errorf("%v", err)
+			return err
+		}
+	}
+
+	dbmgr.Logger.Infof("Rolling back change:%s on table:%s to: [%s]", changeID, tableName, describeCapacity(before))
+	wait := func() error { return WaitForTableActiveClient(dbmgr, tableName, RollbackWaitTimeout) }
+
+	switching := before.BillingMode == "PROVISIONED" && current.BillingMode != "PROVISIONED"
+	if before.BillingMode != after.BillingMode || before.Rcu != after.Rcu || before.Wcu != after.Wcu {
+		opts := UpdateOptions{ReserveDecreases: reserveDecreases}
+		if before.BillingMode == "PAY_PER_REQUEST" {
+			opts.SwitchToOnDemand = true
+		} else {
+			opts.SwitchToProvisioned = switching
+			opts.Rcu = fmt.Sprintf("%d", before.Rcu)
+			opts.Wcu = fmt.Sprintf("%d", before.Wcu)
+		}
+		if switching {
+			// The index capacities are restored by the switch itself.
+			for _, gsi := range before.Gsis {
+				if current.Gsi(gsi.IndexName) != nil {
+					opts.Gsis = append(opts.Gsis, gsi)
+				}
+			}
+		}
+		if err := ExecuteUpdate(dbmgr, tableName, opts); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	if before.BillingMode == "PROVISIONED" && !switching {
+		for _, gsi := range before.Gsis {
+			changed := after.Gsi(gsi.IndexName)
+			if changed == nil || (changed.Rcu == gsi.Rcu && changed.Wcu == gsi.Wcu) || current.Gsi(gsi.IndexName) == nil {
+				continue
+			}
+			if err := UpdateIndexCapacity(dbmgr, tableName, gsi.IndexName, gsi.Rcu, gsi.Wcu, reserveDecreases); err != nil {
+				return err
+			}
+			if err := wait(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if before.BillingMode == "PAY_PER_REQUEST" && (before.MaxReadRequestUnits != after.MaxReadRequestUnits || before.MaxWriteRequestUnits != after.MaxWriteRequestUnits) {
+		maxRead, maxWrite := before.MaxReadRequestUnits, before.MaxWriteRequestUnits
+		if maxRead == 0 {
+			maxRead = -1
+		}
+		if maxWrite == 0 {
+			maxWrite = -1
+		}
+		if err := UpdateOnDemandClient(dbmgr, tableName, maxRead, maxWrite); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	if before.TableClass != after.TableClass {
+		if err := updateTableClass(dbmgr, tableName, before.TableClass); err != nil {
+			return err
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+	}
+
+	if before.DeletionProtection != after.DeletionProtection {
+		if err := updateDeletionProtection(dbmgr, tableName, before.DeletionProtection); err != nil {
+			return err
+		}
+	}
+
+	dbmgr.Logger.Infof("Rolled back change:%s on table:%s", changeID, tableName)
+	return nil
+}
@@ -0,0 +1,115 @@
+package update
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bazelgo/dynamodb-manager/client"
+)
+
+// DisabledStream is the view type UpdateOptions.StreamViewType takes to disable the stream of a table.
+const DisabledStream = "DISABLED"
+
+var UpdateStreamClient = client.UpdateStream
+
+// EventSourceMapping is a Lambda event source mapping as listed by aws lambda list-event-source-mappings.
+type EventSourceMapping struct {
+	UUID           string `json:"UUID" yaml:"UUID"`
+	EventSourceArn string `json:"EventSourceArn" yaml:"EventSourceArn"`
+	FunctionArn    string `json:"FunctionArn" yaml:"FunctionArn"`
+	State          string `json:"State" yaml:"State"`
+}
+
+// eventSourceMappingList is the event source mapping file, the output of aws lambda list-event-source-mappings.
+type eventSourceMappingList struct {
+	EventSourceMappings []EventSourceMapping `json:"EventSourceMappings" yaml:"EventSourceMappings"`
+}
+
+// LoadEventSourceMappings reads an event source mapping file, parsed as JSON for a .json extension and as YAML otherwise.
+// It returns the event source mappings and an error.
+func LoadEventSourceMappings(path string) ([]EventSourceMapping, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var list eventSourceMappingList
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(content, &list)
+	} else {
+		err = yaml.Unmarshal(content, &list)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("failed to parse event source mapping file:%s - error:%v", path, err))
+	}
+	return list.EventSourceMappings, nil
+}
+
+// activeConsumers returns the event source mappings reading from a stream that aren't disabled.
+func activeConsumers(mappings []EventSourceMapping, streamArn string) []EventSourceMapping {
+	var consumers []EventSourceMapping
+	for _, mapping := range mappings {
+		if mapping.EventSourceArn == streamArn && !strings.EqualFold(mapping.State, "Disabled") && !strings.EqualFold(mapping.State, "Disabling") {
+			consumers = append(consumers, mapping)
+		}
+	}
+	return consumers
+}
+
+// warnStreamConsumers warns about the consumers that stop receiving records once the current stream of a table is gone.
+// A mapping file that can't be read is only reported, since it must not block the update.
+func warnStreamConsumers(dbmgr *client.DynamoDBManager, tableName string, streamArn string, mappingFile string) {
+	if mappingFile == "" {
+		dbmgr.Logger.Warnf("Stream:%s of table:%s goes away, its consumers are not checked without an event source mapping file", streamArn, tableName)
+		return
+	}
+
+	mappings, err := LoadEventSourceMappings(mappingFile)
+	if err != nil {
+		dbmgr.Logger.Warnf("Failed to check the consumers of stream:%s of table:%s due to: %v", streamArn, tableName, err)
+		return
+	}
+	for _, consumer := range activeConsumers(mappings, streamArn) {
+		dbmgr.Logger.Warnf("Stream:%s of table:%s goes away but is consumed by function:%s (mapping:%s, state:%s)", streamArn, tableName, consumer.FunctionArn, consumer.UUID, consumer.State)
+	}
+}
+
+// updateStream enables the stream of a table with a view type, or disables it with DisabledStream.
+// DynamoDB can't change the view type of an enabled stream, so such a change disables the stream and enables
+// a new one, with a new stream ARN. The consumers of a stream going away are looked up in the mapping file.
+// It returns an error if the table can't be described or updated.
+func updateStream(dbmgr *client.DynamoDBManager, tableName string, viewType string, mappingFile string) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+
+	current := client.StreamViewTypeOf(table)
+	if current == viewType {
+		dbmgr.Logger.Warnf("No need to change the stream, table:%s already has it set to %s!", tableName, viewType)
+		return nil
+	}
+
+	dbmgr.Logger.Infof("Table:%s - stream: %s -> %s", tableName, current, viewType)
+	if current != DisabledStream {
+		warnStreamConsumers(dbmgr, tableName, aws.ToString(table.LatestStreamArn), mappingFile)
+		if err := UpdateStreamClient(dbmgr, tableName, false, ""); err != nil {
+			return err
+		}
+		if viewType == DisabledStream {
+			return nil
+		}
+		dbmgr.Logger.Warnf("Table:%s gets a new stream with view type:%s, its consumers must move to the new stream ARN", tableName, viewType)
+		if err := WaitForTableActiveClient(dbmgr, tableName, UpdateWaitTimeout); err != nil {
+			return err
+		}
+	}
+	return UpdateStreamClient(dbmgr, tableName, true, viewType)
+}
@@ -3,57 +3,445 @@ package update
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
 	"github.com/bazelgo/dynamodb-manager/client"
 )
 
 var (
-	SwitchToOnDemandCapacityClient  = client.SwitchToOnDemandCapacity
-	UpdateProvisionedCapacityClient = client.UpdateProvisionedCapacity
-	GetCurrentBillingModeClient     = client.GetCurrentBillingMode
+	SwitchToOnDemandCapacityClient    = client.SwitchToOnDemandCapacity
+	SwitchToProvisionedCapacityClient = client.SwitchToProvisionedCapacity
+	UpdateProvisionedCapacityClient   = client.UpdateProvisionedCapacity
+	GetCurrentBillingModeClient       = client.GetCurrentBillingMode
+	GetCapacityDecreaseInfoClient     = client.GetCapacityDecreaseInfo
+	DescribeTableClient               = client.DescribeTable
+	GetScalableTargetsClient          = client.GetScalableTargets
+	CheckUnprotectClient              = client.CheckDisableProtection
 )
 
-// ExecuteUpdate updates the capacity mode and provisioned capacity of a DynamoDB table.
-// It takes a DynamoDBManager, table name, parameters for Read Capacity Units (RCU), Write Capacity Units (WCU),
-// and flags to switch to on-demand or provisioned capacity as input.
+// ErrCooldown matches the errors of updates refused because a DynamoDB limit on capacity decreases or
+// mode switches blocks them for now, so that they can be retried later.
+var ErrCooldown = errors.New("update blocked by a DynamoDB cooldown")
+
+// cooldownError is an update refusal that matches ErrCooldown.
+type cooldownError struct {
+	message string
+}
+
+func (e *cooldownError) Error() string {
+	return e.message
+}
+
+func (e *cooldownError) Is(target error) bool {
+	return target == ErrCooldown
+}
+
+// OnDemandSwitchCooldown is the window in which DynamoDB limits switching a table to on-demand again.
+// DescribeTable only exposes the time of the last switch, so any switch within the window is treated as blocking.
+const OnDemandSwitchCooldown = 24 * time.Hour
+
+// checkOnDemandSwitchCooldown verifies that the table wasn't switched to on-demand within the OnDemandSwitchCooldown.
+// It returns an error if the switch must be refused.
+func checkOnDemandSwitchCooldown(dbmgr *client.DynamoDBManager, tableName string) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return errors.New("Failed to update the table!")
+	}
+
+	if table.BillingModeSummary == nil || table.BillingModeSummary.LastUpdateToPayPerRequestDateTime == nil {
+		return nil
+	}
+
+	lastSwitch := *table.BillingModeSummary.LastUpdateToPayPerRequestDateTime
+	if time.Since(lastSwitch) < OnDemandSwitchCooldown {
+		return &cooldownError{fmt.Sprintf("Refused to switch table:%s to on-demand : last switch at %v, next one is allowed after %v", tableName, lastSwitch.UTC(), lastSwitch.Add(OnDemandSwitchCooldown).UTC())}
+	}
+	return nil
+}
+
+// isCapacityDecrease reports whether moving from the current to the new capacity value lowers it.
+// Empty current values (e.g. on-demand tables) never count as a decrease.
+func isCapacityDecrease(current string, next string) bool {
+	if current == "" || next == "" {
+		return false
+	}
+	currentVal, errCurrent := strconv.ParseInt(current, 10, 64)
+	nextVal, errNext := strconv.ParseInt(next, 10, 64)
+	if errCurrent != nil || errNext != nil {
+		return false
+	}
+	return nextVal < currentVal
+}
+
+// checkDecreaseBudget verifies that a provisioned capacity decrease still fits into today's decrease budget
+// of the table while leaving at least reserveDecreases decreases unused.
+// It returns an error if the decrease must be refused.
+func checkDecreaseBudget(dbmgr *client.DynamoDBManager, tableName string, reserveDecreases int) error {
+	decreasesToday, lastDecrease, err := GetCapacityDecreaseInfoClient(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to get the capacity decrease info of table:%s due to error:%v", tableName, err)
+		return errors.New("Failed to update the table!")
+	}
+	return checkRemainingDecreases(dbmgr, "table:"+tableName, decreasesToday, lastDecrease, reserveDecreases)
+}
+
+// checkRemainingDecreases verifies that one more capacity decrease of a table or index, named by subject, fits into
+// its daily decrease budget while leaving at least reserveDecreases decreases unused.
+// It returns an error if the decrease must be refused.
+func checkRemainingDecreases(dbmgr *client.DynamoDBManager, subject string, decreasesToday int64, lastDecrease *time.Time, reserveDecreases int) error {
+	remaining, allowedNow := client.RemainingCapacityDecreases(decreasesToday, lastDecrease, time.Now())
+	if remaining == 0 {
+		return &cooldownError{fmt.Sprintf("Refused to decrease capacity of %s : no decreases left for today (%d used)", subject, decreasesToday)}
+	}
+
+	if !allowedNow {
+		return &cooldownError{fmt.Sprintf("Refused to decrease capacity of %s : last decrease at %v, next one is allowed after %v", subject, lastDecrease.UTC(), lastDecrease.Add(client.DecreaseInterval).UTC())}
+	}
+
+	if remaining-1 < int64(reserveDecreases) {
+		return &cooldownError{fmt.Sprintf("Refused to decrease capacity of %s : only %d decreases left for today, %d must be reserved", subject, remaining, reserveDecreases)}
+	}
+
+	if remaining == 1 {
+		dbmgr.Logger.Warnf("This decrease exhausts today's decrease budget of %s, no more decreases are possible until tomorrow (UTC)", subject)
+	} else {
+		dbmgr.Logger.Infof("Decreases left for %s after this update: %d", subject, remaining-1)
+	}
+	return nil
+}
+
+// UpdateIndexCapacity changes the provisioned capacity of a global secondary index of a provisioned table.
+// Like table decreases, a decrease must fit into the daily decrease budget of the index while leaving at least
+// reserveDecreases decreases unused.
+// It returns an error if the table has no such index, the decrease is refused or the update fails.
+func UpdateIndexCapacity(dbmgr *client.DynamoDBManager, tableName string, indexName string, rcu int64, wcu int64, reserveDecreases int) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		return err
+	}
+
+	var throughput *types.ProvisionedThroughputDescription
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		if aws.ToString(gsi.IndexName) == indexName {
+			throughput = gsi.ProvisionedThroughput
+		}
+	}
+	if throughput == nil {
+		return errors.New(fmt.Sprintf("table:%s has no provisioned index:%s", tableName, indexName))
+	}
+
+	currentRcu, currentWcu := aws.ToInt64(throughput.ReadCapacityUnits), aws.ToInt64(throughput.WriteCapacityUnits)
+	if rcu == currentRcu && wcu == currentWcu {
+		dbmgr.Logger.Warnf("No need to update index:%s of table:%s, it already has rcu:%d wcu:%d", indexName, tableName, rcu, wcu)
+		return nil
+	}
+	if rcu < currentRcu || wcu < currentWcu {
+		subject := fmt.Sprintf("index:%s of table:%s", indexName, tableName)
+		if err := checkRemainingDecreases(dbmgr, subject, aws.ToInt64(throughput.NumberOfDecreasesToday), throughput.LastDecreaseDateTime, reserveDecreases); err != nil {
+			dbmgr.Logger.Errorf("%v", err)
+			return err
+		}
+	}
+	return UpdateGsiCapacityClient(dbmgr, tableName, indexName, rcu, wcu)
+}
+
+// provisionedIndexes returns the capacity every global secondary index of a table gets when the table is switched
+// to provisioned mode: the capacity requested in gsis, or the default capacity.
+// It returns the index capacities and an error if gsis holds an index the table doesn't have.
+func provisionedIndexes(state *client.TableState, gsis []client.GsiState) ([]client.GsiState, error) {
+	for _, requested := range gsis {
+		if state.Gsi(requested.IndexName) == nil {
+			return nil, errors.New(fmt.Sprintf("table:%s has no index:%s", state.TableName, requested.IndexName))
+		}
+	}
+
+	indexes := make([]client.GsiState, 0, len(state.Gsis))
+	for _, current := range state.Gsis {
+		gsi := client.GsiState{IndexName: current.IndexName, Rcu: client.DefaultRcu, Wcu: client.DefaultWcu}
+		for _, requested := range gsis {
+			if requested.IndexName == current.IndexName {
+				gsi = requested
+			}
+		}
+		indexes = append(indexes, gsi)
+	}
+	return indexes, nil
+}
+
+// warnAutoScaling warns when Application Auto Scaling manages the read or write capacity of a table,
+// as it will override a manual change, at the latest once the utilization moves.
+// A failed lookup is only reported, since it must not block the update.
+func warnAutoScaling(dbmgr *client.DynamoDBManager, tableName string, rcu string, wcu string) {
+	targets, err := GetScalableTargetsClient(dbmgr, []string{client.ScalingResourceId(tableName, "")})
+	if err != nil {
+		dbmgr.Logger.Warnf("Failed to check the auto scaling of table:%s due to: %v", tableName, err)
+		return
+	}
+
+	for _, target := range targets {
+		dimension, value := "read", rcu
+		if target.ScalableDimension == client.TableWriteDimension {
+			dimension, value = "write", wcu
+		} else if target.ScalableDimension != client.TableReadDimension {
+			continue
+		}
+
+		minCapacity, maxCapacity := int64(aws.ToInt32(target.MinCapacity)), int64(aws.ToInt32(target.MaxCapacity))
+		capacity, _ := strconv.ParseInt(value, 10, 64)
+		if capacity < minCapacity || capacity > maxCapacity {
+			dbmgr.Logger.Warnf("Table:%s %s capacity is auto scaled between min:%d and max:%d - auto scaling will move the manual change to %s back into that range", tableName, dimension, minCapacity, maxCapacity, value)
+		} else {
+			dbmgr.Logger.Warnf("Table:%s %s capacity is auto scaled between min:%d and max:%d - auto scaling will override the manual change to %s", tableName, dimension, minCapacity, maxCapacity, value)
+		}
+	}
+}
+
+// UpdateOptions holds the requested changes for a table update.
+// Rcu and Wcu accept absolute values as well as changes relative to the current capacity (see ParseCapacityChange).
+type UpdateOptions struct {
+	Rcu                 string
+	Wcu                 string
+	SwitchToOnDemand    bool
+	SwitchToProvisioned bool
+	ReserveDecreases    int               // daily capacity decreases that must stay unused
+	MinCapacity         int64             // lower clamp for resolved rcu and wcu
+	MaxCapacity         int64             // upper clamp for resolved rcu and wcu, 0 means no limit
+	TableClass          string            // table class to switch to, empty keeps the current one
+	DeletionProtection  *bool             // deletion protection to set, nil keeps the current one
+	BackupFirst         bool              // create an on-demand backup and wait for it before changing the table
+	StreamViewType      string            // stream view type to enable, DisabledStream to disable the stream, empty keeps it
+	EventSourceMappings string            // event source mapping file to check for consumers of a stream going away
+	Gsis                []client.GsiState // index capacities when switching to provisioned, others get the default
+}
+
+// changesCapacity reports whether the options change the billing mode or provisioned capacity.
+func (o *UpdateOptions) changesCapacity() bool {
+	return o.Rcu != "" || o.Wcu != "" || o.SwitchToOnDemand || o.SwitchToProvisioned
+}
+
+// ExecuteUpdate updates the capacity mode, provisioned capacity, table class, deletion protection and stream of a DynamoDB table.
+// It takes a DynamoDBManager, table name and the UpdateOptions as input.
+// An omitted rcu or wcu keeps the current value of a provisioned table, while tables switched to provisioned
+// mode get the default capacity instead. The table class, deletion protection and stream changes follow
+// once the capacity update is done, one after the other, as DynamoDB accepts one table update at a time.
+// With BackupFirst the table is only changed once its backup is available. Global tables are checked first,
+// as their capacity changes are propagated to every replica.
+// It returns an error if the update operation fails.
+func ExecuteUpdate(dbmgr *client.DynamoDBManager, tableName string, opts UpdateOptions) error {
+	if err := checkGlobalTable(dbmgr, tableName, opts); err != nil {
+		dbmgr.Logger.Errorf("%v", err)
+		return err
+	}
+
+	if opts.BackupFirst {
+		if err := backupBeforeUpdate(dbmgr, tableName); err != nil {
+			dbmgr.Logger.Errorf("%v", err)
+			return err
+		}
+	}
+
+	var steps []func() error
+	if opts.TableClass != "" {
+		steps = append(steps, func() error { return updateTableClass(dbmgr, tableName, opts.TableClass) })
+	}
+	if opts.DeletionProtection != nil {
+		steps = append(steps, func() error { return updateDeletionProtection(dbmgr, tableName, *opts.DeletionProtection) })
+	}
+	if opts.StreamViewType != "" {
+		steps = append(steps, func() error { return updateStream(dbmgr, tableName, opts.StreamViewType, opts.EventSourceMappings) })
+	}
+	if len(steps) == 0 {
+		return updateCapacity(dbmgr, tableName, opts)
+	}
+
+	pending := false
+	if opts.changesCapacity() {
+		// Refuse a class change before touching the capacity rather than leaving the update half done.
+		if opts.TableClass != "" {
+			if _, err := CheckTableClassChange(dbmgr, tableName, opts.TableClass); err != nil {
+				dbmgr.Logger.Errorf("%v", err)
+				return err
+			}
+		}
+		if err := updateCapacity(dbmgr, tableName, opts); err != nil {
+			return err
+		}
+		pending = true
+	}
+
+	for _, step := range steps {
+		if pending {
+			if err := WaitForTableActiveClient(dbmgr, tableName, UpdateWaitTimeout); err != nil {
+				return err
+			}
+		}
+		if err := step(); err != nil {
+			return err
+		}
+		pending = true
+	}
+	return nil
+}
+
+// updateCapacity updates the capacity mode and provisioned capacity of a DynamoDB table.
 // It returns an error if the update operation fails.
-func ExecuteUpdate(dbmgr *client.DynamoDBManager, tableName string, paramRcu string, paramWcu string, switchToOnDemand bool, switchToProvisioned bool) error {
+func updateCapacity(dbmgr *client.DynamoDBManager, tableName string, opts UpdateOptions) error {
 	billingMode, rcu, wcu, err := GetCurrentBillingModeClient(dbmgr, tableName)
 	if err != nil {
 		dbmgr.Logger.Errorf("Failed to get the billing mode info of table:%s : as current billing mode due to error:%v", tableName, err)
 		return errors.New("Failed to update the table!")
 	}
 
-	if switchToOnDemand {
+	if opts.SwitchToOnDemand {
 		if billingMode != "PAY_PER_REQUEST" {
+			if err := checkOnDemandSwitchCooldown(dbmgr, tableName); err != nil {
+				dbmgr.Logger.Errorf("%v", err)
+				return err
+			}
 			return SwitchToOnDemandCapacityClient(dbmgr, tableName)
 		} else {
 			dbmgr.Logger.Warn("No need to switch, as it already is on demand mode!")
 			return nil
 		}
 	} else {
-		if billingMode != "PROVISIONED" && !switchToProvisioned {
+		if billingMode != "PROVISIONED" && !opts.SwitchToProvisioned {
 			dbmgr.Logger.Errorf("Failed to update table:%s : as current billing mode:%s - does not support modification of rcu or wcu", tableName, billingMode)
 			return errors.New("Failed to update the table!")
 		}
 
-		if paramRcu == "" && paramWcu == "" {
-			return UpdateProvisionedCapacityClient(dbmgr, switchToProvisioned, tableName, "", "")
-		}
-
-		if paramRcu == "" {
-			paramRcu = fmt.Sprintf("%d", client.DefaultRcu)
+		paramRcu, err := resolveCapacity(opts.Rcu, rcu, client.DefaultRcu, opts.MinCapacity, opts.MaxCapacity)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to update table:%s : invalid rcu due to error:%v", tableName, err)
+			return errors.New("Failed to update the table!")
 		}
 
-		if paramWcu == "" {
-			paramWcu = fmt.Sprintf("%d", client.DefaultWcu)
+		paramWcu, err := resolveCapacity(opts.Wcu, wcu, client.DefaultWcu, opts.MinCapacity, opts.MaxCapacity)
+		if err != nil {
+			dbmgr.Logger.Errorf("Failed to update table:%s : invalid wcu due to error:%v", tableName, err)
+			return errors.New("Failed to update the table!")
 		}
 
 		if paramRcu != rcu || paramWcu != wcu {
-			return UpdateProvisionedCapacityClient(dbmgr, switchToProvisioned, tableName, paramRcu, paramWcu)
+			if billingMode == "PROVISIONED" && (isCapacityDecrease(rcu, paramRcu) || isCapacityDecrease(wcu, paramWcu)) {
+				if err := checkDecreaseBudget(dbmgr, tableName, opts.ReserveDecreases); err != nil {
+					dbmgr.Logger.Errorf("%v", err)
+					return err
+				}
+			}
+			if billingMode != "PROVISIONED" {
+				return switchToProvisioned(dbmgr, tableName, paramRcu, paramWcu, opts.Gsis)
+			}
+			warnAutoScaling(dbmgr, tableName, paramRcu, paramWcu)
+			return UpdateProvisionedCapacityClient(dbmgr, opts.SwitchToProvisioned, tableName, paramRcu, paramWcu)
 		} else {
 			dbmgr.Logger.Warn("No need to update, as it already is provisioned mode or remain the same rcu and wcu!")
 			return nil
 		}
 	}
 }
+
+// switchToProvisioned switches an on-demand table to provisioned mode, setting the capacity of its global secondary
+// indexes in the same update as DynamoDB requires.
+// It returns an error if the table can't be described, gsis holds an unknown index or the update fails.
+func switchToProvisioned(dbmgr *client.DynamoDBManager, tableName string, rcu string, wcu string, gsis []client.GsiState) error {
+	table, err := DescribeTableClient(dbmgr, tableName)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to describe table:%s due to error:%v", tableName, err)
+		return errors.New("Failed to update the table!")
+	}
+
+	indexes, err := provisionedIndexes(client.TableStateFromDescription(tableName, table), gsis)
+	if err != nil {
+		dbmgr.Logger.Errorf("Failed to update table:%s : %v", tableName, err)
+		return errors.New("Failed to update the table!")
+	}
+
+	rcuVal, _ := strconv.ParseInt(rcu, 10, 64)
+	wcuVal, _ := strconv.ParseInt(wcu, 10, 64)
+	return SwitchToProvisionedCapacityClient(dbmgr, tableName, rcuVal, wcuVal, indexes)
+}
+
+// ProposedState returns the billing mode and capacities a table would have after ExecuteUpdate with the given
+// options, so that a change can be estimated before it is made. Indexes of a table switched to provisioned
+// mode get the capacity the switch sends for them: the one in opts.Gsis, or the default for the others.
+// It returns the proposed TableState and an error if the options can't be applied to the table.
+func ProposedState(state *client.TableState, opts UpdateOptions) (*client.TableState, error) {
+	proposed := *state
+	proposed.Gsis = append([]client.GsiState(nil), state.Gsis...)
+	if opts.TableClass != "" {
+		proposed.TableClass = opts.TableClass
+	}
+	if opts.DeletionProtection != nil {
+		proposed.DeletionProtection = *opts.DeletionProtection
+	}
+
+	if opts.SwitchToOnDemand {
+		proposed.BillingMode = "PAY_PER_REQUEST"
+		proposed.Rcu, proposed.Wcu = 0, 0
+		for i := range proposed.Gsis {
+			proposed.Gsis[i].Rcu, proposed.Gsis[i].Wcu = 0, 0
+		}
+		return &proposed, nil
+	}
+
+	if state.BillingMode != "PROVISIONED" && !opts.SwitchToProvisioned {
+		if opts.Rcu != "" || opts.Wcu != "" {
+			return nil, errors.New(fmt.Sprintf("billing mode:%s does not support modification of rcu or wcu", state.BillingMode))
+		}
+		return &proposed, nil
+	}
+
+	current := func(value int64) string {
+		if value == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%d", value)
+	}
+	for _, capacity := range []struct {
+		param        string
+		value        *int64
+		defaultValue int64
+	}{
+		{opts.Rcu, &proposed.Rcu, client.DefaultRcu},
+		{opts.Wcu, &proposed.Wcu, client.DefaultWcu},
+	} {
+		resolved, err := resolveCapacity(capacity.param, current(*capacity.value), capacity.defaultValue, opts.MinCapacity, opts.MaxCapacity)
+		if err != nil {
+			return nil, err
+		}
+		*capacity.value, _ = strconv.ParseInt(resolved, 10, 64)
+	}
+
+	if state.BillingMode != "PROVISIONED" {
+		indexes, err := provisionedIndexes(state, opts.Gsis)
+		if err != nil {
+			return nil, err
+		}
+		proposed.BillingMode = "PROVISIONED"
+		proposed.Gsis = indexes
+	}
+	return &proposed, nil
+}
+
+// ExecuteBulkUpdate applies the same UpdateOptions to every given table, continuing after failures.
+// It returns an error listing the tables that couldn't be updated.
+func ExecuteBulkUpdate(dbmgr *client.DynamoDBManager, tableNames []string, opts UpdateOptions) error {
+	var failedTables []string
+	for _, tableName := range tableNames {
+		dbmgr.Logger.Infof("Begin to update table:%s ...", tableName)
+		if err := ExecuteUpdate(dbmgr, tableName, opts); err != nil {
+			dbmgr.Logger.Errorf("Failed to update table:%s due to: %v", tableName, err)
+			failedTables = append(failedTables, tableName)
+		}
+	}
+
+	if len(failedTables) > 0 {
+		return errors.New(fmt.Sprintf("Failed to update %d of %d tables: %s", len(failedTables), len(tableNames), strings.Join(failedTables, ",")))
+	}
+	return nil
+}